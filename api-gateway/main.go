@@ -5,10 +5,13 @@ import (
 	"news-kafka/api-gateway/pkg/api"
 	"news-kafka/api-gateway/pkg/kafka"
 	"news-kafka/api-gateway/pkg/logger"
+	"news-kafka/api-gateway/pkg/tracing"
 
 	"fmt"
 	"log"
 	"net/http"
+	"os/signal"
+	"syscall"
 
 	"github.com/IBM/sarama"
 )
@@ -16,8 +19,10 @@ import (
 // tasknews> go test ./... -coverprofile=coverage.out
 // tasknews> go test ./... -v -coverprofile=coverage.out
 
-// http://127.0.0.1:8080/news/{rubric}/{countNews}
-// http://127.0.0.1:8080/newsDetailed?id_news=1
+// http://127.0.0.1:8080/api/v1/news?rubric=Sport&page=1&page_size=10
+// http://127.0.0.1:8080/api/v1/news/1/comments
+// Маршруты без версии для UI: http://127.0.0.1:8080/news/{rubric}/{countNews}, http://127.0.0.1:8080/newsDetailed?id_news=1
+// Описание API: http://127.0.0.1:8080/docs, спецификация OpenAPI - http://127.0.0.1:8080/openapi.json
 
 //https://localhost:9443
 //psql -U postgres -d prgComments
@@ -37,20 +42,34 @@ func main() {
 	//==============================================
 	//Logger
 	//==============================================
-	logs, err := logger.NewLogger("logs.json", 50)
+	// Записи в формате JSON Lines, уровень в переменной окружения LOGLEVEL.
+	// Буфер записывается каждые 5 с, файл ротируется по размеру и по дням
+	sink, err := logger.NewLoggerWithOptions("logs.json", logger.DefaultOptions)
 	if err != nil {
-		fmt.Printf("Error creating logger: %v", err)
+		log.Fatalf("Error creating logger: %v", err)
 	}
-	defer logs.Close()
+	defer sink.Close()
+	logs := logger.New(sink, logger.LevelFromEnv())
 
-	errorChannel := make(chan error)
+	//==============================================
+	//Tracing
+	//==============================================
+	// Экспорт трассировки настраивается переменной окружения OTEL_TRACES_EXPORTER
+	shutdownTracing, err := tracing.Init(context.Background(), "api-gateway")
+	if err != nil {
+		log.Fatalf("Failed to init tracing: %v", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	// Остановка по SIGINT/SIGTERM
+	signalCtx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
+	// Чтение ответов сервисов останавливается после HTTP-сервера:
+	// текущим запросам нужны ответы из Kafka
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel() // cancel when we are finished consuming integers
 
-	// выводим ошибки
-	go handleErrors(ctx, errorChannel, logs)
-
 	// Создаём объект сервера.
 	var srv server
 
@@ -70,65 +89,85 @@ func main() {
 	}
 	defer kafkaProducer.Close()
 
+	// Записи лога также публикуются в топик логов, из которого их забирает service-logs
+	if config.TopicLogs != "" {
+		logPublisher := kafka.NewLogPublisher(kafkaProducer, config.TopicLogs)
+		sink.SetPublisher(logPublisher)
+		defer func() {
+			// Записи, сделанные до остановки, отправляются до закрытия producer
+			sink.Flush()
+			sink.SetPublisher(nil)
+			logPublisher.Close()
+		}()
+	}
+
 	kafkaConsumer, err := kafka.NewConsumer(config.KafkaBrokers)
 	if err != nil {
 		log.Fatalf("Failed to create Kafka consumer: %v", err)
 	}
 	defer kafkaConsumer.Close()
 
-	// Запуск гоурутины для потребления сообщений service-news
-	responseNewsCh, err := kafkaConsumer.Consume(config.TopicReceivedNews, 0, sarama.OffsetNewest)
+	// Топик ответов этого экземпляра: сервисы отправляют ответы в reply_to,
+	// поэтому несколько реплик api-gateway не забирают чужие ответы
+	instanceID, err := kafka.InstanceID()
 	if err != nil {
-		log.Fatalf("Failed to consume partition News: %v", err)
+		log.Fatalf("Failed to get instance id: %v", err)
 	}
+	replyTopic := config.ReplyTopic(instanceID)
+	fmt.Println("api-gateway: reply topic", replyTopic)
 
-	// Запуск гоурутины для потребления сообщений service-news
-	responseOneNewsCh, err := kafkaConsumer.Consume(config.TopicReceivedOneNews, 0, sarama.OffsetNewest)
+	err = kafka.EnsureTopic(config.KafkaBrokers, replyTopic)
 	if err != nil {
-		log.Fatalf("Failed to consume partition News: %v", err)
+		log.Fatalf("Failed to create reply topic: %v", err)
 	}
 
-	// Запуск гоурутины для потребления сообщений service-comments
-	responseCommentsCh, err := kafkaConsumer.Consume(config.TopicReceivedComments, 0, sarama.OffsetNewest)
+	responseCh, err := kafkaConsumer.Consume(replyTopic, 0, sarama.OffsetNewest)
 	if err != nil {
-		log.Fatalf("Failed to consume partition Comments: %v", err)
+		log.Fatalf("Failed to consume partition %v: %v", replyTopic, err)
 	}
 
-	// Запуск гоурутины для потребления сообщений service-comments
-	responseAddCommentsCh, err := kafkaConsumer.Consume(config.TopicReceivedAddComments, 0, sarama.OffsetNewest)
-	if err != nil {
-		log.Fatalf("Failed to consume partition Comments: %v", err)
-	}
+	// Диспетчер ответов: сообщения передаются ожидающим запросам по request_id
+	dispatcher := kafka.NewDispatcher(logs)
+	go dispatcher.Listen(ctx, responseCh)
 
-	// Запуск гоурутины для потребления сообщений service-censor
-	responseCensorCh, err := kafkaConsumer.Consume(config.TopicReceivedAddCensor, 0, sarama.OffsetNewest)
+	//==============================================
+	//API
+	//==============================================
+	configAPI, err := api.ReadConfig("configAPI.json")
 	if err != nil {
-		log.Fatalf("Failed to consume partition Censor: %v", err)
+		log.Fatalf("Failed to read API config: %v", err)
 	}
 
-	apiChannels := api.ApiChannels{
-		ResponseNewsCh:        responseNewsCh,
-		ResponseOneNewsCh:     responseOneNewsCh,
-		ResponseCommentsCh:    responseCommentsCh,
-		ResponseAddCommentsCh: responseAddCommentsCh,
-		ResponseCensorCh:      responseCensorCh,
-		ErrorChannel:          errorChannel,
-	}
+	srv.api = api.New(kafkaProducer, kafkaConsumer, config, configAPI, dispatcher, replyTopic, logs)
 
-	srv.api = api.New(kafkaProducer, kafkaConsumer, config, apiChannels)
+	// Ping сервисов для проверки готовности /readyz
+	go srv.api.PingServices(signalCtx)
 
 	fmt.Println("Запуск веб-сервера на http://127.0.0.1:8080 ...")
-	http.ListenAndServe(":8080", srv.api.Router())
-}
+	httpServer := &http.Server{Addr: ":8080", Handler: srv.api.Handler()}
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- httpServer.ListenAndServe()
+	}()
+
+	// Ожидание SIGINT/SIGTERM
+	select {
+	case err := <-serveErr:
+		log.Fatalf("Failed to start web server: %v", err)
+	case <-signalCtx.Done():
+	}
+	stop()
+	logs.Info("shutting down")
+
+	// Новые запросы не принимаются, текущие завершаются не дольше shutdown_grace_ms
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), configAPI.ShutdownGrace())
+	defer cancelShutdown()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		logs.Warn("failed to shut down web server", logger.Err(err))
+	}
 
-func handleErrors(ctx context.Context, errs <-chan error, logs *logger.Logger) {
-	for err := range errs {
-		select {
-		case <-ctx.Done():
+	// Остановка чтения ответов сервисов
+	cancel()
 
-			return
-		default:
-			logs.LogRequest(logger.GetRequestId(), logger.GetLocalIP(), 500, err.Error())
-		}
-	}
+	// Далее в defer: закрытие consumer и producer, запись буфера логгера
 }
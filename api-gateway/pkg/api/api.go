@@ -4,65 +4,75 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"log/slog"
 	"net/http"
 	"news-kafka/api-gateway/pkg/kafka"
 	"news-kafka/api-gateway/pkg/logger"
+	"news-kafka/api-gateway/pkg/metrics"
+	"news-kafka/api-gateway/pkg/ratelimit"
+	"news-kafka/api-gateway/pkg/tracing"
+	"news-kafka/contracts"
 	"strconv"
 	"sync"
 	"text/template"
 	"time"
 
-	"github.com/IBM/sarama"
 	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Программный интерфейс сервера GoNews
 type API struct {
-	producer              *kafka.Producer
-	consumer              *kafka.Consumer
-	configKafka           *kafka.Config
-	responseNewsCh        <-chan *sarama.ConsumerMessage
-	responseOneNewsCh     <-chan *sarama.ConsumerMessage
-	responseCommentsCh    <-chan *sarama.ConsumerMessage
-	responseAddCommentsCh <-chan *sarama.ConsumerMessage
-	responseCensorCh      <-chan *sarama.ConsumerMessage
-	router                *mux.Router
-	errorChannel          chan<- error
-}
-
-type ApiChannels struct {
-	ResponseNewsCh        <-chan *sarama.ConsumerMessage
-	ResponseOneNewsCh     <-chan *sarama.ConsumerMessage
-	ResponseCommentsCh    <-chan *sarama.ConsumerMessage
-	ResponseAddCommentsCh <-chan *sarama.ConsumerMessage
-	ResponseCensorCh      <-chan *sarama.ConsumerMessage
-	ErrorChannel          chan<- error
+	producer    kafka.ProducerInterface
+	consumer    kafka.ConsumerInterface
+	configKafka *kafka.Config
+	config      *Config
+	dispatcher  *kafka.Dispatcher
+	replyTopic  string
+	router      *mux.Router
+	health      *health
+	rateLimits  ratelimit.Store
+	logs        *slog.Logger
 }
 
 // Конструктор объекта API
-func New(producer *kafka.Producer, consumer *kafka.Consumer, configKafka *kafka.Config, apiChannels ApiChannels) *API {
+// replyTopic - топик ответов этого экземпляра api-gateway, передается сервисам в reply_to
+func New(producer kafka.ProducerInterface, consumer kafka.ConsumerInterface, configKafka *kafka.Config, config *Config, dispatcher *kafka.Dispatcher, replyTopic string, logs *slog.Logger) *API {
 	api := API{
-		producer:              producer,
-		consumer:              consumer,
-		configKafka:           configKafka,
-		responseNewsCh:        apiChannels.ResponseNewsCh,
-		responseOneNewsCh:     apiChannels.ResponseOneNewsCh,
-		responseCommentsCh:    apiChannels.ResponseCommentsCh,
-		responseAddCommentsCh: apiChannels.ResponseAddCommentsCh,
-		responseCensorCh:      apiChannels.ResponseCensorCh,
-		errorChannel:          apiChannels.ErrorChannel,
+		producer:    producer,
+		consumer:    consumer,
+		configKafka: configKafka,
+		config:      config,
+		dispatcher:  dispatcher,
+		replyTopic:  replyTopic,
+		logs:        logs,
+		rateLimits:  ratelimit.NewMemoryStore(),
+		health: &health{
+			results:      make(map[string]pingResult),
+			checkBrokers: func() error { return kafka.CheckBrokers(configKafka.KafkaBrokers) },
+		},
 	}
 	api.router = mux.NewRouter()
 	// Добавляем middleware для request_id
 	api.router.Use(RequestIDMiddleware)
+	// Добавляем middleware для трассировки
+	api.router.Use(TracingMiddleware)
+	// Добавляем middleware для метрик
+	api.router.Use(MetricsMiddleware)
+	// Добавляем middleware для ограничения частоты запросов
+	api.router.Use(api.RateLimitMiddleware)
 	// Добавляем middleware для считывания тела запроса
-	api.router.Use(ReadBodyMiddleware)
+	api.router.Use(ReadBodyMiddleware(config.Limits().MaxBodyBytes))
 	// Добавляем middleware для логирования
-	api.router.Use(func(next http.Handler) http.Handler { return LoggingMiddleware(next, api.errorChannel) })
+	api.router.Use(func(next http.Handler) http.Handler { return LoggingMiddleware(next, api.logs, config.Redactor()) })
 	// Добавляем middleware для логирования ошибок сервера
-	api.router.Use(func(next http.Handler) http.Handler { return ErrorHandlerMiddleware(next, api.errorChannel) })
+	api.router.Use(func(next http.Handler) http.Handler { return ErrorHandlerMiddleware(next, api.logs) })
 
 	api.endpoints()
 	return &api
@@ -74,6 +84,17 @@ func (api *API) Router() *mux.Router {
 	return api.router
 }
 
+// Handler - обработчик веб-сервера: маршруты API и служебные /metrics, /healthz, /readyz.
+// Служебные маршруты обслуживаются без middleware api, чтобы их опрос не попадал в логи.
+func (api *API) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	mux.HandleFunc("/healthz", api.healthzHandler)
+	mux.HandleFunc("/readyz", api.readyzHandler)
+	mux.Handle("/", api.router)
+	return mux
+}
+
 type LoggingResponseWriter struct {
 	http.ResponseWriter
 	statusCode int
@@ -102,6 +123,15 @@ func RequestIDMiddleware(next http.Handler) http.Handler {
 
 		// Добавляем ID в контекст
 		ctx := context.WithValue(r.Context(), "request_id", requestID)
+
+		// Записи лога с контекстом запроса содержат request_id
+		ctx = logger.ContextWith(ctx, logger.RequestID(requestID))
+
+		// Метаданные для заголовков сообщений Kafka
+		ctx = kafka.ContextWithMetadata(ctx, kafka.Metadata{
+			RequestID: requestID,
+			Source:    logger.GetServiceName(),
+		})
 		r = r.WithContext(ctx)
 
 		// Вызываем следующий обработчик
@@ -109,13 +139,78 @@ func RequestIDMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-// Middleware(2) для считывания тела запроса.
-func ReadBodyMiddleware(next http.Handler) http.Handler {
+// Middleware(2) для трассировки: спан запроса продолжает traceparent клиента
+// и содержит запросы к сервисам через Kafka.
+func TracingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := propagation.TraceContext{}.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+		route := routeName(r)
+		requestID, _ := r.Context().Value("request_id").(string)
+		ctx, span := tracing.Tracer().Start(ctx, r.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", r.Method),
+				attribute.String("http.route", route),
+				attribute.String("request_id", requestID),
+			),
+		)
+		defer span.End()
+
+		lrw := &LoggingResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(lrw, r.WithContext(ctx))
+
+		span.SetAttributes(attribute.Int("http.response.status_code", lrw.statusCode))
+		if lrw.statusCode >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(lrw.statusCode))
+		}
+	})
+}
+
+// Middleware(3) для метрик: время ответа и коды ответов по маршрутам, количество запросов в обработке
+func MetricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		metrics.HTTPInFlight.Inc()
+		defer metrics.HTTPInFlight.Dec()
+
+		start := time.Now()
+		lrw := &LoggingResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(lrw, r)
+
+		route := routeName(r)
+		metrics.HTTPDuration.WithLabelValues(route, r.Method).Observe(time.Since(start).Seconds())
+		metrics.HTTPRequests.WithLabelValues(route, r.Method, strconv.Itoa(lrw.statusCode)).Inc()
+	})
+}
+
+// routeName - шаблон маршрута запроса, чтобы спаны и метрики не зависели от параметров пути
+func routeName(r *http.Request) string {
+	if current := mux.CurrentRoute(r); current != nil {
+		if template, err := current.GetPathTemplate(); err == nil {
+			return template
+		}
+	}
+	return r.URL.Path
+}
+
+// Middleware(4) для считывания тела запроса не длиннее maxBytes байт, более длинное тело - ответ 413.
+func ReadBodyMiddleware(maxBytes int64) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return readBody(next, maxBytes)
+	}
+}
+
+func readBody(next http.Handler, maxBytes int64) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Считываем тело запроса
-		body, err := ioutil.ReadAll(r.Body)
+		body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxBytes))
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeProblem(w, r, CodePayloadTooLarge, fmt.Sprintf("request body is larger than %d bytes", maxBytes), nil)
+			return
+		}
 		if err != nil {
-			http.Error(w, "ошибка чтения тела запроса", http.StatusInternalServerError)
+			writeProblem(w, r, CodeInternal, "failed to read request body", nil)
 			return
 		}
 		defer r.Body.Close()
@@ -131,45 +226,50 @@ func ReadBodyMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-// Middleware(3) для логирования запросов
-func LoggingMiddleware(next http.Handler, errorChan chan<- error) http.Handler {
+// Middleware(5) для логирования запросов.
+// Тело запроса и адрес клиента записываются после скрытия данных по правилам redact.
+func LoggingMiddleware(next http.Handler, logs *slog.Logger, redact *logger.Redactor) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Получаем тело запроса из контекста
 		body, ok := r.Context().Value("requestBody").([]byte)
 		if !ok {
-			http.Error(w, "ошибка получения тела запроса", http.StatusInternalServerError)
+			writeProblem(w, r, CodeInternal, "request body is not available", nil)
 			return
 		}
 
 		// Создаем кастомный ResponseWriter
-		lrw := &LoggingResponseWriter{ResponseWriter: w}
+		lrw := &LoggingResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		start := time.Now()
 
 		// Вызываем следующий обработчик
 		next.ServeHTTP(lrw, r)
 
-		requestID := r.Context().Value("request_id").(string)
-
-		// Получаем HTTP-код ответа из ответа
-		statusCode := lrw.statusCode
-
-		errorChan <- fmt.Errorf("RequestID:%v, RemoteAddr:%v, StatusCode:%v, %v[%v]", requestID, r.RemoteAddr, statusCode, r.URL.Path, string(body))
-
+		// request_id добавляется из контекста запроса
+		logs.LogAttrs(r.Context(), slog.LevelInfo, "http request",
+			slog.Int(logger.KeyStatusCode, lrw.statusCode),
+			slog.String(logger.KeyRemoteAddr, redact.Addr(r.RemoteAddr)),
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			redact.Body(body),
+			logger.Latency(time.Since(start)),
+		)
 	})
 }
 
-// Middleware(4) для для обработки ошибок
-func ErrorHandlerMiddleware(next http.Handler, errorChan chan<- error) http.Handler {
+// Middleware(6) для для обработки ошибок
+func ErrorHandlerMiddleware(next http.Handler, logs *slog.Logger) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Создание кастомного ResponseWriter для перехвата записи ошибок
 		crw := &customResponseWriter{ResponseWriter: w}
 		next.ServeHTTP(crw, r)
 
-		// Проверка на наличие ошибок
-		if crw.statusCode >= 400 {
-			logMessage := crw.errorMessage
-			if logMessage != "" {
-				errorChan <- fmt.Errorf("%v, StatusCode:%v", logMessage, crw.statusCode)
+		// Проверка на наличие ошибок: ошибки сервера - уровень error, ошибки клиента - warn
+		if crw.statusCode >= 400 && crw.errorMessage != "" {
+			level := slog.LevelWarn
+			if crw.statusCode >= http.StatusInternalServerError {
+				level = slog.LevelError
 			}
+			logs.LogAttrs(r.Context(), level, crw.errorMessage, slog.Int(logger.KeyStatusCode, crw.statusCode))
 		}
 	})
 }
@@ -193,12 +293,29 @@ func (crw *customResponseWriter) WriteHeader(code int) {
 // Регистрация обработчиков API.
 func (api *API) endpoints() {
 
-	api.router.HandleFunc("/", api.templateHandler).Methods(http.MethodGet, http.MethodOptions)
-	api.router.HandleFunc("/news/{rubric}/{countNews}", api.newsHandler).Methods(http.MethodGet, http.MethodOptions)
-	api.router.HandleFunc("/newsDetailed", api.newsDetailedHandler).Methods(http.MethodGet, http.MethodOptions)
-	api.router.HandleFunc("/comments", api.addCommentsHandler).Methods(http.MethodPost, http.MethodOptions)
+	// Имена маршрутов - ключи лимитов rate_limit.routes в configAPI.json
+	api.router.HandleFunc("/", api.templateHandler).Methods(http.MethodGet, http.MethodOptions).Name("index")
+	api.endpointsV1()
+
+	// Маршруты без версии оставлены для UI, новым клиентам - /api/v1
+	api.router.HandleFunc("/news/{rubric}/{countNews}", deprecated(api.newsHandler)).Methods(http.MethodGet, http.MethodOptions).Name("news")
+	api.router.HandleFunc("/newsDetailed", deprecated(api.newsDetailedHandler)).Methods(http.MethodGet, http.MethodOptions).Name("news_detailed")
+	api.router.HandleFunc("/comments", deprecated(api.addCommentsHandler)).Methods(http.MethodPost, http.MethodOptions).Name("comments_add")
 
-	api.router.PathPrefix("/").Handler(http.StripPrefix("/", http.FileServer(http.Dir("./ui"))))
+	// Спецификация OpenAPI маршрутов выше и страница документации
+	api.router.HandleFunc("/openapi.json", api.openAPIHandler).Methods(http.MethodGet, http.MethodOptions).Name("openapi")
+	api.router.HandleFunc("/docs", api.docsHandler).Methods(http.MethodGet, http.MethodOptions).Name("docs")
+
+	api.router.PathPrefix("/").Handler(http.StripPrefix("/", http.FileServer(http.Dir("./ui")))).Name("static")
+}
+
+// deprecated - маршрут без версии: заголовок Deprecation сообщает клиентам о переходе на /api/v1
+func deprecated(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", "<"+APIPrefixV1+">; rel=\"successor-version\"")
+		next(w, r)
+	}
 }
 
 // Базовый маршрут.
@@ -208,437 +325,359 @@ func (api *API) templateHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Отправляем HTML страницу с данными
 	if err := tmpl.ExecuteTemplate(w, "base", nil); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		api.logs.ErrorContext(r.Context(), "failed to render template", logger.Err(err))
+		writeProblem(w, r, CodeInternal, "failed to render page", nil)
 		return
 	}
 
 }
 
-// Получение всех новостей.
-func (api *API) newsHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	if r.Method == http.MethodOptions {
-		return
+// request отправляет сообщение в топик сервиса и ожидает ответ на него до дедлайна контекста.
+// Ответ приходит в топик экземпляра и выбирается диспетчером по request_id и типу запроса.
+// Топик ответа и дедлайн передаются сервису в заголовках сообщения,
+// спан запроса включает отправку и ожидание ответа.
+func (api *API) request(ctx context.Context, topic, requestID, typeQuery string, message contracts.Message, reply contracts.Message) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "request "+topic,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("request_id", requestID),
+			attribute.String("type_query", typeQuery),
+		),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	md := kafka.MetadataFromContext(ctx)
+	md.RequestID = requestID
+	md.ReplyTo = api.replyTopic
+	md.Deadline = kafka.DeadlineMs(ctx)
+
+	kafkaMessage, err := kafka.NewMessage(kafka.ContextWithMetadata(ctx, md), api.configKafka.Codec(), topic, message)
+	if err != nil {
+		return err
 	}
 
-	vars := mux.Vars(r)
-	rubric := vars["rubric"]
-	countNewsStr := vars["countNews"]
-	countNews, err := strconv.Atoi(countNewsStr)
+	// Регистрируем ожидание до отправки, чтобы не пропустить быстрый ответ
+	replyCh, unregister, err := api.dispatcher.Register(requestID, typeQuery)
 	if err != nil {
-		http.Error(w, "Invalid count parameter", http.StatusBadRequest)
-		return
+		return err
 	}
+	defer unregister()
 
-	// Получение параметров из запроса
-	filter := r.URL.Query().Get("filter")
-	if filter == "undefined" {
-		filter = ""
+	// Отправка сообщения в Kafka
+	err = api.producer.Send(ctx, kafkaMessage)
+	if err != nil {
+		return err
 	}
 
-	pageStr := r.URL.Query().Get("page")
-	pageCurr := 1
-	if pageStr != "" {
-		pageCurr, err = strconv.Atoi(pageStr)
-		if err != nil {
-			http.Error(w, "Invalid page parameter", http.StatusBadRequest)
-			return
-		}
+	// Ожидание ответа
+	msg, err := api.dispatcher.Wait(ctx, replyCh)
+	if err != nil {
+		return fmt.Errorf("RequestID:%v, Type:%v: %w", requestID, typeQuery, err)
 	}
-	request_id := r.Context().Value("request_id").(string)
 
-	sendMessage := kafka.SendMessServiceNews{
-		ID:        request_id,
+	if err := kafka.Decode(msg, reply); err != nil {
+		return fmt.Errorf("RequestID:%v, Type:%v: %w: %w", requestID, typeQuery, kafka.ErrInvalidReply, err)
+	}
+	return nil
+}
+
+// newsList - запрос страницы списка новостей в service-news. Рубрика % - все рубрики.
+func (api *API) newsList(ctx context.Context, requestID, rubric string, countNews int, filter string, page int) (contracts.NewsReply, error) {
+	sendMessage := contracts.NewsRequest{
+		ID:        requestID,
 		Name:      logger.GetServiceName(),
-		Status:    192,
-		TypeQuery: "News",
+		TypeQuery: contracts.TypeNews,
 		Rubric:    rubric,
 		CountNews: countNews,
 		Filter:    filter,
-		Page:      pageCurr,
-	}
-
-	bytesMessage, err := json.Marshal(sendMessage)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		Page:      page,
 	}
 
-	// Десериализация сообщения в структуру
-	var serviceNews kafka.GetMessServiceNews
-	serviceNews.Status = 0
-
-	// Запуск обработки запроса в отдельной гоурутине
-	wg := sync.WaitGroup{}
-	wg.Add(1)
-	go func(request_id string, bytesMessage []byte) {
-		defer wg.Done()
-
-		// Отправка сообщения в Kafka
-		err = api.producer.SendMessage(api.configKafka.TopicResponseNews, request_id, bytesMessage)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-
-		// Чтение сообщений Kafka
-		select {
-		case msg, ok := <-api.responseNewsCh:
-
-			if !ok {
-				api.errorChannel <- fmt.Errorf("response channel closed")
-				return
-			}
-
-			if err := json.Unmarshal(msg.Value, &serviceNews); err != nil {
-				api.errorChannel <- err
-				return
-			}
-
-			if serviceNews.ID != request_id || serviceNews.TypeQuery != "News" {
-				api.errorChannel <- fmt.Errorf("error ID and Type message")
-				return
-			}
-
-		case <-time.After(3 * time.Second):
-			api.errorChannel <- fmt.Errorf("timeout waiting for response")
-			return
-		}
-	}(request_id, bytesMessage)
-
-	// Ожидание завершения обработки запроса в гоурутине
-	wg.Wait()
-
-	if serviceNews.Status == 192 {
-		// Формирование ответа JSON
-		response := map[string]interface{}{
-			"news":     serviceNews.News,
-			"paginate": serviceNews.Paginate,
-		}
-
-		// Отправка ответа клиенту
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(response)
-	} else {
-		http.Error(w, "server error", http.StatusInternalServerError)
-	}
+	var serviceNews contracts.NewsReply
+	err := api.request(ctx, api.configKafka.TopicResponseNews, requestID, sendMessage.TypeQuery, &sendMessage, &serviceNews)
+	return serviceNews, err
 }
 
-// Получение всех comments by news.
-func (api *API) newsDetailedHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	if r.Method == http.MethodOptions {
-		return
+// oneNews - запрос новости по идентификатору в service-news
+func (api *API) oneNews(ctx context.Context, requestID string, idNews int) (contracts.NewsReply, error) {
+	sendMessage := contracts.NewsRequest{
+		ID:        requestID,
+		Name:      logger.GetServiceName(),
+		TypeQuery: contracts.TypeOneNews,
+		IdNews:    idNews,
+		Rubric:    "",
+		CountNews: 1,
+		Filter:    "",
+		Page:      1,
 	}
 
-	// Получение параметров из запроса
-	id_news_str := r.URL.Query().Get("id_news")
-	var err error
-	id_news := 0
-	if id_news_str != "" {
-		id_news, err = strconv.Atoi(id_news_str)
-		if err != nil {
-			http.Error(w, "Invalid id_news parameter", http.StatusBadRequest)
-			return
-		}
-	}
-	request_id := r.Context().Value("request_id").(string)
+	var serviceNews contracts.NewsReply
+	err := api.request(ctx, api.configKafka.TopicResponseNews, requestID, sendMessage.TypeQuery, &sendMessage, &serviceNews)
+	return serviceNews, err
+}
 
-	sendMessage := kafka.SendMessServiceComments{
-		ID:          request_id,
+// newsComments - запрос комментариев к новости в service-comments
+func (api *API) newsComments(ctx context.Context, requestID string, idNews int) (contracts.CommentsReply, error) {
+	sendMessage := contracts.CommentsRequest{
+		ID:          requestID,
 		Name:        logger.GetServiceName(),
-		Status:      192,
-		TypeQuery:   "CommentsByIdNews",
-		IdNews:      id_news,
+		TypeQuery:   contracts.TypeCommentsByIdNews,
+		IdNews:      idNews,
 		CommentTime: 0,
 		UserName:    "",
 		Content:     "",
 	}
 
-	bytesMessage, err := json.Marshal(sendMessage)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
+	var serviceComments contracts.CommentsReply
+	err := api.request(ctx, api.configKafka.TopicResponseComments, requestID, sendMessage.TypeQuery, &sendMessage, &serviceComments)
+	return serviceComments, err
+}
 
-	sendMessageNews := kafka.SendMessServiceNews{
-		ID:        request_id,
-		Name:      logger.GetServiceName(),
-		Status:    192,
-		TypeQuery: "OneNews",
-		IdNews:    id_news,
-		Rubric:    "",
-		CountNews: 1,
-		Filter:    "",
-		Page:      1,
+// addComment - проверка комментария в service-censor и сохранение в service-comments.
+// Время комментария назначает api-gateway, comment_time клиента не используется.
+// comment.ParentId - комментарий, на который дан ответ, его проверяет service-comments.
+// Возвращает сохраненный комментарий с ID и уровнем вложенности из ответа service-comments.
+func (api *API) addComment(ctx context.Context, requestID string, idNews int, comment contracts.Comment) (contracts.Comment, contracts.Reply, error) {
+	sendMessage := contracts.CommentsRequest{
+		ID:          requestID,
+		Name:        logger.GetServiceName(),
+		TypeQuery:   contracts.TypeCommentNew,
+		IdNews:      idNews,
+		CommentTime: time.Now().Unix(),
+		UserName:    comment.UserName,
+		Content:     comment.Content,
+		ParentId:    comment.ParentId,
 	}
 
-	bytesMessageNews, err := json.Marshal(sendMessageNews)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
+	var serviceComments contracts.CommentsReply
 
-	// Отправка сообщения в Kafka Comments
-	err = api.producer.SendMessage(api.configKafka.TopicResponseComments, request_id, bytesMessage)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+	// 1. Проверка комментария в service-censor
+	err := api.request(ctx, api.configKafka.TopicResponseCensor, requestID, sendMessage.TypeQuery, &sendMessage, &serviceComments)
+	if err != nil || !serviceComments.IsOK() {
+		return contracts.Comment{}, serviceComments.Reply, err
 	}
 
-	// Отправка сообщения в Kafka News
-	err = api.producer.SendMessage(api.configKafka.TopicResponseNews, request_id, bytesMessageNews)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+	// 2. Сохранение комментария в service-comments
+	serviceComments = contracts.CommentsReply{}
+	err = api.request(ctx, api.configKafka.TopicResponseComments, requestID, sendMessage.TypeQuery, &sendMessage, &serviceComments)
+	if err != nil || !serviceComments.IsOK() {
+		return contracts.Comment{}, serviceComments.Reply, err
 	}
+	if len(serviceComments.Comments) == 0 {
+		return contracts.Comment{}, contracts.ReplyFail(contracts.StatusInternal, "saved comment missing in reply", nil), nil
+	}
+	return serviceComments.Comments[0], serviceComments.Reply, nil
+}
 
-	// Десериализация сообщения в структуру
-	var serviceComments kafka.GetMessServiceComments
-	var serviceNews kafka.GetMessServiceNews
-
-	serviceNews.Status = 0
-	serviceComments.Status = 0
-
-	// Запуск обработки запроса в отдельной гоурутине
-	wg := sync.WaitGroup{}
-	wg.Add(1)
-	go func(request_id string, bytesMessage []byte) {
-		defer wg.Done()
-
-		select {
-		case msg, ok := <-api.responseCommentsCh:
-
-			if !ok {
-				api.errorChannel <- fmt.Errorf("response channel closed")
-				return
-			}
-
-			if err := json.Unmarshal(msg.Value, &serviceComments); err != nil {
-				api.errorChannel <- err
-				return
-			}
-
-			if serviceComments.ID != request_id || serviceComments.TypeQuery != "CommentsByIdNews" {
-				api.errorChannel <- fmt.Errorf("error ID and Type message")
-				return
-			}
-
-		case <-time.After(3 * time.Second):
-			api.errorChannel <- fmt.Errorf("timeout waiting for response")
-			return
-		}
-	}(request_id, bytesMessage)
-
-	wg.Add(1)
-	go func(request_id string, bytesMessageNews []byte) {
-		defer wg.Done()
-
-		select {
-		case msg, ok := <-api.responseOneNewsCh:
-
-			if !ok {
-				api.errorChannel <- fmt.Errorf("response channel closed")
-				return
-			}
-
-			if err := json.Unmarshal(msg.Value, &serviceNews); err != nil {
-				api.errorChannel <- err
-				return
-			}
-
-			if serviceNews.ID != request_id || serviceNews.TypeQuery != "OneNews" {
-				api.errorChannel <- fmt.Errorf("error ID and Type message")
-				return
-			}
+// updateComment - проверка нового текста комментария в service-censor и изменение в service-comments.
+// Автора userName и время изменения проверяет и назначает service-comments. Возвращает измененный комментарий.
+func (api *API) updateComment(ctx context.Context, requestID string, idNews, idComment int, userName, content string) (contracts.Comment, contracts.Reply, error) {
+	sendMessage := contracts.CommentsRequest{
+		ID:        requestID,
+		Name:      logger.GetServiceName(),
+		TypeQuery: contracts.TypeCommentUpdate,
+		IdNews:    idNews,
+		UserName:  userName,
+		Content:   content,
+		IdComment: idComment,
+	}
 
-		case <-time.After(3 * time.Second):
-			api.errorChannel <- fmt.Errorf("timeout waiting for response")
-			return
-		}
-	}(request_id, bytesMessageNews)
+	var serviceComments contracts.CommentsReply
 
-	wg.Wait()
+	// 1. Проверка нового текста в service-censor
+	err := api.request(ctx, api.configKafka.TopicResponseCensor, requestID, sendMessage.TypeQuery, &sendMessage, &serviceComments)
+	if err != nil || !serviceComments.IsOK() {
+		return contracts.Comment{}, serviceComments.Reply, err
+	}
 
-	if serviceNews.Status == 192 || serviceComments.Status == 192 {
-		// Формирование ответа JSON
-		response := map[string]interface{}{
-			"comments": serviceComments.Comments,
-			"news":     serviceNews.News,
-			"idNews":   id_news,
-		}
+	// 2. Изменение комментария в service-comments
+	serviceComments = contracts.CommentsReply{}
+	err = api.request(ctx, api.configKafka.TopicResponseComments, requestID, sendMessage.TypeQuery, &sendMessage, &serviceComments)
+	if err != nil || !serviceComments.IsOK() {
+		return contracts.Comment{}, serviceComments.Reply, err
+	}
+	if len(serviceComments.Comments) == 0 {
+		return contracts.Comment{}, contracts.ReplyFail(contracts.StatusInternal, "updated comment missing in reply", nil), nil
+	}
+	return serviceComments.Comments[0], serviceComments.Reply, nil
+}
 
-		// Отправка ответа клиенту
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(response)
-	} else {
-		http.Error(w, "server error", http.StatusInternalServerError)
+// deleteComment - удаление комментария в service-comments, автора userName проверяет service-comments
+func (api *API) deleteComment(ctx context.Context, requestID string, idNews, idComment int, userName string) (contracts.Reply, error) {
+	sendMessage := contracts.CommentsRequest{
+		ID:        requestID,
+		Name:      logger.GetServiceName(),
+		TypeQuery: contracts.TypeCommentDelete,
+		IdNews:    idNews,
+		UserName:  userName,
+		IdComment: idComment,
 	}
 
+	var serviceComments contracts.CommentsReply
+	err := api.request(ctx, api.configKafka.TopicResponseComments, requestID, sendMessage.TypeQuery, &sendMessage, &serviceComments)
+	return serviceComments.Reply, err
 }
 
-// Добавление comments.
-func (api *API) addCommentsHandler(w http.ResponseWriter, r *http.Request) {
+// replyOK - проверка результата запроса к сервису. Если сервис не ответил
+// или ответил ошибкой, клиенту отправляется ответ с ошибкой.
+func (api *API) replyOK(w http.ResponseWriter, r *http.Request, reply contracts.Reply, err error) bool {
+	if err != nil {
+		api.logs.ErrorContext(r.Context(), "service request failed", logger.Err(err))
+		writeRequestError(w, r, err)
+		return false
+	}
+	if !reply.IsOK() {
+		writeError(w, r, reply)
+		return false
+	}
+	return true
+}
 
+// Получение всех новостей.
+func (api *API) newsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
 	if r.Method == http.MethodOptions {
 		return
 	}
 
+	vars := mux.Vars(r)
+	rubric := vars["rubric"]
+	countNewsStr := vars["countNews"]
+
 	// Получение параметров из запроса
-	id_news_str := r.URL.Query().Get("id_news")
-	var err error
-	id_news := 0
-	if id_news_str != "" {
-		id_news, err = strconv.Atoi(id_news_str)
-		if err != nil {
-			http.Error(w, "Invalid id_news parameter", http.StatusBadRequest)
-			return
-		}
+	filter := r.URL.Query().Get("filter")
+	if filter == "undefined" {
+		filter = ""
 	}
-	request_id := r.Context().Value("request_id").(string)
+	pageStr := r.URL.Query().Get("page")
 
-	var comment kafka.Comment
-	err = json.NewDecoder(r.Body).Decode(&comment)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if errs := Validate(api.config.Limits().newsFields(rubric, countNewsStr, filter, pageStr)...); errs != nil {
+		writeInvalid(w, r, errs)
 		return
 	}
 
-	sendMessage := kafka.SendMessServiceComments{
-		ID:          request_id,
-		Name:        logger.GetServiceName(),
-		Status:      192,
-		TypeQuery:   "CommentNew",
-		IdNews:      id_news,
-		CommentTime: comment.CommentTime,
-		UserName:    comment.UserName,
-		Content:     comment.Content,
-	}
-
-	bytesMessage, err := json.Marshal(sendMessage)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+	// Значения проверены правилами Int
+	countNews, _ := strconv.Atoi(countNewsStr)
+	pageCurr := 1
+	if pageStr != "" {
+		pageCurr, _ = strconv.Atoi(pageStr)
 	}
+	request_id := r.Context().Value("request_id").(string)
 
-	var serviceComments kafka.GetMessServiceComments
+	ctx, cancel := context.WithTimeout(r.Context(), api.config.Timeout("news"))
+	defer cancel()
 
-	// 1. Отправка сообщения в Kafka Censor
-	err = api.producer.SendMessage(api.configKafka.TopicResponseCensor, request_id, bytesMessage)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	serviceNews, err := api.newsList(ctx, request_id, rubric, countNews, filter, pageCurr)
+	if !api.replyOK(w, r, serviceNews.Reply, err) {
 		return
 	}
 
-	select {
-	case msg, ok := <-api.responseCensorCh:
-
-		if !ok {
-			api.errorChannel <- fmt.Errorf("response channel closed")
-			return
-		}
-
-		if err := json.Unmarshal(msg.Value, &serviceComments); err != nil {
-			api.errorChannel <- err
-			return
-		}
+	// Формирование ответа JSON
+	response := map[string]interface{}{
+		"news":     serviceNews.News,
+		"paginate": serviceNews.Paginate,
+	}
 
-		if serviceComments.ID != request_id || serviceComments.TypeQuery != "CommentNew" {
-			api.errorChannel <- fmt.Errorf("error ID and Type message")
-			return
-		}
+	// Отправка ответа клиенту
+	json.NewEncoder(w).Encode(response)
+}
 
-	case <-time.After(3 * time.Second):
-		api.errorChannel <- fmt.Errorf("timeout waiting for response")
+// Получение всех comments by news.
+func (api *API) newsDetailedHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method == http.MethodOptions {
 		return
 	}
 
-	if serviceComments.Status != 192 {
-		w.WriteHeader(http.StatusBadRequest)
+	// Получение параметров из запроса
+	id_news_str := r.URL.Query().Get("id_news")
+	if errs := Validate(idNewsField(id_news_str)); errs != nil {
+		writeInvalid(w, r, errs)
 		return
 	}
+	id_news, _ := strconv.Atoi(id_news_str)
+	request_id := r.Context().Value("request_id").(string)
 
-	// 2. Отправка сообщения в Kafka Comments
-	err = api.producer.SendMessage(api.configKafka.TopicResponseComments, request_id, bytesMessage)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
+	ctx, cancel := context.WithTimeout(r.Context(), api.config.Timeout("news_detailed"))
+	defer cancel()
 
-	select {
-	case msg, ok := <-api.responseAddCommentsCh:
+	var serviceComments contracts.CommentsReply
+	var serviceNews contracts.NewsReply
+	var errComments, errNews error
 
-		if !ok {
-			api.errorChannel <- fmt.Errorf("response channel closed")
-			return
-		}
+	// Запросы в service-comments и service-news выполняются параллельно
+	wg := sync.WaitGroup{}
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		serviceComments, errComments = api.newsComments(ctx, request_id, id_news)
+	}()
+	go func() {
+		defer wg.Done()
+		serviceNews, errNews = api.oneNews(ctx, request_id, id_news)
+	}()
+	wg.Wait()
 
-		if err := json.Unmarshal(msg.Value, &serviceComments); err != nil {
-			api.errorChannel <- err
+	for _, err := range []error{errNews, errComments} {
+		if err != nil {
+			api.logs.ErrorContext(r.Context(), "service request failed", logger.Err(err))
+			writeRequestError(w, r, err)
 			return
 		}
+	}
 
-		if serviceComments.ID != request_id || serviceComments.TypeQuery != "CommentNew" {
-			api.errorChannel <- fmt.Errorf("error ID and Type message")
+	// Статья важнее комментариев: сначала проверяем ответ service-news
+	for _, reply := range []contracts.Reply{serviceNews.Reply, serviceComments.Reply} {
+		if !reply.IsOK() {
+			writeError(w, r, reply)
 			return
 		}
-
-	case <-time.After(3 * time.Second):
-		api.errorChannel <- fmt.Errorf("timeout waiting for response")
-		return
 	}
 
-	// Отправка ответа клиенту
-	if serviceComments.Status == 192 {
-		w.WriteHeader(http.StatusOK)
-	} else {
-		w.WriteHeader(http.StatusBadRequest)
+	// Формирование ответа JSON
+	response := map[string]interface{}{
+		"comments": serviceComments.Comments,
+		"news":     serviceNews.News,
+		"idNews":   id_news,
 	}
 
-	/*// 1. check comment service-censor
-	wg := sync.WaitGroup{}
-	wg.Add(1)
-	go func(request_id string, bytesMessage []byte) {
-		defer wg.Done()
+	// Отправка ответа клиенту
+	json.NewEncoder(w).Encode(response)
+}
 
-		// Отправка сообщения в Kafka Comments
-		err = api.producer.SendMessage(api.configKafka.TopicResponseComments, request_id, bytesMessage)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
+// Добавление comments.
+func (api *API) addCommentsHandler(w http.ResponseWriter, r *http.Request) {
 
-		select {
-		case msg, ok := <-api.responseAddCommentsCh:
+	if r.Method == http.MethodOptions {
+		return
+	}
 
-			if !ok {
-				api.errorChannel <- fmt.Errorf("response channel closed")
-				return
-			}
+	// Получение параметров из запроса
+	id_news_str := r.URL.Query().Get("id_news")
 
-			if err := json.Unmarshal(msg.Value, &serviceComments); err != nil {
-				api.errorChannel <- err
-				return
-			}
+	var comment contracts.Comment
+	if err := json.NewDecoder(r.Body).Decode(&comment); err != nil {
+		writeInvalid(w, r, map[string]string{"body": "invalid JSON: " + err.Error()})
+		return
+	}
 
-			if serviceComments.ID != request_id || serviceComments.TypeQuery != "CommentNew" {
-				api.errorChannel <- fmt.Errorf("error ID and Type message")
-				return
-			}
+	fields := append([]Field{idNewsField(id_news_str)}, api.config.Limits().commentFields(comment)...)
+	if errs := Validate(fields...); errs != nil {
+		writeInvalid(w, r, errs)
+		return
+	}
+	id_news, _ := strconv.Atoi(id_news_str)
+	request_id := r.Context().Value("request_id").(string)
 
-		case <-time.After(3 * time.Second):
-			api.errorChannel <- fmt.Errorf("timeout waiting for response")
-			return
-		}
-	}(request_id, bytesMessage)
+	// Дедлайн общий для проверки цензурой и сохранения комментария
+	ctx, cancel := context.WithTimeout(r.Context(), api.config.Timeout("comments_add"))
+	defer cancel()
 
-	wg.Wait()
+	_, reply, err := api.addComment(ctx, request_id, id_news, comment)
+	if !api.replyOK(w, r, reply, err) {
+		return
+	}
 
 	// Отправка ответа клиенту
-	if serviceComments.Status == 192 {
-		w.WriteHeader(http.StatusOK)
-	} else {
-		w.WriteHeader(http.StatusBadRequest)
-	}*/
-
+	w.WriteHeader(http.StatusOK)
 }
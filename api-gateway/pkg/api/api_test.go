@@ -0,0 +1,76 @@
+package api
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"news-kafka/api-gateway/pkg/logger"
+	"news-kafka/api-gateway/pkg/metrics"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// Метрики группируются по шаблону маршрута, а не по пути запроса
+func TestMetricsMiddleware(t *testing.T) {
+	router := mux.NewRouter()
+	router.Use(MetricsMiddleware)
+	router.HandleFunc("/news/{rubric}/{countNews}", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, float64(1), testutil.ToFloat64(metrics.HTTPInFlight))
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	requests := metrics.HTTPRequests.WithLabelValues("/news/{rubric}/{countNews}", http.MethodGet, "404")
+	before := testutil.ToFloat64(requests)
+
+	for _, path := range []string{"/news/sport/10", "/news/tech/5"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	assert.Equal(t, before+2, testutil.ToFloat64(requests))
+	assert.Equal(t, float64(0), testutil.ToFloat64(metrics.HTTPInFlight))
+}
+
+// В лог запросов не попадают имя пользователя, текст комментария и полный IP клиента
+func TestLoggingMiddleware_Redacts(t *testing.T) {
+	var logs bytes.Buffer
+	router := mux.NewRouter()
+	router.Use(ReadBodyMiddleware(1024))
+	router.Use(func(next http.Handler) http.Handler {
+		return LoggingMiddleware(next, slog.New(slog.NewJSONHandler(&logs, nil)), logger.DefaultRedactor())
+	})
+	router.HandleFunc("/comments/add", func(w http.ResponseWriter, r *http.Request) {})
+
+	req := httptest.NewRequest(http.MethodPost, "/comments/add?id_news=1", strings.NewReader(`{"user_name":"gopher","content":"call +7 999 123-45-67","comment_time":1730100873}`))
+	req.RemoteAddr = "203.0.113.57:41234"
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.NotContains(t, logs.String(), "gopher")
+	assert.NotContains(t, logs.String(), "123-45-67")
+	assert.NotContains(t, logs.String(), "203.0.113.57")
+	assert.Contains(t, logs.String(), `"remote_addr":"203.0.113.0:41234"`)
+	assert.Contains(t, logs.String(), `"comment_time":1730100873`)
+}
+
+func TestReadBodyMiddleware_TooLarge(t *testing.T) {
+	called := false
+	router := mux.NewRouter()
+	router.Use(ReadBodyMiddleware(20))
+	router.HandleFunc("/comments/add", func(w http.ResponseWriter, r *http.Request) { called = true })
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/comments/add", strings.NewReader(`{"user_name":"gopher"}`)))
+	assert.False(t, called)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	assert.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(), `"code":"payload_too_large"`)
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/comments/add", strings.NewReader(`{"user_name":"b"}`)))
+	assert.True(t, called)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
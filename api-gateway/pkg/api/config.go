@@ -0,0 +1,149 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"news-kafka/api-gateway/pkg/logger"
+	"news-kafka/api-gateway/pkg/ratelimit"
+	"time"
+)
+
+// Значения по умолчанию, если они не заданы в конфигурации
+const (
+	defaultTimeout       = 3 * time.Second  //Время ожидания ответа для маршрута
+	defaultPingInterval  = 5 * time.Second  //Интервал отправки Ping сервисам
+	defaultShutdownGrace = 10 * time.Second //Время на завершение запросов при остановке
+)
+
+// Config - структура для хранения конфигурации API
+type Config struct {
+	TimeoutsMs      map[string]int      `json:"timeouts_ms"`       //Время ожидания ответа по маршрутам, ключ default - для остальных
+	PingIntervalMs  int                 `json:"ping_interval_ms"`  //Интервал отправки Ping сервисам
+	PingMaxAgeMs    int                 `json:"ping_max_age_ms"`   //Возраст последнего ответа на Ping, после которого сервис не готов
+	ShutdownGraceMs int                 `json:"shutdown_grace_ms"` //Время на завершение запросов при остановке
+	Redact          *logger.RedactRules `json:"redact"`            //Скрытие данных в логе запросов, по умолчанию logger.DefaultRedactRules
+	RateLimit       *RateLimitConfig    `json:"rate_limit"`        //Ограничение частоты запросов, без него не ограничивается
+	Validation      *ValidationConfig   `json:"validation"`        //Ограничения параметров запросов, по умолчанию - без списка рубрик
+
+	redactor *logger.Redactor
+}
+
+// Способ определения клиента для лимитов
+const (
+	ClientKeyIP     = "ip"      //IP-адрес клиента
+	ClientKeyAPIKey = "api_key" //Ключ API из заголовка, если он есть в clients, иначе IP-адрес
+)
+
+// RateLimitConfig - лимиты частоты запросов: корзина токенов на каждую пару маршрут - клиент
+type RateLimitConfig struct {
+	ClientKey         string                     `json:"client_key"`          //ip или api_key
+	APIKeyHeader      string                     `json:"api_key_header"`      //Заголовок с ключом API, по умолчанию X-API-Key
+	TrustForwardedFor bool                       `json:"trust_forwarded_for"` //Последний IP-адрес X-Forwarded-For, если api-gateway за прокси
+	Default           ratelimit.Limit            `json:"default"`             //Лимит для маршрутов без своего лимита
+	Routes            map[string]ratelimit.Limit `json:"routes"`              //Лимиты по имени маршрута: news, news_detailed, comments_add, ...
+	Clients           map[string]ratelimit.Limit `json:"clients"`             //Лимиты отдельных клиентов по ключу API или IP-адресу, для всех маршрутов
+}
+
+// Заголовок с ключом API по умолчанию
+const defaultAPIKeyHeader = "X-API-Key"
+
+// Limit - лимит для маршрута и клиента: лимит клиента, затем маршрута, затем по умолчанию
+func (c *RateLimitConfig) Limit(route, client string) ratelimit.Limit {
+	if limit, ok := c.Clients[client]; ok {
+		return limit
+	}
+	if limit, ok := c.Routes[route]; ok {
+		return limit
+	}
+	return c.Default
+}
+
+// ReadConfig - функция для чтения конфигурации из файла
+func ReadConfig(filePath string) (*Config, error) {
+	// Чтение содержимого файла
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	// Декодирование JSON данных
+	var config Config
+	err = json.Unmarshal(data, &config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config data: %w", err)
+	}
+
+	if rl := config.RateLimit; rl != nil {
+		if rl.ClientKey == "" {
+			rl.ClientKey = ClientKeyIP
+		}
+		if rl.ClientKey != ClientKeyIP && rl.ClientKey != ClientKeyAPIKey {
+			return nil, fmt.Errorf("invalid rate_limit client_key: %v", rl.ClientKey)
+		}
+		if rl.APIKeyHeader == "" {
+			rl.APIKeyHeader = defaultAPIKeyHeader
+		}
+	}
+
+	if config.Redact != nil {
+		config.redactor, err = logger.NewRedactor(*config.Redact)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redact rules: %w", err)
+		}
+	}
+
+	return &config, nil
+}
+
+// Timeout - время ожидания ответа для маршрута
+func (c *Config) Timeout(route string) time.Duration {
+	if ms, ok := c.TimeoutsMs[route]; ok && ms > 0 {
+		return time.Duration(ms) * time.Millisecond
+	}
+	if ms, ok := c.TimeoutsMs["default"]; ok && ms > 0 {
+		return time.Duration(ms) * time.Millisecond
+	}
+	return defaultTimeout
+}
+
+// PingInterval - интервал отправки Ping сервисам
+func (c *Config) PingInterval() time.Duration {
+	if c.PingIntervalMs > 0 {
+		return time.Duration(c.PingIntervalMs) * time.Millisecond
+	}
+	return defaultPingInterval
+}
+
+// PingMaxAge - возраст последнего успешного ответа на Ping, после которого сервис считается неготовым.
+// По умолчанию три интервала отправки Ping.
+func (c *Config) PingMaxAge() time.Duration {
+	if c.PingMaxAgeMs > 0 {
+		return time.Duration(c.PingMaxAgeMs) * time.Millisecond
+	}
+	return 3 * c.PingInterval()
+}
+
+// ShutdownGrace - время на завершение текущих HTTP-запросов при остановке api-gateway
+func (c *Config) ShutdownGrace() time.Duration {
+	if c.ShutdownGraceMs > 0 {
+		return time.Duration(c.ShutdownGraceMs) * time.Millisecond
+	}
+	return defaultShutdownGrace
+}
+
+// Redactor - правила скрытия данных в логе запросов
+func (c *Config) Redactor() *logger.Redactor {
+	if c.redactor != nil {
+		return c.redactor
+	}
+	return logger.DefaultRedactor()
+}
+
+// Limits - ограничения параметров запросов, незаданные значения - по умолчанию
+func (c *Config) Limits() ValidationConfig {
+	if c.Validation == nil {
+		return ValidationConfig{}.withDefaults()
+	}
+	return c.Validation.withDefaults()
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"io/ioutil"
+	"news-kafka/api-gateway/pkg/logger"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadConfig_Timeouts(t *testing.T) {
+	tmpFile, err := ioutil.TempFile("", "configAPI.json")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	configData := `{"timeouts_ms": {"default": 2000, "comments_add": 5000}}`
+	if _, err := tmpFile.Write([]byte(configData)); err != nil {
+		t.Fatalf("failed to write to temp file: %v", err)
+	}
+	tmpFile.Close()
+
+	config, err := ReadConfig(tmpFile.Name())
+	assert.NoError(t, err)
+
+	assert.Equal(t, 5*time.Second, config.Timeout("comments_add"))
+	assert.Equal(t, 2*time.Second, config.Timeout("news"))
+	assert.Equal(t, defaultTimeout, (&Config{}).Timeout("news"))
+}
+
+func TestConfig_Ping(t *testing.T) {
+	assert.Equal(t, defaultPingInterval, (&Config{}).PingInterval())
+	assert.Equal(t, 3*defaultPingInterval, (&Config{}).PingMaxAge())
+
+	config := &Config{PingIntervalMs: 1000}
+	assert.Equal(t, time.Second, config.PingInterval())
+	assert.Equal(t, 3*time.Second, config.PingMaxAge())
+
+	config.PingMaxAgeMs = 500
+	assert.Equal(t, 500*time.Millisecond, config.PingMaxAge())
+}
+
+func TestConfig_ShutdownGrace(t *testing.T) {
+	assert.Equal(t, defaultShutdownGrace, (&Config{}).ShutdownGrace())
+	assert.Equal(t, 2*time.Second, (&Config{ShutdownGraceMs: 2000}).ShutdownGrace())
+}
+
+func TestReadConfig_FileNotFound(t *testing.T) {
+	_, err := ReadConfig("non_existing_file.json")
+	assert.Error(t, err)
+}
+
+func TestReadConfig_Redact(t *testing.T) {
+	config, err := ReadConfig("../../configAPI.json")
+	assert.NoError(t, err)
+	assert.Equal(t, "192.168.1.0:1234", config.Redactor().Addr("192.168.1.7:1234"))
+
+	// Без правил в конфигурации используются правила по умолчанию
+	assert.Equal(t, logger.DefaultRedactor(), (&Config{}).Redactor())
+
+	tmpFile := filepath.Join(t.TempDir(), "configAPI.json")
+	assert.NoError(t, os.WriteFile(tmpFile, []byte(`{"redact": {"patterns": ["("]}}`), 0644))
+	_, err = ReadConfig(tmpFile)
+	assert.ErrorContains(t, err, "invalid redact rules")
+}
+
+func TestReadConfig_RateLimit(t *testing.T) {
+	config, err := ReadConfig("../../configAPI.json")
+	assert.NoError(t, err)
+	rl := config.RateLimit
+	assert.Equal(t, ClientKeyIP, rl.ClientKey)
+	assert.Equal(t, rl.Routes["comments_add"], rl.Limit("comments_add", "10.0.0.1"))
+	assert.Equal(t, rl.Default, rl.Limit("index", "10.0.0.1"))
+
+	tmpFile := filepath.Join(t.TempDir(), "configAPI.json")
+	assert.NoError(t, os.WriteFile(tmpFile, []byte(`{"rate_limit": {"client_key": "cookie"}}`), 0644))
+	_, err = ReadConfig(tmpFile)
+	assert.ErrorContains(t, err, "invalid rate_limit client_key")
+}
@@ -0,0 +1,38 @@
+package api
+
+import (
+	"net/http"
+	"news-kafka/api-gateway/pkg/logger"
+	"os"
+)
+
+// Файлы документации API, пути относительно рабочего каталога api-gateway
+const (
+	openAPIFile = "ui/openapi.json"   //Спецификация OpenAPI 3
+	docsFile    = "ui/html/docs.html" //Страница документации Swagger UI
+)
+
+// Спецификация OpenAPI маршрутов API.
+func (api *API) openAPIHandler(w http.ResponseWriter, r *http.Request) {
+	api.serveDocFile(w, r, openAPIFile, "application/json")
+}
+
+// Страница документации API.
+func (api *API) docsHandler(w http.ResponseWriter, r *http.Request) {
+	api.serveDocFile(w, r, docsFile, "text/html; charset=utf-8")
+}
+
+// serveDocFile - отправка файла документации
+func (api *API) serveDocFile(w http.ResponseWriter, r *http.Request, name, contentType string) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		api.logs.ErrorContext(r.Context(), "failed to read documentation", logger.Err(err))
+		writeProblem(w, r, CodeInternal, "documentation is not available", nil)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Write(data)
+}
@@ -0,0 +1,183 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Маршруты, не входящие в REST API и не описанные в спецификации
+var undocumentedRoutes = map[string]bool{"index": true, "static": true, "openapi": true, "docs": true}
+
+// openAPIParameter - параметр операции или ссылка на параметр из components
+type openAPIParameter struct {
+	Ref    string `json:"$ref"`
+	Name   string `json:"name"`
+	In     string `json:"in"`
+	Schema struct {
+		Enum      []string `json:"enum"`
+		Maximum   int      `json:"maximum"`
+		MaxLength int      `json:"maxLength"`
+	} `json:"schema"`
+}
+
+// openAPISpec - часть спецификации, которую проверяют тесты
+type openAPISpec struct {
+	OpenAPI string `json:"openapi"`
+	Paths   map[string]map[string]struct {
+		Parameters []openAPIParameter `json:"parameters"`
+	} `json:"paths"`
+	Components struct {
+		Parameters map[string]openAPIParameter `json:"parameters"`
+		Schemas    map[string]struct {
+			Properties map[string]struct {
+				MaxLength int `json:"maxLength"`
+			} `json:"properties"`
+		} `json:"schemas"`
+	} `json:"components"`
+}
+
+// parameters - параметры операции со ссылками, замененными параметрами из components, по имени
+func (spec openAPISpec) parameters(t *testing.T, path, method string) map[string]openAPIParameter {
+	t.Helper()
+	params := make(map[string]openAPIParameter)
+	for _, p := range spec.Paths[path][method].Parameters {
+		if p.Ref != "" {
+			ref, ok := spec.Components.Parameters[strings.TrimPrefix(p.Ref, "#/components/parameters/")]
+			require.True(t, ok, "unknown parameter %s", p.Ref)
+			p = ref
+		}
+		params[p.Name] = p
+	}
+	return params
+}
+
+func readOpenAPISpec(t *testing.T) openAPISpec {
+	t.Helper()
+	data, err := os.ReadFile("../../" + openAPIFile)
+	require.NoError(t, err)
+	var spec openAPISpec
+	require.NoError(t, json.Unmarshal(data, &spec))
+	return spec
+}
+
+// Маршруты mux и операции спецификации совпадают: пути, методы и параметры пути
+func TestOpenAPI_MatchesRoutes(t *testing.T) {
+	spec := readOpenAPISpec(t)
+	assert.True(t, strings.HasPrefix(spec.OpenAPI, "3."))
+
+	api := &API{config: &Config{}, router: mux.NewRouter()}
+	api.endpoints()
+
+	routes := make(map[string][]string)
+	err := api.router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		// Маршрут подмаршрутизатора /api/v1 без обработчика
+		if route.GetHandler() == nil || undocumentedRoutes[route.GetName()] {
+			return nil
+		}
+		path, err := route.GetPathTemplate()
+		require.NoError(t, err)
+		methods, err := route.GetMethods()
+		require.NoError(t, err, path)
+		for _, method := range methods {
+			// OPTIONS - предварительные запросы CORS, в спецификации не описываются
+			if method != http.MethodOptions {
+				routes[path] = append(routes[path], strings.ToLower(method))
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	documented := make(map[string][]string)
+	pathParam := regexp.MustCompile(`\{([^}]+)\}`)
+	for path, operations := range spec.Paths {
+		for method := range operations {
+			documented[path] = append(documented[path], method)
+
+			var inPath []string
+			for _, p := range spec.parameters(t, path, method) {
+				if p.In == "path" {
+					inPath = append(inPath, p.Name)
+				}
+			}
+			var want []string
+			for _, m := range pathParam.FindAllStringSubmatch(path, -1) {
+				want = append(want, m[1])
+			}
+			assert.ElementsMatch(t, want, inPath, "path parameters of %s %s", method, path)
+		}
+	}
+	for _, methods := range routes {
+		sort.Strings(methods)
+	}
+	for _, methods := range documented {
+		sort.Strings(methods)
+	}
+	assert.Equal(t, routes, documented)
+}
+
+// Ограничения параметров в спецификации совпадают с validation в configAPI.json
+func TestOpenAPI_MatchesValidation(t *testing.T) {
+	spec := readOpenAPISpec(t)
+	config, err := ReadConfig("../../configAPI.json")
+	require.NoError(t, err)
+	limits := config.Limits()
+
+	news := spec.parameters(t, "/news/{rubric}/{countNews}", "get")
+	assert.Equal(t, limits.Rubrics, news["rubric"].Schema.Enum)
+	assert.Equal(t, limits.MaxCountNews, news["countNews"].Schema.Maximum)
+	assert.Equal(t, limits.MaxFilterLen, news["filter"].Schema.MaxLength)
+	assert.Equal(t, limits.MaxPage, news["page"].Schema.Maximum)
+
+	v1News := spec.parameters(t, "/api/v1/news", "get")
+	assert.Equal(t, limits.Rubrics, v1News["rubric"].Schema.Enum)
+	assert.Equal(t, limits.MaxCountNews, v1News["page_size"].Schema.Maximum)
+	assert.Equal(t, limits.MaxFilterLen, v1News["filter"].Schema.MaxLength)
+	assert.Equal(t, limits.MaxPage, v1News["page"].Schema.Maximum)
+
+	v1Comments := spec.parameters(t, "/api/v1/news/{id}/comments", "get")
+	assert.Equal(t, []string{CommentsViewFlat, CommentsViewTree}, v1Comments["view"].Schema.Enum)
+
+	comment := spec.Components.Schemas["NewComment"].Properties
+	assert.Equal(t, limits.MaxUserNameLen, comment["user_name"].MaxLength)
+	assert.Equal(t, limits.MaxContentLen, comment["content"].MaxLength)
+
+	update := spec.Components.Schemas["CommentUpdate"].Properties
+	assert.Equal(t, limits.MaxContentLen, update["content"].MaxLength)
+	assert.Equal(t, limits.MaxUserNameLen, update["user_name"].MaxLength)
+	assert.Equal(t, limits.MaxUserNameLen, spec.Components.Schemas["CommentAuthor"].Properties["user_name"].MaxLength)
+}
+
+func TestOpenAPIHandler(t *testing.T) {
+	api := &API{config: &Config{}, router: mux.NewRouter(), logs: slog.New(slog.NewJSONHandler(io.Discard, nil))}
+	api.endpoints()
+
+	// Файлы документации читаются относительно рабочего каталога api-gateway
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir("../.."))
+	defer os.Chdir(wd)
+
+	rec := httptest.NewRecorder()
+	api.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.True(t, json.Valid(rec.Body.Bytes()))
+
+	rec = httptest.NewRecorder()
+	api.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/docs", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "/openapi.json")
+}
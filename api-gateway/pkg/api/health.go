@@ -0,0 +1,218 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"news-kafka/api-gateway/pkg/kafka"
+	"news-kafka/api-gateway/pkg/logger"
+	"news-kafka/contracts"
+	"sync"
+	"time"
+)
+
+// Статусы проверок готовности
+const (
+	checkOK   = "ok"
+	checkFail = "fail"
+)
+
+// pingResult - последний ответ сервиса на Ping
+type pingResult struct {
+	reply     contracts.PingReply //Последний полученный ответ
+	err       error               //Ошибка последней проверки
+	lastOK    time.Time           //Время последнего успешного ответа
+	checkedAt time.Time           //Время последней проверки
+}
+
+// health - состояние брокеров Kafka и сервисов по ответам на Ping
+type health struct {
+	mu           sync.Mutex
+	results      map[string]pingResult
+	checkBrokers func() error
+	brokers      pingResult //Результат последней проверки брокеров, reply не используется
+}
+
+// Check - результат одной проверки готовности
+type Check struct {
+	Status         string            `json:"status"`                      //ok или fail
+	Error          string            `json:"error,omitempty"`             //Причина неготовности
+	LastReplyAgeMs int64             `json:"last_reply_age_ms,omitempty"` //Возраст последнего успешного ответа на Ping
+	Details        map[string]string `json:"details,omitempty"`           //Состояние, которое сообщил сервис
+}
+
+// Readiness - ответ /readyz
+type Readiness struct {
+	Status string           `json:"status"` //ok или fail
+	Checks map[string]Check `json:"checks"` //Проверки: kafka и сервисы по именам
+}
+
+// downstreams - сервисы, которым отправляется Ping, и их топики запросов
+func (api *API) downstreams() map[string]string {
+	return map[string]string{
+		"service-news":     api.configKafka.TopicResponseNews,
+		"service-comments": api.configKafka.TopicResponseComments,
+		"service-censor":   api.configKafka.TopicResponseCensor,
+	}
+}
+
+// SetBrokerCheck - замена проверки брокеров Kafka, например, для брокера в памяти.
+// Новая проверка выполняется сразу, далее - вместе с Ping сервисов.
+func (api *API) SetBrokerCheck(check func() error) {
+	api.health.mu.Lock()
+	api.health.checkBrokers = check
+	api.health.mu.Unlock()
+	api.pingBrokers()
+}
+
+// pingBrokers - проверка брокеров Kafka и сохранение результата. /readyz читает только
+// сохраненный результат, поэтому частые запросы проверки не открывают соединения с брокерами.
+func (api *API) pingBrokers() {
+	api.health.mu.Lock()
+	checkBrokers := api.health.checkBrokers
+	api.health.mu.Unlock()
+
+	err := checkBrokers()
+
+	api.health.mu.Lock()
+	defer api.health.mu.Unlock()
+	api.health.brokers.err = err
+	api.health.brokers.checkedAt = time.Now()
+	if err == nil {
+		api.health.brokers.lastOK = api.health.brokers.checkedAt
+	}
+}
+
+// PingServices - проверка брокеров и отправка Ping всем сервисам сразу и далее с интервалом
+// из конфигурации до отмены контекста. Результаты используются в /readyz.
+func (api *API) PingServices(ctx context.Context) {
+	ticker := time.NewTicker(api.config.PingInterval())
+	defer ticker.Stop()
+
+	for {
+		api.pingBrokers()
+
+		var wg sync.WaitGroup
+		for name, topic := range api.downstreams() {
+			wg.Add(1)
+			go func(name, topic string) {
+				defer wg.Done()
+				api.ping(ctx, name, topic)
+			}(name, topic)
+		}
+		wg.Wait()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ping - отправка Ping сервису и сохранение ответа
+func (api *API) ping(ctx context.Context, name, topic string) {
+	ctx, cancel := context.WithTimeout(ctx, api.config.Timeout("ping"))
+	defer cancel()
+
+	requestID := logger.GetRequestId()
+	ctx = kafka.ContextWithMetadata(ctx, kafka.Metadata{RequestID: requestID, Source: logger.GetServiceName()})
+	message := contracts.Header{ID: requestID, Name: logger.GetServiceName(), TypeQuery: contracts.TypePing}
+
+	var reply contracts.PingReply
+	err := api.request(ctx, topic, requestID, contracts.TypePing, &message, &reply)
+	if err == nil && !reply.IsOK() {
+		err = fmt.Errorf("%v: %v", reply.Status, reply.Message)
+	}
+
+	api.health.mu.Lock()
+	defer api.health.mu.Unlock()
+	result := api.health.results[name]
+	result.reply = reply
+	result.err = err
+	result.checkedAt = time.Now()
+	if err == nil {
+		result.lastOK = result.checkedAt
+	}
+	api.health.results[name] = result
+}
+
+// Readiness - свежесть результатов проверки брокеров Kafka и ответов сервисов на Ping
+func (api *API) Readiness() Readiness {
+	api.health.mu.Lock()
+	brokers := api.health.brokers
+	results := make(map[string]pingResult, len(api.health.results))
+	for name, result := range api.health.results {
+		results[name] = result
+	}
+	api.health.mu.Unlock()
+
+	readiness := Readiness{Status: checkOK, Checks: make(map[string]Check)}
+	fail := func(name string, check Check) {
+		check.Status = checkFail
+		readiness.Status = checkFail
+		readiness.Checks[name] = check
+	}
+
+	// Брокеры недоступны с последней проверки, даже если до нее они отвечали
+	maxAge := api.config.PingMaxAge()
+	if check := resultCheck(brokers, maxAge); check.Status != checkOK {
+		fail("kafka", check)
+	} else if brokers.err != nil {
+		fail("kafka", Check{Error: brokers.err.Error()})
+	} else {
+		readiness.Checks["kafka"] = Check{Status: checkOK}
+	}
+
+	for name := range api.downstreams() {
+		if check := resultCheck(results[name], maxAge); check.Status != checkOK {
+			fail(name, check)
+		} else {
+			readiness.Checks[name] = check
+		}
+	}
+
+	return readiness
+}
+
+// resultCheck - проверка готовности по последнему результату: успешный ответ не старше maxAge
+func resultCheck(result pingResult, maxAge time.Duration) Check {
+	check := Check{Status: checkFail, Details: result.reply.Details}
+	if result.lastOK.IsZero() {
+		check.Error = "no successful ping reply"
+		if result.err != nil {
+			check.Error = result.err.Error()
+		}
+		return check
+	}
+
+	age := time.Since(result.lastOK)
+	check.LastReplyAgeMs = age.Milliseconds()
+	if age > maxAge {
+		check.Error = fmt.Sprintf("last successful ping reply is older than %v", maxAge)
+		if result.err != nil {
+			check.Error += ": " + result.err.Error()
+		}
+		return check
+	}
+	check.Status = checkOK
+	return check
+}
+
+// Проверка, что процесс api-gateway работает.
+func (api *API) healthzHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": checkOK})
+}
+
+// Проверка готовности api-gateway принимать запросы.
+func (api *API) readyzHandler(w http.ResponseWriter, r *http.Request) {
+	readiness := api.Readiness()
+
+	w.Header().Set("Content-Type", "application/json")
+	if readiness.Status != checkOK {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(readiness)
+}
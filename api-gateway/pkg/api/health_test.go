@@ -0,0 +1,38 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"news-kafka/api-gateway/pkg/kafka"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// /readyz читает сохраненный результат проверки брокеров и не проверяет их сам
+func TestReadiness_BrokerCheckCached(t *testing.T) {
+	calls := 0
+	var brokerErr error
+	api := &API{config: &Config{}, configKafka: &kafka.Config{}, health: &health{results: make(map[string]pingResult)}}
+	api.SetBrokerCheck(func() error {
+		calls++
+		return brokerErr
+	})
+	require.Equal(t, 1, calls)
+
+	for i := 0; i < 10; i++ {
+		rec := httptest.NewRecorder()
+		api.readyzHandler(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code) // сервисы еще не ответили на Ping
+	}
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, checkOK, api.Readiness().Checks["kafka"].Status)
+
+	brokerErr = errors.New("kafka down")
+	api.pingBrokers()
+	check := api.Readiness().Checks["kafka"]
+	assert.Equal(t, checkFail, check.Status)
+	assert.Equal(t, "kafka down", check.Error)
+}
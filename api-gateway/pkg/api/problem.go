@@ -0,0 +1,114 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"news-kafka/api-gateway/pkg/kafka"
+	"news-kafka/contracts"
+)
+
+// ProblemContentType - тип содержимого ответа с ошибкой (RFC 7807)
+const ProblemContentType = "application/problem+json"
+
+// Коды ошибок в ответах api-gateway. Коды ошибок, полученных от сервисов,
+// совпадают со статусами contracts.Reply.
+const (
+	CodeInvalid          = contracts.StatusInvalid   //Некорректные параметры запроса
+	CodeNotFound         = contracts.StatusNotFound  //Данные или маршрут не найдены
+	CodeForbidden        = contracts.StatusForbidden //Запрос не разрешен клиенту, например, изменение чужого комментария
+	CodeRejected         = contracts.StatusRejected  //Запрос отклонен по бизнес-правилам, например, цензурой
+	CodeInternal         = contracts.StatusInternal  //Внутренняя ошибка api-gateway или сервиса
+	CodeTimeout          = contracts.StatusTimeout   //Ответ сервиса не получен до истечения дедлайна
+	CodeUnavailable      = "unavailable"             //Запрос не доставлен сервису
+	CodeRateLimited      = "rate_limited"            //Превышен лимит частоты запросов
+	CodeMethodNotAllowed = "method_not_allowed"      //Метод не поддерживается маршрутом
+	CodeConflict         = "conflict"                //request_id уже используется другим запросом
+	CodePayloadTooLarge  = "payload_too_large"       //Тело запроса длиннее validation.max_body_bytes
+)
+
+// problemCode - HTTP-код и возможность повтора для кода ошибки
+type problemCode struct {
+	status    int
+	retryable bool
+}
+
+// problemCodes - коды ошибок; неизвестный код - внутренняя ошибка
+var problemCodes = map[string]problemCode{
+	CodeInvalid:          {http.StatusBadRequest, false},
+	CodeNotFound:         {http.StatusNotFound, false},
+	CodeForbidden:        {http.StatusForbidden, false},
+	CodeRejected:         {http.StatusUnprocessableEntity, false},
+	CodeInternal:         {http.StatusInternalServerError, false},
+	CodeTimeout:          {http.StatusGatewayTimeout, true},
+	CodeUnavailable:      {http.StatusServiceUnavailable, true},
+	CodeRateLimited:      {http.StatusTooManyRequests, true},
+	CodeMethodNotAllowed: {http.StatusMethodNotAllowed, false},
+	CodeConflict:         {http.StatusConflict, false},
+	CodePayloadTooLarge:  {http.StatusRequestEntityTooLarge, false},
+}
+
+// Problem - ответ с ошибкой в формате application/problem+json (RFC 7807).
+// Клиенты различают ошибки по code, а не по тексту title и detail.
+type Problem struct {
+	Type      string            `json:"type"`                 //URI типа ошибки, содержит code
+	Title     string            `json:"title"`                //Текст HTTP-кода
+	Status    int               `json:"status"`               //HTTP-код
+	Detail    string            `json:"detail,omitempty"`     //Описание ошибки
+	Instance  string            `json:"instance,omitempty"`   //Путь запроса
+	Code      string            `json:"code"`                 //Код ошибки, не меняется между версиями
+	RequestID string            `json:"request_id,omitempty"` //Сквозной идентификатор запроса
+	Retryable bool              `json:"retryable"`            //Повтор того же запроса может завершиться успешно
+	Errors    map[string]string `json:"errors,omitempty"`     //Нарушения по именам полей запроса
+}
+
+// NewProblem - описание ошибки по коду для запроса r
+func NewProblem(r *http.Request, code, detail string, errs map[string]string) Problem {
+	c, ok := problemCodes[code]
+	if !ok {
+		code, c = CodeInternal, problemCodes[CodeInternal]
+	}
+	requestID, _ := r.Context().Value("request_id").(string)
+	return Problem{
+		Type:      "urn:news-kafka:problem:" + code,
+		Title:     http.StatusText(c.status),
+		Status:    c.status,
+		Detail:    detail,
+		Instance:  r.URL.Path,
+		Code:      code,
+		RequestID: requestID,
+		Retryable: c.retryable,
+		Errors:    errs,
+	}
+}
+
+// writeProblem - отправка клиенту ошибки с кодом code
+func writeProblem(w http.ResponseWriter, r *http.Request, code, detail string, errs map[string]string) {
+	problem := NewProblem(r, code, detail, errs)
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(problem.Status)
+	json.NewEncoder(w).Encode(problem)
+}
+
+// writeError - отправка клиенту ошибки из ответа сервиса
+func writeError(w http.ResponseWriter, r *http.Request, reply contracts.Reply) {
+	writeProblem(w, r, reply.Status, reply.Message, reply.Details)
+}
+
+// writeRequestError - отправка клиенту ошибки, если сервис не ответил на запрос или ответ не декодирован
+func writeRequestError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, kafka.ErrReplyTimeout):
+		writeProblem(w, r, CodeTimeout, "deadline exceeded waiting for service response", nil)
+	case errors.Is(err, kafka.ErrDuplicateRequest):
+		writeProblem(w, r, CodeConflict, "request_id is already used by another request in progress", nil)
+	case errors.Is(err, kafka.ErrInvalidReply):
+		writeProblem(w, r, CodeInternal, "invalid response from service", nil)
+	case errors.Is(err, context.Canceled):
+		writeProblem(w, r, CodeUnavailable, "request canceled", nil)
+	default:
+		writeProblem(w, r, CodeUnavailable, "no response from service", nil)
+	}
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"news-kafka/api-gateway/pkg/kafka"
+	"news-kafka/api-gateway/pkg/ratelimit"
+	"news-kafka/contracts"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decodeProblem(t *testing.T, rec *httptest.ResponseRecorder) Problem {
+	t.Helper()
+	assert.Equal(t, ProblemContentType, rec.Header().Get("Content-Type"))
+	var problem Problem
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&problem))
+	assert.Equal(t, rec.Code, problem.Status)
+	return problem
+}
+
+func TestWriteError(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/newsDetailed?id_news=1", nil)
+	req = req.WithContext(context.WithValue(req.Context(), "request_id", "req-1"))
+
+	rec := httptest.NewRecorder()
+	writeError(rec, req, contracts.ReplyFail(contracts.StatusRejected, "offensive words", map[string]string{"content": "bad"}))
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Equal(t, Problem{
+		Type:      "urn:news-kafka:problem:rejected",
+		Title:     "Unprocessable Entity",
+		Status:    http.StatusUnprocessableEntity,
+		Detail:    "offensive words",
+		Instance:  "/newsDetailed",
+		Code:      CodeRejected,
+		RequestID: "req-1",
+		Errors:    map[string]string{"content": "bad"},
+	}, decodeProblem(t, rec))
+
+	rec = httptest.NewRecorder()
+	writeError(rec, req, contracts.ReplyFail(contracts.StatusForbidden, "comment belongs to another user", nil))
+	require.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Equal(t, CodeForbidden, decodeProblem(t, rec).Code)
+
+	// Неизвестный статус сервиса - внутренняя ошибка
+	rec = httptest.NewRecorder()
+	writeError(rec, req, contracts.ReplyFail("unknown", "", nil))
+	assert.Equal(t, CodeInternal, decodeProblem(t, rec).Code)
+}
+
+func TestWriteRequestError(t *testing.T) {
+	tests := []struct {
+		err       error
+		code      string
+		status    int
+		retryable bool
+	}{
+		{kafka.ErrReplyTimeout, CodeTimeout, http.StatusGatewayTimeout, true},
+		{context.Canceled, CodeUnavailable, http.StatusServiceUnavailable, true},
+		{errors.New("kafka: broker not available"), CodeUnavailable, http.StatusServiceUnavailable, true},
+		{kafka.ErrDuplicateRequest, CodeConflict, http.StatusConflict, false},
+		{fmt.Errorf("RequestID:1, Type:News: %w: %w", kafka.ErrInvalidReply, contracts.ErrUnsupportedVersion), CodeInternal, http.StatusInternalServerError, false},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		writeRequestError(rec, httptest.NewRequest(http.MethodGet, "/news/Sport/10", nil), tt.err)
+		require.Equal(t, tt.status, rec.Code)
+		problem := decodeProblem(t, rec)
+		assert.Equal(t, tt.code, problem.Code)
+		assert.Equal(t, tt.retryable, problem.Retryable)
+	}
+}
+
+func TestRateLimitMiddleware_Problem(t *testing.T) {
+	router := newRateLimitRouter(&RateLimitConfig{
+		ClientKey: ClientKeyIP,
+		Default:   ratelimit.Limit{RatePerSec: 1, Burst: 1},
+	})
+	serve(router, "/news/Sport/10", "10.0.0.1:1000", "")
+	rec := serve(router, "/news/Sport/10", "10.0.0.1:1000", "")
+	require.Equal(t, http.StatusTooManyRequests, rec.Code)
+
+	problem := decodeProblem(t, rec)
+	assert.Equal(t, CodeRateLimited, problem.Code)
+	assert.True(t, problem.Retryable)
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"news-kafka/api-gateway/pkg/logger"
+	"news-kafka/api-gateway/pkg/ratelimit"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// SetRateLimitStore - хранилище корзин токенов, по умолчанию в памяти api-gateway.
+// ratelimit.RedisStore делает лимиты общими для нескольких экземпляров.
+func (api *API) SetRateLimitStore(store ratelimit.Store) {
+	api.rateLimits = store
+}
+
+// RateLimitMiddleware - ограничение частоты запросов по маршруту и клиенту.
+// Превышение лимита - 429 с заголовком Retry-After. При ошибке хранилища корзин
+// запрос пропускается, чтобы недоступность хранилища не останавливала api-gateway.
+func (api *API) RateLimitMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		config := api.config.RateLimit
+		if config == nil || r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		route := mux.CurrentRoute(r).GetName()
+		client := clientKey(r, config)
+		limit := config.Limit(route, client)
+
+		result, err := api.rateLimits.Take(r.Context(), route+"|"+client, limit)
+		if err != nil {
+			api.logs.WarnContext(r.Context(), "rate limit check failed", logger.Err(err))
+			next.ServeHTTP(w, r)
+			return
+		}
+		if limit.Unlimited() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("X-RateLimit-Limit", strconv.Itoa(max(limit.Burst, 1)))
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		if !result.Allowed {
+			// Retry-After в целых секундах, не меньше 1
+			seconds := int(math.Ceil(result.RetryAfter.Seconds()))
+			if seconds < 1 {
+				seconds = 1
+			}
+			w.Header().Set("Retry-After", strconv.Itoa(seconds))
+			writeProblem(w, r, CodeRateLimited, "rate limit exceeded", nil)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// clientKey - клиент для лимитов: ключ API из clients или IP-адрес. Неизвестный ключ
+// не дает отдельного лимита, иначе клиент получал бы новую корзину с каждым выдуманным ключом.
+func clientKey(r *http.Request, config *RateLimitConfig) string {
+	if config.ClientKey == ClientKeyAPIKey {
+		if key := r.Header.Get(config.APIKeyHeader); key != "" {
+			if _, ok := config.Clients[key]; ok {
+				return key
+			}
+		}
+	}
+	if config.TrustForwardedFor {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			// Последний адрес добавлен доверенным прокси, предыдущие передает сам клиент
+			forwarded = forwarded[strings.LastIndex(forwarded, ",")+1:]
+			if client := strings.TrimSpace(forwarded); client != "" {
+				return client
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
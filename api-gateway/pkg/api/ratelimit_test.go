@@ -0,0 +1,106 @@
+package api
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"news-kafka/api-gateway/pkg/ratelimit"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func newRateLimitRouter(config *RateLimitConfig) *mux.Router {
+	api := &API{
+		config:     &Config{RateLimit: config},
+		rateLimits: ratelimit.NewMemoryStore(),
+		logs:       slog.New(slog.NewJSONHandler(io.Discard, nil)),
+	}
+	router := mux.NewRouter()
+	router.Use(api.RateLimitMiddleware)
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+	router.HandleFunc("/comments", ok).Name("comments_add")
+	router.HandleFunc("/news/{rubric}/{countNews}", ok).Name("news")
+	return router
+}
+
+func serve(router http.Handler, path, remoteAddr, apiKey string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.RemoteAddr = remoteAddr
+	if apiKey != "" {
+		req.Header.Set("X-API-Key", apiKey)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	router := newRateLimitRouter(&RateLimitConfig{
+		ClientKey: ClientKeyIP,
+		Default:   ratelimit.Limit{RatePerSec: 100, Burst: 100},
+		Routes:    map[string]ratelimit.Limit{"comments_add": {RatePerSec: 0.5, Burst: 2}},
+	})
+
+	for i := 0; i < 2; i++ {
+		assert.Equal(t, http.StatusOK, serve(router, "/comments", "10.0.0.1:1000", "").Code)
+	}
+	rec := serve(router, "/comments", "10.0.0.1:1001", "")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "2", rec.Header().Get("Retry-After"))
+	assert.Equal(t, "0", rec.Header().Get("X-RateLimit-Remaining"))
+
+	// Лимит считается отдельно для каждого клиента и маршрута
+	assert.Equal(t, http.StatusOK, serve(router, "/comments", "10.0.0.2:1000", "").Code)
+	assert.Equal(t, http.StatusOK, serve(router, "/news/sport/10", "10.0.0.1:1000", "").Code)
+}
+
+func TestRateLimitMiddleware_APIKey(t *testing.T) {
+	router := newRateLimitRouter(&RateLimitConfig{
+		ClientKey:    ClientKeyAPIKey,
+		APIKeyHeader: "X-API-Key",
+		Default:      ratelimit.Limit{RatePerSec: 1, Burst: 1},
+		Clients:      map[string]ratelimit.Limit{"partner": {RatePerSec: 100, Burst: 5}},
+	})
+
+	// Клиенты с ключом API не делят лимит с другими клиентами с того же адреса
+	assert.Equal(t, http.StatusOK, serve(router, "/news/sport/10", "10.0.0.1:1000", "").Code)
+	assert.Equal(t, http.StatusTooManyRequests, serve(router, "/news/sport/10", "10.0.0.1:1000", "").Code)
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, http.StatusOK, serve(router, "/news/sport/10", "10.0.0.1:1000", "partner").Code)
+	}
+	assert.Equal(t, http.StatusTooManyRequests, serve(router, "/news/sport/10", "10.0.0.1:1000", "partner").Code)
+
+	// Неизвестный ключ - лимит по IP-адресу, уже исчерпанный
+	assert.Equal(t, http.StatusTooManyRequests, serve(router, "/news/sport/10", "10.0.0.1:1000", "made-up").Code)
+}
+
+func TestClientKey_ForwardedFor(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.0.0.1:1000"
+	req.Header.Set("X-Forwarded-For", "203.0.113.7, 10.0.0.1")
+
+	assert.Equal(t, "10.0.0.1", clientKey(req, &RateLimitConfig{ClientKey: ClientKeyIP}))
+	assert.Equal(t, "10.0.0.1", clientKey(req, &RateLimitConfig{ClientKey: ClientKeyIP, TrustForwardedFor: true}))
+
+	// Адрес, переданный клиентом в начале заголовка, не меняет ключ
+	req.Header.Set("X-Forwarded-For", "198.51.100.1, 203.0.113.7")
+	assert.Equal(t, "203.0.113.7", clientKey(req, &RateLimitConfig{ClientKey: ClientKeyIP, TrustForwardedFor: true}))
+}
+
+func TestClientKey_UnknownAPIKey(t *testing.T) {
+	config := &RateLimitConfig{
+		ClientKey:    ClientKeyAPIKey,
+		APIKeyHeader: "X-API-Key",
+		Clients:      map[string]ratelimit.Limit{"partner": {RatePerSec: 100, Burst: 5}},
+	}
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.0.0.1:1000"
+
+	req.Header.Set("X-API-Key", "partner")
+	assert.Equal(t, "partner", clientKey(req, config))
+	req.Header.Set("X-API-Key", "made-up")
+	assert.Equal(t, "10.0.0.1", clientKey(req, config))
+}
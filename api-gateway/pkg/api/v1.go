@@ -0,0 +1,344 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"news-kafka/contracts"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// APIPrefixV1 - префикс маршрутов версии 1 REST API
+const APIPrefixV1 = "/api/v1"
+
+// Размер страницы списка новостей, если page_size не задан
+const defaultPageSize = 10
+
+// Регистрация маршрутов /api/v1. Имена маршрутов - ключи лимитов rate_limit.routes,
+// время ожидания ответа - как у соответствующих маршрутов без версии.
+func (api *API) endpointsV1() {
+	v1 := api.router.PathPrefix(APIPrefixV1).Subrouter()
+	v1.HandleFunc("/news", api.v1NewsHandler).Methods(http.MethodGet, http.MethodOptions).Name("v1_news")
+	v1.HandleFunc("/news/{id}", api.v1NewsItemHandler).Methods(http.MethodGet, http.MethodOptions).Name("v1_news_item")
+	v1.HandleFunc("/news/{id}/comments", api.v1CommentsHandler).Methods(http.MethodGet, http.MethodOptions).Name("v1_comments")
+	v1.HandleFunc("/news/{id}/comments", api.v1AddCommentHandler).Methods(http.MethodPost).Name("v1_comments_add")
+	v1.HandleFunc("/news/{id}/comments/{comment_id}", api.v1UpdateCommentHandler).Methods(http.MethodPut).Name("v1_comments_update")
+	v1.HandleFunc("/news/{id}/comments/{comment_id}", api.v1DeleteCommentHandler).Methods(http.MethodDelete).Name("v1_comments_delete")
+	v1.HandleFunc("/rubrics", api.v1RubricsHandler).Methods(http.MethodGet, http.MethodOptions).Name("v1_rubrics")
+
+	// Неизвестные маршруты и методы /api/v1 не передаются статическим файлам UI
+	v1.NotFoundHandler = v1NotFoundHandler(v1)
+	v1.MethodNotAllowedHandler = v1.NotFoundHandler
+}
+
+// v1NotFoundHandler - ответ на запрос без подходящего маршрута: 405 и заголовок Allow,
+// если путь есть с другими методами, иначе 404. mux сообщает о несовпадении метода
+// не для всех маршрутов, поэтому методы пути определяются по шаблонам маршрутов.
+func v1NotFoundHandler(router *mux.Router) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var allowed []string
+		router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+			pattern, err := route.GetPathRegexp()
+			if err != nil {
+				return nil
+			}
+			if ok, _ := regexp.MatchString(pattern, r.URL.Path); ok {
+				methods, _ := route.GetMethods()
+				allowed = append(allowed, methods...)
+			}
+			return nil
+		})
+		if len(allowed) == 0 {
+			writeProblem(w, r, CodeNotFound, "route not found", nil)
+			return
+		}
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		writeProblem(w, r, CodeMethodNotAllowed, "method not allowed", nil)
+	})
+}
+
+// writeJSON - отправка клиенту ответа в формате JSON
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// newsID - идентификатор новости из пути; при ошибке клиенту отправляется ответ 400
+func newsID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id := mux.Vars(r)["id"]
+	if errs := Validate(idField(id)); errs != nil {
+		writeInvalid(w, r, errs)
+		return 0, false
+	}
+	n, _ := strconv.Atoi(id)
+	return n, true
+}
+
+// commentID - идентификаторы новости и комментария из пути; при ошибке клиенту отправляется ответ 400
+func commentID(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	vars := mux.Vars(r)
+	if errs := Validate(idField(vars["id"]), commentIDField(vars["comment_id"])); errs != nil {
+		writeInvalid(w, r, errs)
+		return 0, 0, false
+	}
+	idNews, _ := strconv.Atoi(vars["id"])
+	idComment, _ := strconv.Atoi(vars["comment_id"])
+	return idNews, idComment, true
+}
+
+// GET /api/v1/news?rubric=&filter=&page=&page_size= - страница списка новостей, без rubric - все рубрики.
+func (api *API) v1NewsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+
+	query := r.URL.Query()
+	rubric, filter, pageStr, pageSizeStr := query.Get("rubric"), query.Get("filter"), query.Get("page"), query.Get("page_size")
+	if errs := Validate(api.config.Limits().newsListFields(rubric, filter, pageStr, pageSizeStr)...); errs != nil {
+		writeInvalid(w, r, errs)
+		return
+	}
+
+	// Значения проверены правилами Int
+	page, pageSize := 1, defaultPageSize
+	if pageStr != "" {
+		page, _ = strconv.Atoi(pageStr)
+	}
+	if pageSizeStr != "" {
+		pageSize, _ = strconv.Atoi(pageSizeStr)
+	}
+	if rubric == "" {
+		rubric = "%"
+	}
+	requestID := r.Context().Value("request_id").(string)
+
+	ctx, cancel := context.WithTimeout(r.Context(), api.config.Timeout("news"))
+	defer cancel()
+
+	serviceNews, err := api.newsList(ctx, requestID, rubric, pageSize, filter, page)
+	if !api.replyOK(w, r, serviceNews.Reply, err) {
+		return
+	}
+
+	news := serviceNews.News
+	if news == nil {
+		news = []contracts.News{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"news":     news,
+		"paginate": serviceNews.Paginate,
+	})
+}
+
+// GET /api/v1/news/{id} - новость.
+func (api *API) v1NewsItemHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+
+	id, ok := newsID(w, r)
+	if !ok {
+		return
+	}
+	requestID := r.Context().Value("request_id").(string)
+
+	ctx, cancel := context.WithTimeout(r.Context(), api.config.Timeout("news_detailed"))
+	defer cancel()
+
+	serviceNews, err := api.oneNews(ctx, requestID, id)
+	if !api.replyOK(w, r, serviceNews.Reply, err) {
+		return
+	}
+	if len(serviceNews.News) == 0 {
+		writeProblem(w, r, CodeNotFound, "news not found", nil)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, serviceNews.News[0])
+}
+
+// CommentNode - комментарий с ответами на него в представлении view=tree
+type CommentNode struct {
+	contracts.Comment
+	Replies []*CommentNode `json:"replies"`
+}
+
+// commentTree - дерево комментариев из списка. Порядок комментариев и ответов сохраняется.
+// Ответ на удаленный комментарий, которого нет в списке, становится комментарием верхнего уровня.
+func commentTree(comments []contracts.Comment) []*CommentNode {
+	nodes := make(map[int]*CommentNode, len(comments))
+	for _, c := range comments {
+		nodes[c.Id] = &CommentNode{Comment: c, Replies: []*CommentNode{}}
+	}
+
+	roots := []*CommentNode{}
+	for _, c := range comments {
+		node := nodes[c.Id]
+		if parent, ok := nodes[c.ParentId]; ok && c.ParentId != 0 {
+			parent.Replies = append(parent.Replies, node)
+			continue
+		}
+		roots = append(roots, node)
+	}
+	return roots
+}
+
+// GET /api/v1/news/{id}/comments?view= - комментарии к новости: view=flat (по умолчанию) - список,
+// view=tree - дерево ответов.
+func (api *API) v1CommentsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+
+	id, ok := newsID(w, r)
+	if !ok {
+		return
+	}
+	view := r.URL.Query().Get("view")
+	if errs := Validate(commentsViewField(view)); errs != nil {
+		writeInvalid(w, r, errs)
+		return
+	}
+	requestID := r.Context().Value("request_id").(string)
+
+	ctx, cancel := context.WithTimeout(r.Context(), api.config.Timeout("news_detailed"))
+	defer cancel()
+
+	serviceComments, err := api.newsComments(ctx, requestID, id)
+	if !api.replyOK(w, r, serviceComments.Reply, err) {
+		return
+	}
+
+	if view == CommentsViewTree {
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"comments": commentTree(serviceComments.Comments),
+		})
+		return
+	}
+
+	comments := serviceComments.Comments
+	if comments == nil {
+		comments = []contracts.Comment{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"comments": comments,
+	})
+}
+
+// POST /api/v1/news/{id}/comments - добавление комментария или ответа на комментарий parent_id,
+// в ответе 201 сохраненный комментарий с id и depth, адрес комментария - в заголовке Location.
+func (api *API) v1AddCommentHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := newsID(w, r)
+	if !ok {
+		return
+	}
+
+	var comment contracts.Comment
+	if err := json.NewDecoder(r.Body).Decode(&comment); err != nil {
+		writeInvalid(w, r, map[string]string{"body": "invalid JSON: " + err.Error()})
+		return
+	}
+	if errs := Validate(api.config.Limits().commentFields(comment)...); errs != nil {
+		writeInvalid(w, r, errs)
+		return
+	}
+	requestID := r.Context().Value("request_id").(string)
+
+	// Дедлайн общий для проверки цензурой и сохранения комментария
+	ctx, cancel := context.WithTimeout(r.Context(), api.config.Timeout("comments_add"))
+	defer cancel()
+
+	saved, reply, err := api.addComment(ctx, requestID, id, comment)
+	if !api.replyOK(w, r, reply, err) {
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("%s/news/%d/comments/%d", APIPrefixV1, saved.IdNews, saved.Id))
+	writeJSON(w, http.StatusCreated, saved)
+}
+
+// PUT /api/v1/news/{id}/comments/{comment_id} - изменение текста комментария автором user_name
+// с повторной проверкой цензурой, в ответе измененный комментарий с временем изменения edited_at.
+func (api *API) v1UpdateCommentHandler(w http.ResponseWriter, r *http.Request) {
+	idNews, idComment, ok := commentID(w, r)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		UserName string `json:"user_name"`
+		Content  string `json:"content"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeInvalid(w, r, map[string]string{"body": "invalid JSON: " + err.Error()})
+		return
+	}
+	if errs := Validate(api.config.Limits().commentUpdateFields(body.UserName, body.Content)...); errs != nil {
+		writeInvalid(w, r, errs)
+		return
+	}
+	requestID := r.Context().Value("request_id").(string)
+
+	// Дедлайн общий для проверки цензурой и изменения комментария
+	ctx, cancel := context.WithTimeout(r.Context(), api.config.Timeout("comments_add"))
+	defer cancel()
+
+	updated, reply, err := api.updateComment(ctx, requestID, idNews, idComment, body.UserName, body.Content)
+	if !api.replyOK(w, r, reply, err) {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, updated)
+}
+
+// DELETE /api/v1/news/{id}/comments/{comment_id} - удаление комментария автором user_name, в ответе 204.
+func (api *API) v1DeleteCommentHandler(w http.ResponseWriter, r *http.Request) {
+	idNews, idComment, ok := commentID(w, r)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		UserName string `json:"user_name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeInvalid(w, r, map[string]string{"body": "invalid JSON: " + err.Error()})
+		return
+	}
+	if errs := Validate(api.config.Limits().commentAuthorFields(body.UserName)...); errs != nil {
+		writeInvalid(w, r, errs)
+		return
+	}
+	requestID := r.Context().Value("request_id").(string)
+
+	ctx, cancel := context.WithTimeout(r.Context(), api.config.Timeout("comments_add"))
+	defer cancel()
+
+	reply, err := api.deleteComment(ctx, requestID, idNews, idComment, body.UserName)
+	if !api.replyOK(w, r, reply, err) {
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET /api/v1/rubrics - допустимые рубрики из validation.rubrics.
+func (api *API) v1RubricsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+
+	rubrics := api.config.Limits().Rubrics
+	if rubrics == nil {
+		rubrics = []string{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"rubrics": rubrics,
+	})
+}
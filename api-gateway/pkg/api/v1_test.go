@@ -0,0 +1,139 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"news-kafka/contracts"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestV1_Validation(t *testing.T) {
+	router := newValidationRouter()
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		body    string
+		details map[string]string
+	}{
+		{"news", http.MethodGet, "/api/v1/news?rubric=tech&page=0&page_size=1000", "", map[string]string{
+			"rubric":    "must be one of: Sport",
+			"page":      "must be between 1 and 1000",
+			"page_size": "must be between 1 and 50",
+		}},
+		{"news item", http.MethodGet, "/api/v1/news/abc", "", map[string]string{
+			"id": "must be an integer",
+		}},
+		{"comments", http.MethodGet, "/api/v1/news/0/comments", "", map[string]string{
+			"id": "must be at least 1",
+		}},
+		{"comments view", http.MethodGet, "/api/v1/news/1/comments?view=nested", "", map[string]string{
+			"view": "must be one of: flat, tree",
+		}},
+		{"comment reply", http.MethodPost, "/api/v1/news/1/comments", `{"user_name":"bob","content":"hello","parent_id":-1}`, map[string]string{
+			"parent_id": "must be at least 1",
+		}},
+		{"comment add", http.MethodPost, "/api/v1/news/1/comments", `{"user_name":"","content":"hello"}`, map[string]string{
+			"user_name": "required",
+		}},
+		{"comment update", http.MethodPut, "/api/v1/news/1/comments/abc", `{"content":""}`, map[string]string{
+			"comment_id": "must be an integer",
+		}},
+		{"comment update content", http.MethodPut, "/api/v1/news/1/comments/2", `{"user_name":"bob","content":" "}`, map[string]string{
+			"content": "required",
+		}},
+		{"comment update author", http.MethodPut, "/api/v1/news/1/comments/2", `{"content":"hello"}`, map[string]string{
+			"user_name": "required",
+		}},
+		{"comment delete", http.MethodDelete, "/api/v1/news/0/comments/0", "", map[string]string{
+			"id":         "must be at least 1",
+			"comment_id": "must be at least 1",
+		}},
+		{"comment delete author", http.MethodDelete, "/api/v1/news/1/comments/2", `{"user_name":"gopher1"}`, map[string]string{
+			"user_name": "must be at most 5 characters",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+			require.Equal(t, http.StatusBadRequest, rec.Code)
+
+			problem := decodeProblem(t, rec)
+			assert.Equal(t, CodeInvalid, problem.Code)
+			assert.Equal(t, tt.details, problem.Errors)
+		})
+	}
+}
+
+// Неизвестные маршруты и методы /api/v1 - ошибки в формате problem+json, а не статические файлы
+func TestV1_NotFound(t *testing.T) {
+	router := newValidationRouter()
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/unknown", nil))
+	require.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, CodeNotFound, decodeProblem(t, rec).Code)
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/api/v1/news/1", nil))
+	require.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	assert.Equal(t, CodeMethodNotAllowed, decodeProblem(t, rec).Code)
+	assert.Equal(t, "GET, OPTIONS", rec.Header().Get("Allow"))
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/news/1/comments/2", nil))
+	require.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	assert.Equal(t, "PUT, DELETE", rec.Header().Get("Allow"))
+}
+
+func TestCommentTree(t *testing.T) {
+	// Новые первыми, как их возвращает service-comments; комментарий 2 удален
+	comments := []contracts.Comment{
+		{Id: 6, ParentId: 2, Depth: 1},
+		{Id: 5, ParentId: 3, Depth: 2},
+		{Id: 4, ParentId: 1, Depth: 1},
+		{Id: 3, ParentId: 1, Depth: 1},
+		{Id: 1},
+	}
+
+	roots := commentTree(comments)
+	require.Len(t, roots, 2)
+	assert.Equal(t, 6, roots[0].Id)
+	assert.Empty(t, roots[0].Replies)
+	assert.Equal(t, 1, roots[1].Id)
+	require.Len(t, roots[1].Replies, 2)
+	assert.Equal(t, 4, roots[1].Replies[0].Id)
+	assert.Equal(t, 3, roots[1].Replies[1].Id)
+	require.Len(t, roots[1].Replies[1].Replies, 1)
+	assert.Equal(t, 5, roots[1].Replies[1].Replies[0].Id)
+
+	data, err := json.Marshal(commentTree(nil))
+	require.NoError(t, err)
+	assert.JSONEq(t, `[]`, string(data))
+}
+
+func TestV1_Rubrics(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newValidationRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/rubrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var body struct {
+		Rubrics []string `json:"rubrics"`
+	}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+	assert.Equal(t, []string{"Sport"}, body.Rubrics)
+}
+
+func TestLegacyRoutes_Deprecated(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newValidationRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/newsDetailed", nil))
+	assert.Equal(t, "true", rec.Header().Get("Deprecation"))
+	assert.Equal(t, `</api/v1>; rel="successor-version"`, rec.Header().Get("Link"))
+}
@@ -0,0 +1,220 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"news-kafka/contracts"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Ограничения параметров запросов по умолчанию, если они не заданы в конфигурации
+const (
+	defaultMaxCountNews   = 100  //Размер страницы новостей
+	defaultMaxPage        = 1000 //Номер страницы новостей
+	defaultMaxFilterLen   = 200  //Длина строки поиска по заголовкам
+	defaultMaxUserNameLen = 64   //Длина имени автора комментария
+	defaultMaxContentLen  = 2000 //Длина текста комментария
+)
+
+// ValidationConfig - ограничения параметров маршрутов и тел запросов
+type ValidationConfig struct {
+	Rubrics        []string `json:"rubrics"`           //Допустимые рубрики, пустой список - любые
+	MaxCountNews   int      `json:"max_count_news"`    //Наибольший размер страницы новостей
+	MaxPage        int      `json:"max_page"`          //Наибольший номер страницы новостей, ограничивает смещение в БД
+	MaxFilterLen   int      `json:"max_filter_len"`    //Наибольшая длина строки поиска, символов
+	MaxUserNameLen int      `json:"max_user_name_len"` //Наибольшая длина имени автора комментария, символов
+	MaxContentLen  int      `json:"max_content_len"`   //Наибольшая длина текста комментария, символов
+	MaxBodyBytes   int64    `json:"max_body_bytes"`    //Наибольший размер тела запроса, по умолчанию - по длинам полей комментария
+}
+
+// withDefaults - ограничения, в которых незаданные значения заменены значениями по умолчанию
+func (v ValidationConfig) withDefaults() ValidationConfig {
+	if v.MaxCountNews <= 0 {
+		v.MaxCountNews = defaultMaxCountNews
+	}
+	if v.MaxPage <= 0 {
+		v.MaxPage = defaultMaxPage
+	}
+	if v.MaxFilterLen <= 0 {
+		v.MaxFilterLen = defaultMaxFilterLen
+	}
+	if v.MaxUserNameLen <= 0 {
+		v.MaxUserNameLen = defaultMaxUserNameLen
+	}
+	if v.MaxContentLen <= 0 {
+		v.MaxContentLen = defaultMaxContentLen
+	}
+	if v.MaxBodyBytes <= 0 {
+		// Символ поля в JSON занимает до 6 байт (\uXXXX), остальное - имена полей и числа
+		v.MaxBodyBytes = int64(6*(v.MaxUserNameLen+v.MaxContentLen) + 1024)
+	}
+	return v
+}
+
+// Rule - правило проверки значения поля, возвращает описание нарушения или пустую строку
+type Rule func(value string) string
+
+// Field - поле запроса: параметр пути, строки запроса или тела, и правила его проверки
+type Field struct {
+	Name  string
+	Value string
+	Rules []Rule
+}
+
+// Validate - проверка полей по правилам. Результат - описание первого нарушенного правила
+// по имени поля, nil - нарушений нет. Незаполненное поле проверяется только правилом Required.
+func Validate(fields ...Field) map[string]string {
+	var errs map[string]string
+	for _, field := range fields {
+		for _, rule := range field.Rules {
+			msg := rule(field.Value)
+			if msg == "" {
+				continue
+			}
+			if errs == nil {
+				errs = make(map[string]string)
+			}
+			errs[field.Name] = msg
+			break
+		}
+	}
+	return errs
+}
+
+// Required - поле заполнено не только пробелами
+func Required() Rule {
+	return func(value string) string {
+		if strings.TrimSpace(value) == "" {
+			return "required"
+		}
+		return ""
+	}
+}
+
+// Int - целое число от min до max, max <= 0 - без верхней границы
+func Int(min, max int) Rule {
+	return func(value string) string {
+		if value == "" {
+			return ""
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return "must be an integer"
+		}
+		if n < min || (max > 0 && n > max) {
+			if max > 0 {
+				return fmt.Sprintf("must be between %d and %d", min, max)
+			}
+			return fmt.Sprintf("must be at least %d", min)
+		}
+		return ""
+	}
+}
+
+// MaxLen - длина не больше max символов
+func MaxLen(max int) Rule {
+	return func(value string) string {
+		if utf8.RuneCountInString(value) > max {
+			return fmt.Sprintf("must be at most %d characters", max)
+		}
+		return ""
+	}
+}
+
+// OneOf - одно из допустимых значений, пустой список - любое значение
+func OneOf(allowed []string) Rule {
+	return func(value string) string {
+		if value == "" || len(allowed) == 0 {
+			return ""
+		}
+		for _, a := range allowed {
+			if value == a {
+				return ""
+			}
+		}
+		return "must be one of: " + strings.Join(allowed, ", ")
+	}
+}
+
+// Поля маршрутов. Значения передаются строками в том виде, в каком они пришли в запросе.
+
+// newsFields - параметры маршрута news: /news/{rubric}/{countNews}?filter=&page=
+func (v ValidationConfig) newsFields(rubric, countNews, filter, page string) []Field {
+	return []Field{
+		{Name: "rubric", Value: rubric, Rules: []Rule{Required(), OneOf(v.Rubrics)}},
+		{Name: "countNews", Value: countNews, Rules: []Rule{Required(), Int(1, v.MaxCountNews)}},
+		{Name: "filter", Value: filter, Rules: []Rule{MaxLen(v.MaxFilterLen)}},
+		{Name: "page", Value: page, Rules: []Rule{Int(1, v.MaxPage)}},
+	}
+}
+
+// newsListFields - параметры маршрута v1_news: /api/v1/news?rubric=&filter=&page=&page_size=
+func (v ValidationConfig) newsListFields(rubric, filter, page, pageSize string) []Field {
+	return []Field{
+		{Name: "rubric", Value: rubric, Rules: []Rule{OneOf(v.Rubrics)}},
+		{Name: "filter", Value: filter, Rules: []Rule{MaxLen(v.MaxFilterLen)}},
+		{Name: "page", Value: page, Rules: []Rule{Int(1, v.MaxPage)}},
+		{Name: "page_size", Value: pageSize, Rules: []Rule{Int(1, v.MaxCountNews)}},
+	}
+}
+
+// Представления списка комментариев маршрута v1_comments
+const (
+	CommentsViewFlat = "flat" //Список, новые первыми, ответы ссылаются на комментарий в parent_id
+	CommentsViewTree = "tree" //Дерево: комментарии к статье с вложенными ответами в replies
+)
+
+// commentsViewField - параметр view маршрута v1_comments
+func commentsViewField(view string) Field {
+	return Field{Name: "view", Value: view, Rules: []Rule{OneOf([]string{CommentsViewFlat, CommentsViewTree})}}
+}
+
+// idField - идентификатор ресурса в пути маршрутов /api/v1
+func idField(id string) Field {
+	return Field{Name: "id", Value: id, Rules: []Rule{Required(), Int(1, 0)}}
+}
+
+// idNewsField - идентификатор статьи в параметре id_news
+func idNewsField(idNews string) Field {
+	return Field{Name: "id_news", Value: idNews, Rules: []Rule{Required(), Int(1, 0)}}
+}
+
+// commentFields - тело запроса маршрута comments_add, parent_id 0 - комментарий к статье
+func (v ValidationConfig) commentFields(comment contracts.Comment) []Field {
+	parentID := ""
+	if comment.ParentId != 0 {
+		parentID = strconv.Itoa(comment.ParentId)
+	}
+	return []Field{
+		{Name: "user_name", Value: comment.UserName, Rules: []Rule{Required(), MaxLen(v.MaxUserNameLen)}},
+		{Name: "content", Value: comment.Content, Rules: []Rule{Required(), MaxLen(v.MaxContentLen)}},
+		{Name: "parent_id", Value: parentID, Rules: []Rule{Int(1, 0)}},
+	}
+}
+
+// commentIDField - идентификатор комментария в пути маршрутов /api/v1
+func commentIDField(id string) Field {
+	return Field{Name: "comment_id", Value: id, Rules: []Rule{Required(), Int(1, 0)}}
+}
+
+// commentUpdateFields - тело запроса маршрута v1_comments_update: автор комментария и новый текст
+func (v ValidationConfig) commentUpdateFields(userName, content string) []Field {
+	return append(v.commentAuthorFields(userName),
+		Field{Name: "content", Value: content, Rules: []Rule{Required(), MaxLen(v.MaxContentLen)}},
+	)
+}
+
+// commentAuthorFields - автор комментария в теле запросов изменения и удаления,
+// service-comments сравнивает его с автором сохраненного комментария
+func (v ValidationConfig) commentAuthorFields(userName string) []Field {
+	return []Field{
+		{Name: "user_name", Value: userName, Rules: []Rule{Required(), MaxLen(v.MaxUserNameLen)}},
+	}
+}
+
+// writeInvalid - ответ 400 с нарушениями по именам полей
+func writeInvalid(w http.ResponseWriter, r *http.Request, errs map[string]string) {
+	writeProblem(w, r, CodeInvalid, "invalid request parameters", errs)
+}
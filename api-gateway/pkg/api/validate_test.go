@@ -0,0 +1,118 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	errs := Validate(
+		Field{Name: "name", Value: "  ", Rules: []Rule{Required(), MaxLen(3)}},
+		Field{Name: "count", Value: "abc", Rules: []Rule{Int(1, 10)}},
+		Field{Name: "page", Value: "0", Rules: []Rule{Int(1, 0)}},
+		Field{Name: "size", Value: "11", Rules: []Rule{Int(1, 10)}},
+		Field{Name: "text", Value: "привет", Rules: []Rule{MaxLen(5)}},
+		Field{Name: "rubric", Value: "tech", Rules: []Rule{OneOf([]string{"Sport", "Technology"})}},
+		Field{Name: "optional", Value: "", Rules: []Rule{Int(1, 10), OneOf([]string{"a"})}},
+		Field{Name: "ok", Value: "Sport", Rules: []Rule{Required(), OneOf([]string{"Sport"}), MaxLen(5)}},
+	)
+	assert.Equal(t, map[string]string{
+		"name":   "required",
+		"count":  "must be an integer",
+		"page":   "must be at least 1",
+		"size":   "must be between 1 and 10",
+		"text":   "must be at most 5 characters",
+		"rubric": "must be one of: Sport, Technology",
+	}, errs)
+
+	assert.Nil(t, Validate(Field{Name: "rubric", Value: "anything", Rules: []Rule{OneOf(nil)}}))
+}
+
+func TestConfig_Limits(t *testing.T) {
+	limits := (&Config{}).Limits()
+	assert.Equal(t, defaultMaxCountNews, limits.MaxCountNews)
+	assert.Equal(t, defaultMaxPage, limits.MaxPage)
+	assert.Equal(t, int64(6*(defaultMaxUserNameLen+defaultMaxContentLen)+1024), limits.MaxBodyBytes)
+	assert.Empty(t, limits.Rubrics)
+
+	config, err := ReadConfig("../../configAPI.json")
+	require.NoError(t, err)
+	limits = config.Limits()
+	assert.Contains(t, limits.Rubrics, "Technology")
+	assert.Equal(t, 100, limits.MaxCountNews)
+	assert.Equal(t, int64(16384), limits.MaxBodyBytes)
+}
+
+// Запросы с нарушениями отклоняются до отправки в Kafka, поэтому producer не нужен
+func newValidationRouter() *mux.Router {
+	api := &API{
+		config: &Config{Validation: &ValidationConfig{Rubrics: []string{"Sport"}, MaxCountNews: 50, MaxUserNameLen: 5}},
+		router: mux.NewRouter(),
+		logs:   slog.New(slog.NewJSONHandler(io.Discard, nil)),
+	}
+	api.endpoints()
+	return api.router
+}
+
+func TestHandlers_Validation(t *testing.T) {
+	router := newValidationRouter()
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		body    string
+		details map[string]string
+	}{
+		{"news", http.MethodGet, "/news/tech/-1?page=x", "", map[string]string{
+			"rubric":    "must be one of: Sport",
+			"countNews": "must be between 1 and 50",
+			"page":      "must be an integer",
+		}},
+		{"news page", http.MethodGet, "/news/Sport/10?page=1001", "", map[string]string{
+			"page": "must be between 1 and 1000",
+		}},
+		{"news page size", http.MethodGet, "/news/Sport/1000", "", map[string]string{
+			"countNews": "must be between 1 and 50",
+		}},
+		{"news detailed", http.MethodGet, "/newsDetailed", "", map[string]string{
+			"id_news": "required",
+		}},
+		{"comment", http.MethodPost, "/comments?id_news=0", `{"user_name":"gopher","content":" "}`, map[string]string{
+			"id_news":   "must be at least 1",
+			"user_name": "must be at most 5 characters",
+			"content":   "required",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+			require.Equal(t, http.StatusBadRequest, rec.Code)
+
+			var problem Problem
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&problem))
+			assert.Equal(t, CodeInvalid, problem.Code)
+			assert.Equal(t, tt.details, problem.Errors)
+		})
+	}
+}
+
+func TestAddCommentsHandler_InvalidJSON(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newValidationRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/comments?id_news=1", strings.NewReader(`{"user_name":`)))
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	var problem Problem
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&problem))
+	assert.Contains(t, problem.Errors["body"], "invalid JSON")
+}
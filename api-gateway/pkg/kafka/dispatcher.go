@@ -0,0 +1,132 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"news-kafka/api-gateway/pkg/logger"
+	"news-kafka/contracts"
+	"sync"
+
+	"github.com/IBM/sarama"
+)
+
+var (
+	// ErrReplyTimeout - ответ от сервиса не получен за отведенное время
+	ErrReplyTimeout = errors.New("timeout waiting for response")
+	// ErrDuplicateRequest - ответ с таким request_id уже ожидается
+	ErrDuplicateRequest = errors.New("request with this id is already waiting for response")
+	// ErrInvalidReply - ответ от сервиса получен, но не декодирован: неизвестный формат или версия схемы
+	ErrInvalidReply = errors.New("failed to decode service response")
+)
+
+// Dispatcher - маршрутизирует ответы сервисов к ожидающим их запросам по request_id.
+// Каждый топик ответов читается один раз, а сообщение передается только тому
+// запросу, который его ждет.
+type Dispatcher struct {
+	mu      sync.Mutex
+	pending map[string]chan *sarama.ConsumerMessage
+	logs    *slog.Logger
+}
+
+// NewDispatcher - создание нового экземпляра Dispatcher
+func NewDispatcher(logs *slog.Logger) *Dispatcher {
+	return &Dispatcher{
+		pending: make(map[string]chan *sarama.ConsumerMessage),
+		logs:    logs,
+	}
+}
+
+// replyKey - ключ ожидающего запроса
+func replyKey(requestID, typeQuery string) string {
+	return requestID + "/" + typeQuery
+}
+
+// Register - регистрирует ожидание ответа на запрос.
+// Возвращает канал, в который придет ответ, и функцию для снятия регистрации.
+func (d *Dispatcher) Register(requestID, typeQuery string) (<-chan *sarama.ConsumerMessage, func(), error) {
+	key := replyKey(requestID, typeQuery)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.pending[key]; ok {
+		return nil, nil, ErrDuplicateRequest
+	}
+
+	ch := make(chan *sarama.ConsumerMessage, 1)
+	d.pending[key] = ch
+
+	cancel := func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		if d.pending[key] == ch {
+			delete(d.pending, key)
+		}
+	}
+	return ch, cancel, nil
+}
+
+// Wait - ожидание ответа из канала, полученного в Register, до отмены или дедлайна контекста
+func (d *Dispatcher) Wait(ctx context.Context, ch <-chan *sarama.ConsumerMessage) (*sarama.ConsumerMessage, error) {
+	select {
+	case msg := <-ch:
+		return msg, nil
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, ErrReplyTimeout
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// Listen - читает канал сообщений топика ответов и передает их ожидающим запросам
+func (d *Dispatcher) Listen(ctx context.Context, messages <-chan *sarama.ConsumerMessage) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-messages:
+			if !ok {
+				d.report(slog.LevelError, "response channel closed")
+				return
+			}
+			d.dispatch(msg)
+		}
+	}
+}
+
+// dispatch - передача одного сообщения ожидающему запросу
+func (d *Dispatcher) dispatch(msg *sarama.ConsumerMessage) {
+	// Общие поля ответа, необходимые для маршрутизации
+	var header contracts.Header
+	if err := Decode(msg, &header); err != nil {
+		d.report(slog.LevelError, "failed to decode reply", logger.Topic(msg.Topic), logger.Err(err))
+		return
+	}
+
+	// Идентификатор запроса берется из заголовка, сервисы предыдущих версий передают его только в теле
+	requestID := MetadataFromMessage(msg).WithDefaults(Metadata{RequestID: header.ID}).RequestID
+
+	d.mu.Lock()
+	ch, ok := d.pending[replyKey(requestID, header.TypeQuery)]
+	d.mu.Unlock()
+
+	if !ok {
+		d.report(slog.LevelWarn, "no request waiting for reply", logger.RequestID(requestID), slog.String("type_query", header.TypeQuery), logger.Topic(msg.Topic))
+		return
+	}
+
+	select {
+	case ch <- msg:
+	default:
+		d.report(slog.LevelWarn, "duplicate reply", logger.RequestID(requestID), slog.String("type_query", header.TypeQuery), logger.Topic(msg.Topic))
+	}
+}
+
+// report - запись в лог, если он задан
+func (d *Dispatcher) report(level slog.Level, msg string, attrs ...slog.Attr) {
+	if d.logs != nil {
+		d.logs.LogAttrs(context.Background(), level, msg, attrs...)
+	}
+}
@@ -0,0 +1,142 @@
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+)
+
+func replyMessage(id, typeQuery string) *sarama.ConsumerMessage {
+	return &sarama.ConsumerMessage{
+		Topic: "test_topic",
+		Key:   []byte(id),
+		Value: []byte(fmt.Sprintf(`{"id":%q,"type_query":%q}`, id, typeQuery)),
+	}
+}
+
+// Параллельные запросы получают только свои ответы
+func TestDispatcher_RoutesRepliesByRequestID(t *testing.T) {
+	messages := make(chan *sarama.ConsumerMessage)
+	d := NewDispatcher(nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go d.Listen(ctx, messages)
+
+	const count = 50
+	var wg sync.WaitGroup
+	for i := 0; i < count; i++ {
+		id := fmt.Sprintf("req-%d", i)
+		ch, unregister, err := d.Register(id, "News")
+		assert.NoError(t, err)
+
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			defer unregister()
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			msg, err := d.Wait(ctx, ch)
+			assert.NoError(t, err)
+			assert.Equal(t, id, string(msg.Key))
+		}(id)
+	}
+
+	// Ответы приходят в обратном порядке
+	for i := count - 1; i >= 0; i-- {
+		messages <- replyMessage(fmt.Sprintf("req-%d", i), "News")
+	}
+	wg.Wait()
+}
+
+// Идентификатор запроса из заголовка важнее поля тела
+func TestDispatcher_RoutesRepliesByHeader(t *testing.T) {
+	messages := make(chan *sarama.ConsumerMessage)
+	d := NewDispatcher(nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go d.Listen(ctx, messages)
+
+	ch, unregister, err := d.Register("header-id", "News")
+	assert.NoError(t, err)
+	defer unregister()
+
+	msg := replyMessage("", "News")
+	msg.Headers = []*sarama.RecordHeader{{Key: []byte(HeaderRequestID), Value: []byte("header-id")}}
+	messages <- msg
+
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), time.Second)
+	defer waitCancel()
+	received, err := d.Wait(waitCtx, ch)
+	assert.NoError(t, err)
+	assert.Equal(t, msg, received)
+}
+
+// Ответы с одинаковым request_id различаются по типу запроса
+func TestDispatcher_RoutesRepliesByTypeQuery(t *testing.T) {
+	d := NewDispatcher(nil)
+
+	newsCh, unregisterNews, err := d.Register("req", "OneNews")
+	assert.NoError(t, err)
+	defer unregisterNews()
+
+	commentsCh, unregisterComments, err := d.Register("req", "CommentsByIdNews")
+	assert.NoError(t, err)
+	defer unregisterComments()
+
+	d.dispatch(replyMessage("req", "CommentsByIdNews"))
+	d.dispatch(replyMessage("req", "OneNews"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	msg, err := d.Wait(ctx, newsCh)
+	assert.NoError(t, err)
+	assert.Contains(t, string(msg.Value), "OneNews")
+
+	msg, err = d.Wait(ctx, commentsCh)
+	assert.NoError(t, err)
+	assert.Contains(t, string(msg.Value), "CommentsByIdNews")
+}
+
+// После таймаута регистрация снимается, а опоздавший ответ отбрасывается
+func TestDispatcher_TimeoutCleanup(t *testing.T) {
+	var logs bytes.Buffer
+	d := NewDispatcher(slog.New(slog.NewJSONHandler(&logs, nil)))
+
+	ch, unregister, err := d.Register("req", "News")
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err = d.Wait(ctx, ch)
+	assert.ErrorIs(t, err, ErrReplyTimeout)
+	unregister()
+
+	assert.Empty(t, d.pending)
+
+	d.dispatch(replyMessage("req", "News"))
+	assert.Contains(t, logs.String(), "no request waiting for reply")
+}
+
+func TestDispatcher_DuplicateRequest(t *testing.T) {
+	d := NewDispatcher(nil)
+
+	_, unregister, err := d.Register("req", "News")
+	assert.NoError(t, err)
+	defer unregister()
+
+	_, _, err = d.Register("req", "News")
+	assert.ErrorIs(t, err, ErrDuplicateRequest)
+}
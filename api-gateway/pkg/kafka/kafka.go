@@ -1,92 +1,24 @@
 package kafka
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"news-kafka/api-gateway/pkg/metrics"
+	"news-kafka/api-gateway/pkg/tracing"
+	"news-kafka/contracts"
+	"os"
+	"regexp"
 
 	"github.com/IBM/sarama"
+	"go.opentelemetry.io/otel/trace"
 )
 
-// service-news
-// Публикация, получаемая из RSS.
-type News struct {
-	Id         int
-	Title      string `json:"title"`
-	Content    string `json:"content"`
-	PublicTime int64  `json:"public_time"`
-	ImageLink  string `json:"image_link"`
-	Rubric     string `json:"rubric"`
-	Link       string `json:"link"`
-	LinkTitle  string `json:"link_title"`
-}
-
-// Пагинация.
-type Paginate struct {
-	PageCurr       int `json:"page_curr"`        //Номер текущей страницы
-	PageCount      int `json:"page_count"`       //Количество страниц
-	PageCountList  int `json:"page_count_list"`  //Количество новостей на странице
-	PageCountTotal int `json:"page_count_total"` //Количество всего новостей
-}
-
-// Cтруктура для отправки данных в service
-type SendMessServiceNews struct {
-	ID        string `json:"id"`
-	Name      string `json:"name"`
-	Status    int    `json:"status"`
-	TypeQuery string `json:"type_query"`
-	Rubric    string `json:"rubric"`
-	CountNews int    `json:"count_news"`
-	Filter    string `json:"filter"`
-	Page      int    `json:"page"`
-	IdNews    int    `json:"id_news"`
-}
-
-// Cтруктура для получения данных от service
-type GetMessServiceNews struct {
-	ID        string   `json:"id"`
-	Name      string   `json:"name"`
-	Status    int      `json:"status"`
-	TypeQuery string   `json:"type_query"`
-	News      []News   `json:"news"`
-	Paginate  Paginate `json:"paginate"`
-	IdNews    int      `json:"id_news"`
-}
-
-// service-comments
-// Комментарий к публикации
-type Comment struct {
-	Id          int    `json:"id"`
-	IdNews      int    `json:"id_news"`
-	CommentTime int64  `json:"comment_time"`
-	UserName    string `json:"user_name"`
-	Content     string `json:"content"`
-}
-
-// Cтруктура для передачи данных в service
-type SendMessServiceComments struct {
-	ID          string `json:"id"`
-	Name        string `json:"name"`
-	Status      int    `json:"status"`
-	TypeQuery   string `json:"type_query"`
-	IdNews      int    `json:"id_news"`
-	CommentTime int64  `json:"comment_time"`
-	UserName    string `json:"user_name"`
-	Content     string `json:"content"`
-}
-
-// Cтруктура для получения данных от service
-type GetMessServiceComments struct {
-	ID        string    `json:"id"`
-	Name      string    `json:"name"`
-	Status    int       `json:"status"`
-	TypeQuery string    `json:"type_query"`
-	IdNews    int       `json:"id_news"`
-	Comments  []Comment `json:"comments"`
-}
-
 type ProducerInterface interface {
 	SendMessage(topic string, key string, value []byte) error
+	Send(ctx context.Context, msg *sarama.ProducerMessage) error
 	Close() error
 }
 
@@ -121,6 +53,23 @@ func (p *Producer) SendMessage(topic string, key string, value []byte) error {
 	return nil
 }
 
+// Send - отправка подготовленного сообщения в Kafka, например, с заголовками.
+// Спан отправки передается получателю в заголовке traceparent.
+func (p *Producer) Send(ctx context.Context, msg *sarama.ProducerMessage) error {
+	ctx, span := startSpan(ctx, "publish", trace.SpanKindProducer, msg.Topic)
+	if tp := traceparent(ctx); tp != "" {
+		setHeader(msg, HeaderTraceparent, tp)
+	}
+
+	_, _, err := p.producer.SendMessage(msg)
+	if err != nil {
+		err = fmt.Errorf("failed to send message: %w", err)
+	}
+	metrics.KafkaProduced.WithLabelValues(msg.Topic, metrics.Result(err)).Inc()
+	tracing.End(span, err)
+	return err
+}
+
 // Close - закрытие Producer
 func (p *Producer) Close() error {
 	return p.producer.Close()
@@ -161,17 +110,90 @@ func (c *Consumer) Close() error {
 	return c.consumer.Close()
 }
 
+// DeadlineMs - дедлайн контекста в формате unix, мс; 0 - дедлайна нет
+func DeadlineMs(ctx context.Context) int64 {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return 0
+	}
+	return deadline.UnixMilli()
+}
+
+// CheckBrokers - проверка доступности брокеров Kafka: подключение и получение метаданных кластера
+func CheckBrokers(brokers []string) error {
+	client, err := sarama.NewClient(brokers, nil)
+	if err != nil {
+		return fmt.Errorf("kafka brokers unavailable: %w", err)
+	}
+	defer client.Close()
+
+	if len(client.Brokers()) == 0 {
+		return errors.New("kafka brokers unavailable: no brokers in cluster metadata")
+	}
+	return nil
+}
+
+// EnsureTopic - создание топика, если он еще не существует
+func EnsureTopic(brokers []string, topic string) error {
+	admin, err := sarama.NewClusterAdmin(brokers, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create cluster admin: %w", err)
+	}
+	defer admin.Close()
+
+	err = admin.CreateTopic(topic, &sarama.TopicDetail{NumPartitions: 1, ReplicationFactor: 1}, false)
+	var topicErr *sarama.TopicError
+	if errors.As(err, &topicErr) && topicErr.Err == sarama.ErrTopicAlreadyExists {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create topic %v: %w", topic, err)
+	}
+	return nil
+}
+
+// stringHeader - заголовок сообщения Kafka
+func stringHeader(key, value string) sarama.RecordHeader {
+	return sarama.RecordHeader{Key: []byte(key), Value: []byte(value)}
+}
+
+// недопустимые символы в имени топика
+var invalidTopicChars = regexp.MustCompile(`[^a-zA-Z0-9._-]`)
+
+// InstanceID - идентификатор экземпляра api-gateway из переменной окружения GATEWAYINSTANCEID.
+// Идентификатор обязателен и должен сохраняться при пересоздании контейнера: иначе каждый
+// запуск создает новый топик ответов, который никто не удаляет.
+func InstanceID() (string, error) {
+	id := os.Getenv("GATEWAYINSTANCEID")
+	if id == "" {
+		return "", errors.New("GATEWAYINSTANCEID is not set")
+	}
+	return invalidTopicChars.ReplaceAllString(id, "_"), nil
+}
+
+// ReplyTopic - топик ответов, принадлежащий одному экземпляру api-gateway
+func (c *Config) ReplyTopic(instanceID string) string {
+	return c.TopicReplyPrefix + "." + instanceID
+}
+
 // Config - структура для хранения конфигурации
 type Config struct {
-	KafkaBrokers             []string `json:"kafka_brokers"`
-	TopicResponseNews        string   `json:"topic_response_news"`
-	TopicReceivedNews        string   `json:"topic_received_news"`
-	TopicReceivedOneNews     string   `json:"topic_received_one_news"`
-	TopicResponseComments    string   `json:"topic_response_comments"`
-	TopicReceivedComments    string   `json:"topic_received_comments"`
-	TopicReceivedAddComments string   `json:"topic_received_add_comments"`
-	TopicResponseCensor      string   `json:"topic_response_censor"`
-	TopicReceivedAddCensor   string   `json:"topic_received_add_censor"`
+	KafkaBrokers          []string `json:"kafka_brokers"`
+	TopicResponseNews     string   `json:"topic_response_news"`
+	TopicResponseComments string   `json:"topic_response_comments"`
+	TopicResponseCensor   string   `json:"topic_response_censor"`
+	TopicReplyPrefix      string   `json:"topic_reply_prefix"` //Префикс топика ответов экземпляра, см. ReplyTopic
+	ContentType           string   `json:"content_type"`       //Формат кодирования запросов к сервисам
+	TopicLogs             string   `json:"topic_logs"`         //Топик записей лога для service-logs, пустой - только logs.json
+}
+
+// Codec - кодек для запросов к сервисам
+func (c *Config) Codec() contracts.Codec {
+	codec, err := contracts.CodecFor(c.ContentType)
+	if err != nil {
+		return contracts.JSON
+	}
+	return codec
 }
 
 // readConfig - функция для чтения конфигурации из файла
@@ -189,5 +211,9 @@ func ReadConfig(filePath string) (*Config, error) {
 		return nil, fmt.Errorf("failed to unmarshal config data: %w", err)
 	}
 
+	if _, err := contracts.CodecFor(config.ContentType); err != nil {
+		return nil, err
+	}
+
 	return &config, nil
 }
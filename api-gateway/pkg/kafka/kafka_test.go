@@ -1,14 +1,17 @@
 package kafka
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"os"
 
 	"testing"
 
+	"github.com/IBM/sarama"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 // Mock Producer
@@ -21,6 +24,10 @@ func (m *MockProducer) SendMessage(topic string, key string, value []byte) error
 	return args.Error(0)
 }
 
+func (m *MockProducer) Send(ctx context.Context, msg *sarama.ProducerMessage) error {
+	return m.Called(ctx, msg).Error(0)
+}
+
 func (m *MockProducer) Close() error {
 	return m.Called().Error(0)
 }
@@ -81,8 +88,7 @@ func TestReadConfig_Success(t *testing.T) {
 	// Записываем корректные данные в файл
 	configData := `{
         "kafka_brokers": ["localhost:9092"],
-        "topic_response_news": "response_news",
-        "topic_received_news": "received_news"
+        "topic_response_news": "response_news"
     }`
 	if _, err := tmpFile.Write([]byte(configData)); err != nil {
 		t.Fatalf("failed to write to temp file: %v", err)
@@ -102,9 +108,6 @@ func TestReadConfig_Success(t *testing.T) {
 	if config.TopicResponseNews != "response_news" {
 		t.Fatalf("unexpected topic_response_news: %s", config.TopicResponseNews)
 	}
-	if config.TopicReceivedNews != "received_news" {
-		t.Fatalf("unexpected topic_received_news: %s", config.TopicReceivedNews)
-	}
 }
 
 func TestReadConfig_FileNotFound(t *testing.T) {
@@ -135,3 +138,18 @@ func TestReadConfig_InvalidJSON(t *testing.T) {
 		t.Fatalf("expected error, got none")
 	}
 }
+
+func TestReplyTopic(t *testing.T) {
+	os.Setenv("GATEWAYINSTANCEID", "api-gateway/replica:1")
+	defer os.Unsetenv("GATEWAYINSTANCEID")
+
+	config := Config{TopicReplyPrefix: "api-gateway-reply"}
+
+	instanceID, err := InstanceID()
+	require.NoError(t, err)
+	assert.Equal(t, "api-gateway-reply.api-gateway_replica_1", config.ReplyTopic(instanceID))
+
+	os.Unsetenv("GATEWAYINSTANCEID")
+	_, err = InstanceID()
+	assert.Error(t, err)
+}
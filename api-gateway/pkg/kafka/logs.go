@@ -0,0 +1,88 @@
+package kafka
+
+import (
+	"encoding/json"
+	"fmt"
+	"news-kafka/api-gateway/pkg/logger"
+	"os"
+	"sync"
+)
+
+// Размер очереди записей лога, ожидающих отправки в топик логов
+const logQueueSize = 1000
+
+// LogPublisher - публикация записей лога в топик логов, из которого их забирает service-logs.
+// Записи отправляются в отдельной горутине: при заполненной очереди или ошибке Kafka
+// они остаются только в logs.json, а сообщение об этом выводится в stderr.
+type LogPublisher struct {
+	producer ProducerInterface
+	topic    string
+
+	mu      sync.Mutex
+	entries chan logger.RequestLog
+	closed  bool
+	done    chan struct{}
+}
+
+// NewLogPublisher - запуск публикации записей лога в topic
+func NewLogPublisher(producer ProducerInterface, topic string) *LogPublisher {
+	p := &LogPublisher{
+		producer: producer,
+		topic:    topic,
+		entries:  make(chan logger.RequestLog, logQueueSize),
+		done:     make(chan struct{}),
+	}
+	go p.run()
+	return p
+}
+
+// Publish - постановка записей в очередь отправки, не ждет Kafka
+func (p *LogPublisher) Publish(entries []logger.RequestLog) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return
+	}
+	dropped := 0
+	for _, entry := range entries {
+		select {
+		case p.entries <- entry:
+		default:
+			dropped++
+		}
+	}
+	if dropped > 0 {
+		fmt.Fprintf(os.Stderr, "kafka: log queue is full, %v entries not published to %v\n", dropped, p.topic)
+	}
+}
+
+// run - отправка записей до Close. Ключ сообщения - request_id,
+// поэтому записи одного запроса попадают в одну партицию по порядку
+func (p *LogPublisher) run() {
+	defer close(p.done)
+
+	for entry := range p.entries {
+		value, err := json.Marshal(entry)
+		if err == nil {
+			err = p.producer.SendMessage(p.topic, entry.RequestID, value)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "kafka: failed to publish log entry to %v: %v\n", p.topic, err)
+		}
+	}
+}
+
+// Close - отправка записей из очереди и остановка, последующие записи не публикуются
+func (p *LogPublisher) Close() {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.closed = true
+	close(p.entries)
+	p.mu.Unlock()
+
+	<-p.done
+}
@@ -0,0 +1,119 @@
+package kafka
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/IBM/sarama"
+)
+
+// Заголовки Kafka с метаданными запроса
+const (
+	HeaderRequestID     = "request-id"     //Сквозной идентификатор запроса
+	HeaderReplyTo       = "reply-to"       //Топик для ответа
+	HeaderDeadline      = "deadline"       //Время (unix, мс), после которого ответ уже не нужен
+	HeaderTraceparent   = "traceparent"    //Контекст трассировки W3C Trace Context
+	HeaderSchemaVersion = "schema-version" //Версия схемы сообщения
+	HeaderSource        = "source"         //Имя сервиса-отправителя
+)
+
+// Metadata - метаданные запроса, передаваемые в заголовках сообщения
+type Metadata struct {
+	RequestID     string
+	ReplyTo       string
+	Deadline      int64
+	Traceparent   string
+	SchemaVersion int
+	ContentType   string
+	Source        string
+}
+
+type metadataKey struct{}
+
+// ContextWithMetadata - добавление метаданных в контекст
+func ContextWithMetadata(ctx context.Context, md Metadata) context.Context {
+	return context.WithValue(ctx, metadataKey{}, md)
+}
+
+// MetadataFromContext - метаданные из контекста, пустые, если их нет
+func MetadataFromContext(ctx context.Context) Metadata {
+	md, _ := ctx.Value(metadataKey{}).(Metadata)
+	return md
+}
+
+// MetadataFromMessage - метаданные из заголовков полученного сообщения
+func MetadataFromMessage(msg *sarama.ConsumerMessage) Metadata {
+	var md Metadata
+	for _, h := range msg.Headers {
+		if h == nil {
+			continue
+		}
+		value := string(h.Value)
+		switch string(h.Key) {
+		case HeaderRequestID:
+			md.RequestID = value
+		case HeaderReplyTo:
+			md.ReplyTo = value
+		case HeaderDeadline:
+			md.Deadline, _ = strconv.ParseInt(value, 10, 64)
+		case HeaderTraceparent:
+			md.Traceparent = value
+		case HeaderSchemaVersion:
+			md.SchemaVersion, _ = strconv.Atoi(value)
+		case HeaderContentType:
+			md.ContentType = value
+		case HeaderSource:
+			md.Source = value
+		}
+	}
+	return md
+}
+
+// WithDefaults - заполнение пустых полей значениями из defaults,
+// например, полями тела сообщения от отправителей без заголовков
+func (md Metadata) WithDefaults(defaults Metadata) Metadata {
+	if md.RequestID == "" {
+		md.RequestID = defaults.RequestID
+	}
+	if md.ReplyTo == "" {
+		md.ReplyTo = defaults.ReplyTo
+	}
+	if md.Deadline == 0 {
+		md.Deadline = defaults.Deadline
+	}
+	if md.Traceparent == "" {
+		md.Traceparent = defaults.Traceparent
+	}
+	if md.SchemaVersion == 0 {
+		md.SchemaVersion = defaults.SchemaVersion
+	}
+	if md.ContentType == "" {
+		md.ContentType = defaults.ContentType
+	}
+	if md.Source == "" {
+		md.Source = defaults.Source
+	}
+	return md
+}
+
+// headers - заголовки сообщения, пустые поля не передаются
+func (md Metadata) headers() []sarama.RecordHeader {
+	var headers []sarama.RecordHeader
+	add := func(key, value string) {
+		if value != "" {
+			headers = append(headers, stringHeader(key, value))
+		}
+	}
+	add(HeaderRequestID, md.RequestID)
+	add(HeaderReplyTo, md.ReplyTo)
+	if md.Deadline != 0 {
+		add(HeaderDeadline, strconv.FormatInt(md.Deadline, 10))
+	}
+	add(HeaderTraceparent, md.Traceparent)
+	if md.SchemaVersion != 0 {
+		add(HeaderSchemaVersion, strconv.Itoa(md.SchemaVersion))
+	}
+	add(HeaderContentType, md.ContentType)
+	add(HeaderSource, md.Source)
+	return headers
+}
@@ -0,0 +1,47 @@
+package kafka
+
+import (
+	"context"
+	"news-kafka/api-gateway/pkg/tracing"
+
+	"github.com/IBM/sarama"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ContextWithTrace - контекст с родительским спаном из traceparent метаданных
+func ContextWithTrace(ctx context.Context, md Metadata) context.Context {
+	if md.Traceparent == "" {
+		return ctx
+	}
+	return propagation.TraceContext{}.Extract(ctx, propagation.MapCarrier{HeaderTraceparent: md.Traceparent})
+}
+
+// traceparent - traceparent текущего спана контекста, пустой, если спана нет
+func traceparent(ctx context.Context) string {
+	carrier := propagation.MapCarrier{}
+	propagation.TraceContext{}.Inject(ctx, carrier)
+	return carrier.Get(HeaderTraceparent)
+}
+
+// startSpan - спан операции с сообщением топика Kafka
+func startSpan(ctx context.Context, operation string, kind trace.SpanKind, topic string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(attrs,
+		attribute.String("messaging.system", "kafka"),
+		attribute.String("messaging.operation", operation),
+		attribute.String("messaging.destination.name", topic),
+	)
+	return tracing.Tracer().Start(ctx, topic+" "+operation, trace.WithSpanKind(kind), trace.WithAttributes(attrs...))
+}
+
+// setHeader - замена или добавление заголовка сообщения
+func setHeader(msg *sarama.ProducerMessage, key, value string) {
+	for i := range msg.Headers {
+		if string(msg.Headers[i].Key) == key {
+			msg.Headers[i].Value = []byte(value)
+			return
+		}
+	}
+	msg.Headers = append(msg.Headers, stringHeader(key, value))
+}
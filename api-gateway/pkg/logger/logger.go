@@ -5,7 +5,9 @@ package logger
 import (
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"net"
+	"news-kafka/contracts"
 	"os"
 	"sync"
 	"time"
@@ -13,14 +15,31 @@ import (
 	"github.com/google/uuid"
 )
 
-// RequestLog структура для лога запроса
-type RequestLog struct {
-	Timestamp   time.Time `json:"timestamp"`
-	ServiceID   string    `json:"service_id"`
-	RequestID   string    `json:"request_id"`
-	RemoteAddr  string    `json:"remote_addr"`
-	StatusCode  int       `json:"status_code"`
-	DataRequest string    `json:"data_request"`
+// RequestLog структура для лога запроса, формат общий для всех сервисов
+type RequestLog = contracts.LogEntry
+
+// Publisher - дополнительный получатель записанных в файл записей, например, топик логов Kafka.
+// Publish вызывается под блокировкой логгера и не должен ждать отправки.
+type Publisher interface {
+	Publish(entries []RequestLog)
+}
+
+// Options - настройки записи логов в файл
+type Options struct {
+	BufferSize    int           //Количество записей в буфере, при заполнении буфер записывается в файл
+	FlushInterval time.Duration //Интервал записи буфера в файл, 0 - только при заполнении и Close
+	MaxSizeBytes  int64         //Размер файла, после которого он ротируется, 0 - без ротации по размеру
+	Daily         bool          //Ротация файла при смене дня
+	MaxBackups    int           //Количество хранимых сжатых файлов, 0 - хранить все
+}
+
+// DefaultOptions - настройки по умолчанию
+var DefaultOptions = Options{
+	BufferSize:    50,
+	FlushInterval: 5 * time.Second,
+	MaxSizeBytes:  10 << 20,
+	Daily:         true,
+	MaxBackups:    7,
 }
 
 // Logger для записи запросов
@@ -28,21 +47,45 @@ type Logger struct {
 	mu         sync.Mutex
 	logs       []RequestLog
 	bufferSize int
+	opts       Options
+	path       string
 	file       *os.File
+	size       int64  //Размер текущего файла
+	day        string //День записей текущего файла
+	stop       chan struct{}
+	publisher  Publisher
+	wg         sync.WaitGroup //Периодическая запись и сжатие ротированных файлов
 }
 
-// NewLogger создает новый экземпляр логгера
+// NewLogger создает новый экземпляр логгера, буфер записывается в файл только при заполнении и Close
 func NewLogger(filePath string, bufferSize int) (*Logger, error) {
-	file, err := os.OpenFile(filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
+	return NewLoggerWithOptions(filePath, Options{BufferSize: bufferSize})
+}
+
+// NewLoggerWithOptions создает новый экземпляр логгера с периодической записью буфера,
+// ротацией файла по размеру и по дням и сжатием ротированных файлов в gzip
+func NewLoggerWithOptions(filePath string, opts Options) (*Logger, error) {
+	if opts.BufferSize < 1 {
+		opts.BufferSize = 1
+	}
+
+	l := &Logger{
+		logs:       make([]RequestLog, 0, opts.BufferSize),
+		bufferSize: opts.BufferSize,
+		opts:       opts,
+		path:       filePath,
+		stop:       make(chan struct{}),
+	}
+	if err := l.open(); err != nil {
 		return nil, err
 	}
 
-	return &Logger{
-		logs:       make([]RequestLog, 0, bufferSize),
-		bufferSize: bufferSize,
-		file:       file,
-	}, nil
+	if opts.FlushInterval > 0 {
+		l.wg.Add(1)
+		go l.flushEvery(opts.FlushInterval)
+	}
+
+	return l, nil
 }
 
 // LogRequest логирует запрос
@@ -50,49 +93,125 @@ func (l *Logger) LogRequest(requestID, remoteAddr string, statusCode int, dataRe
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
-	logEntry := RequestLog{
+	l.write(RequestLog{
 		Timestamp:   time.Now(),
 		ServiceID:   GetServiceName(),
 		RequestID:   requestID,
 		RemoteAddr:  remoteAddr,
 		StatusCode:  statusCode,
 		DataRequest: dataRequest,
-	}
+	})
+}
+
+// Log записывает подготовленную запись
+func (l *Logger) Log(entry RequestLog) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
 
+	l.write(entry)
+}
+
+// write добавляет запись в буфер, вызывается под блокировкой
+func (l *Logger) write(logEntry RequestLog) {
+	logEntry.Version = contracts.SchemaVersion
 	l.logs = append(l.logs, logEntry)
 
-	// Проверяем, если буфер заполнен
-	if len(l.logs) >= l.bufferSize {
+	// Проверяем, если буфер заполнен. Ошибки записываются сразу,
+	// чтобы не потерять их при аварийном завершении процесса
+	if len(l.logs) >= l.bufferSize || logEntry.Level == slog.LevelError.String() {
 		l.flush()
 	}
 }
 
-// flush записывает логи в файл
+// flush записывает логи в файл, ошибки записи выводятся в stderr
 func (l *Logger) flush() {
 	if len(l.logs) == 0 {
 		return
 	}
 
-	// Преобразуем логи в JSON и записываем в файл
-	encoder := json.NewEncoder(l.file)
 	for _, log := range l.logs {
-		if err := encoder.Encode(log); err != nil {
-			// Здесь можно обработать ошибку, например, записать в stderr
+		line, err := json.Marshal(log)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "logger: failed to encode log entry: %v\n", err)
+			continue
+		}
+		line = append(line, '\n')
+
+		if l.file == nil {
+			fmt.Fprintf(os.Stderr, "logger: log file is closed, entry lost: %s", line)
 			continue
 		}
+		if l.needRotate(log.Timestamp, len(line)) {
+			if err := l.rotate(); err != nil {
+				fmt.Fprintf(os.Stderr, "logger: failed to rotate %v: %v\n", l.path, err)
+			}
+		}
+
+		n, err := l.file.Write(line)
+		l.size += int64(n)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "logger: failed to write %v: %v\n", l.path, err)
+		}
+	}
+
+	if l.publisher != nil {
+		l.publisher.Publish(append([]RequestLog(nil), l.logs...))
 	}
 
 	// Очищаем буфер
 	l.logs = l.logs[:0]
 }
 
-// Close закрывает логгер и записывает оставшиеся логи
-func (l *Logger) Close() error {
+// SetPublisher задает получателя записей, nil - только запись в файл
+func (l *Logger) SetPublisher(p Publisher) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.publisher = p
+}
+
+// Flush записывает буфер в файл и передает записи получателю
+func (l *Logger) Flush() {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
+	l.flush()
+}
+
+// flushEvery - периодическая запись буфера в файл до Close
+func (l *Logger) flushEvery(interval time.Duration) {
+	defer l.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			l.mu.Lock()
+			l.flush()
+			l.mu.Unlock()
+		}
+	}
+}
+
+// Close закрывает логгер и записывает оставшиеся логи.
+// Ожидает завершения сжатия ротированных файлов.
+func (l *Logger) Close() error {
+	l.mu.Lock()
+	if l.file == nil {
+		l.mu.Unlock()
+		return nil
+	}
+	close(l.stop)
 	l.flush() // Записываем оставшиеся записи
-	return l.file.Close()
+	err := l.file.Close()
+	l.file = nil
+	l.mu.Unlock()
+
+	l.wg.Wait()
+	return err
 }
 
 // GetRequestId возвращает id запроса
@@ -0,0 +1,77 @@
+package logger
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactor_Body(t *testing.T) {
+	r, err := NewRedactor(RedactRules{
+		Fields:   []string{"user_name", "comments.content", "*.token"},
+		Emails:   true,
+		Phones:   true,
+		Patterns: []string{`secret-\d+`},
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"fields", `{"user_name":"gopher","id_news":7,"content":"nice"}`, `{"content":"nice","id_news":7,"user_name":"[REDACTED]"}`},
+		{"nested and arrays", `{"comments":[{"content":"a","id":1},{"content":"b","id":2}],"auth":{"token":"t"}}`,
+			`{"auth":{"token":"[REDACTED]"},"comments":[{"content":"[REDACTED]","id":1},{"content":"[REDACTED]","id":2}]}`},
+		{"strings", `{"content":"mail me at go.pher+news@example.com or +7 (999) 123-45-67, 8 999 123 45 67, secret-42"}`,
+			`{"content":"mail me at [REDACTED] or [REDACTED], [REDACTED], [REDACTED]"}`},
+		{"ids are kept", `{"id":"550e8400-e29b-41d4-a716-446655440000","comment_time":1730100873}`,
+			`{"comment_time":1730100873,"id":"550e8400-e29b-41d4-a716-446655440000"}`},
+		{"not json", `user=gopher@example.com`, `user=[REDACTED]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attr := r.Body([]byte(tt.body))
+			assert.Equal(t, "body", attr.Key)
+			if raw, ok := attr.Value.Any().(json.RawMessage); ok {
+				assert.JSONEq(t, tt.want, string(raw))
+			} else {
+				assert.Equal(t, tt.want, attr.Value.String())
+			}
+		})
+	}
+}
+
+func TestRedactor_MaxBodyBytes(t *testing.T) {
+	r, err := NewRedactor(RedactRules{MaxBodyBytes: 10})
+	require.NoError(t, err)
+
+	attr := r.Body([]byte(`{"content":"` + strings.Repeat("я", 20) + `"}`))
+	assert.Equal(t, `{"content"...(truncated, 54 bytes)`, attr.Value.String())
+
+	attr = r.Body([]byte(`{"a":1}`))
+	assert.Equal(t, json.RawMessage(`{"a":1}`), attr.Value.Any())
+}
+
+func TestRedactor_Addr(t *testing.T) {
+	r := DefaultRedactor()
+	assert.Equal(t, "192.168.1.0:54321", r.Addr("192.168.1.17:54321"))
+	assert.Equal(t, "10.0.0.0", r.Addr("10.0.0.9"))
+	assert.Equal(t, "[2001:db8:85a3::]:8080", r.Addr("[2001:db8:85a3:8d3:1319:8a2e:370:7348]:8080"))
+	assert.Equal(t, "localhost:8080", r.Addr("localhost:8080"))
+
+	r, err := NewRedactor(RedactRules{})
+	require.NoError(t, err)
+	assert.Equal(t, "192.168.1.17:54321", r.Addr("192.168.1.17:54321"))
+}
+
+func TestNewRedactor_Errors(t *testing.T) {
+	_, err := NewRedactor(RedactRules{Patterns: []string{"("}})
+	assert.Error(t, err)
+
+	_, err = NewRedactor(RedactRules{Fields: []string{""}})
+	assert.Error(t, err)
+}
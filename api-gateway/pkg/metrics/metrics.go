@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Метрики api-gateway в формате Prometheus.
+// Реестр свой у каждого сервиса, поэтому сервисы можно запускать в одном процессе (e2e).
+var (
+	// Registry - реестр метрик сервиса вместе с метриками процесса и Go
+	Registry = newRegistry()
+	factory  = promauto.With(Registry)
+
+	// HTTPRequests - количество HTTP-запросов по маршруту, методу и коду ответа
+	HTTPRequests = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests by route, method and status code.",
+	}, []string{"route", "method", "code"})
+
+	// HTTPDuration - время обработки HTTP-запросов по маршруту и методу
+	HTTPDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "HTTP request latency by route and method.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route", "method"})
+
+	// HTTPInFlight - количество HTTP-запросов в обработке
+	HTTPInFlight = factory.NewGauge(prometheus.GaugeOpts{
+		Name: "http_requests_in_flight",
+		Help: "HTTP requests currently being served.",
+	})
+
+	// KafkaProduced - количество отправленных в Kafka сообщений по топику и результату
+	KafkaProduced = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "kafka_messages_produced_total",
+		Help: "Messages produced to Kafka by topic and result.",
+	}, []string{"topic", "result"})
+)
+
+// Handler - HTTP-обработчик /metrics
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// newRegistry - реестр со стандартными метриками процесса и Go
+func newRegistry() *prometheus.Registry {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	return registry
+}
+
+// Result - значение метки result по ошибке операции
+func Result(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}
@@ -0,0 +1,124 @@
+// Package ratelimit - ограничение частоты запросов по алгоритму token bucket.
+// Состояние хранится в памяти процесса (MemoryStore) или на Redis-совместимом сервере
+// (RedisStore), если лимиты должны быть общими для нескольких экземпляров api-gateway.
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limit - параметры корзины токенов
+type Limit struct {
+	RatePerSec float64 `json:"rate_per_sec"` //Скорость пополнения корзины, запросов в секунду; 0 - без ограничения
+	Burst      int     `json:"burst"`        //Емкость корзины - количество запросов подряд, не меньше 1
+}
+
+// Unlimited - лимит не задан
+func (l Limit) Unlimited() bool {
+	return l.RatePerSec <= 0
+}
+
+// capacity - емкость корзины
+func (l Limit) capacity() float64 {
+	if l.Burst < 1 {
+		return 1
+	}
+	return float64(l.Burst)
+}
+
+// Result - результат попытки взять токен
+type Result struct {
+	Allowed    bool          //Запрос разрешен
+	Remaining  int           //Токенов осталось в корзине
+	RetryAfter time.Duration //Через сколько появится токен, если запрос не разрешен
+}
+
+// Store - хранилище корзин токенов по ключу
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// Интервал удаления корзин, которые успели наполниться и не отличаются от новых
+const sweepInterval = time.Minute
+
+// MemoryStore - корзины токенов в памяти процесса
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// bucket - состояние корзины
+type bucket struct {
+	tokens float64
+	last   time.Time
+	full   time.Time //Время, когда корзина наполнится без новых запросов
+}
+
+// NewMemoryStore - хранилище корзин в памяти
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// Take - попытка взять токен из корзины key
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	if limit.Unlimited() {
+		return Result{Allowed: true}, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	capacity := limit.capacity()
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, last: now}
+		s.buckets[key] = b
+	}
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = min(capacity, b.tokens+elapsed*limit.RatePerSec)
+		b.last = now
+	}
+
+	result := Result{}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1 - b.tokens) / limit.RatePerSec * float64(time.Second))
+	}
+	result.Remaining = int(b.tokens)
+	b.full = now.Add(time.Duration((capacity - b.tokens) / limit.RatePerSec * float64(time.Second)))
+
+	return result, nil
+}
+
+// sweep - удаление наполнившихся корзин, вызывается под блокировкой
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for key, b := range s.buckets {
+		if !now.Before(b.full) {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+// Len - количество корзин в памяти
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.buckets)
+}
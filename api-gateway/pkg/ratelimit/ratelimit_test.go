@@ -0,0 +1,104 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStore_TokenBucket(t *testing.T) {
+	now := time.Date(2024, 10, 28, 10, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	limit := Limit{RatePerSec: 2, Burst: 3}
+
+	// Емкость корзины - запросы подряд без ожидания
+	for i := 2; i >= 0; i-- {
+		result, err := store.Take(context.Background(), "client", limit)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, i, result.Remaining)
+	}
+
+	result, err := store.Take(context.Background(), "client", limit)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 500*time.Millisecond, result.RetryAfter)
+
+	// Другой ключ - своя корзина
+	result, _ = store.Take(context.Background(), "other", limit)
+	assert.True(t, result.Allowed)
+
+	// За 500 мс корзина пополняется на один токен
+	now = now.Add(500 * time.Millisecond)
+	result, _ = store.Take(context.Background(), "client", limit)
+	assert.True(t, result.Allowed)
+	result, _ = store.Take(context.Background(), "client", limit)
+	assert.False(t, result.Allowed)
+}
+
+func TestMemoryStore_Unlimited(t *testing.T) {
+	store := NewMemoryStore()
+	for i := 0; i < 100; i++ {
+		result, err := store.Take(context.Background(), "client", Limit{})
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+	}
+	assert.Zero(t, store.Len())
+}
+
+func TestMemoryStore_Sweep(t *testing.T) {
+	now := time.Date(2024, 10, 28, 10, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+
+	store.Take(context.Background(), "idle", Limit{RatePerSec: 1, Burst: 2})
+	store.Take(context.Background(), "slow", Limit{RatePerSec: 0.001, Burst: 2})
+	assert.Equal(t, 2, store.Len())
+
+	// Наполнившаяся корзина не отличается от новой и удаляется
+	now = now.Add(2 * sweepInterval)
+	store.Take(context.Background(), "new", Limit{RatePerSec: 1, Burst: 2})
+	assert.Equal(t, 2, store.Len())
+}
+
+// Сервер для теста RedisStore: проверяет аргументы скрипта и возвращает заданный ответ
+type testRedis struct {
+	keys  []string
+	args  []any
+	reply any
+	err   error
+}
+
+func (r *testRedis) Eval(ctx context.Context, script string, keys []string, args ...any) (any, error) {
+	r.keys, r.args = keys, args
+	return r.reply, r.err
+}
+
+func TestRedisStore(t *testing.T) {
+	client := &testRedis{reply: []any{int64(0), int64(0), int64(250)}}
+	store := NewRedisStore(client, "ratelimit:")
+
+	result, err := store.Take(context.Background(), "news|10.0.0.1", Limit{RatePerSec: 4, Burst: 8})
+	require.NoError(t, err)
+	assert.Equal(t, Result{Allowed: false, Remaining: 0, RetryAfter: 250 * time.Millisecond}, result)
+	assert.Equal(t, []string{"ratelimit:news|10.0.0.1"}, client.keys)
+	assert.Equal(t, []any{4.0, 8}, client.args)
+
+	client.reply = []any{int64(1), int64(7), int64(0)}
+	result, err = store.Take(context.Background(), "news|10.0.0.1", Limit{RatePerSec: 4, Burst: 8})
+	require.NoError(t, err)
+	assert.Equal(t, Result{Allowed: true, Remaining: 7}, result)
+
+	client.reply = "OK"
+	_, err = store.Take(context.Background(), "news|10.0.0.1", Limit{RatePerSec: 4, Burst: 8})
+	assert.Error(t, err)
+
+	client.err = errors.New("connection refused")
+	_, err = store.Take(context.Background(), "news|10.0.0.1", Limit{RatePerSec: 4, Burst: 8})
+	assert.ErrorContains(t, err, "connection refused")
+}
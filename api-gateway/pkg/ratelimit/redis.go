@@ -0,0 +1,86 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// RedisClient - выполнение Lua-скрипта на Redis-совместимом сервере (Redis, Valkey, KeyDB).
+// Для go-redis: func(ctx, script, keys, args...) { return client.Eval(ctx, script, keys, args...).Result() }
+type RedisClient interface {
+	Eval(ctx context.Context, script string, keys []string, args ...any) (any, error)
+}
+
+// tokenBucketScript - атомарное обновление корзины на сервере. Время берется из TIME сервера,
+// поэтому расхождение часов экземпляров api-gateway не влияет на лимиты.
+// Корзина удаляется сервером после того, как успеет наполниться.
+const tokenBucketScript = `
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'last')
+local tokens = tonumber(state[1]) or burst
+local last = tonumber(state[2]) or now
+if now > last then
+  tokens = math.min(burst, tokens + (now - last) / 1000 * rate)
+  last = now
+end
+
+local allowed = 0
+local retry = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+else
+  retry = math.ceil((1 - tokens) / rate * 1000)
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'last', tostring(last))
+redis.call('PEXPIRE', KEYS[1], math.ceil((burst - tokens) / rate * 1000) + 1000)
+return {allowed, math.floor(tokens), retry}
+`
+
+// RedisStore - корзины токенов на Redis-совместимом сервере, общие для всех экземпляров api-gateway
+type RedisStore struct {
+	client RedisClient
+	prefix string
+}
+
+// NewRedisStore - хранилище корзин на сервере, prefix добавляется к ключам корзин
+func NewRedisStore(client RedisClient, prefix string) *RedisStore {
+	return &RedisStore{client: client, prefix: prefix}
+}
+
+// Take - попытка взять токен из корзины key
+func (s *RedisStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	if limit.Unlimited() {
+		return Result{Allowed: true}, nil
+	}
+
+	reply, err := s.client.Eval(ctx, tokenBucketScript, []string{s.prefix + key}, limit.RatePerSec, int(limit.capacity()))
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to take token: %w", err)
+	}
+
+	values, ok := reply.([]any)
+	if !ok || len(values) != 3 {
+		return Result{}, fmt.Errorf("unexpected token bucket reply: %v", reply)
+	}
+	var ints [3]int64
+	for i, v := range values {
+		n, ok := v.(int64)
+		if !ok {
+			return Result{}, fmt.Errorf("unexpected token bucket reply: %v", reply)
+		}
+		ints[i] = n
+	}
+
+	return Result{
+		Allowed:    ints[0] == 1,
+		Remaining:  int(ints[1]),
+		RetryAfter: time.Duration(ints[2]) * time.Millisecond,
+	}, nil
+}
@@ -0,0 +1,75 @@
+package contracts
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Форматы кодирования сообщений, передаются в заголовке content-type
+const (
+	ContentTypeJSON     = "application/json"
+	ContentTypeProtobuf = "application/x-protobuf"
+)
+
+// Codec - кодирование сообщений в формат передачи
+type Codec interface {
+	ContentType() string
+	Marshal(msg Message) ([]byte, error)
+	Unmarshal(data []byte, msg Message) error
+}
+
+// Кодеки сообщений
+var (
+	JSON     Codec = jsonCodec{}
+	Protobuf Codec = protobufCodec{}
+)
+
+// CodecFor - кодек для заголовка content-type.
+// Сообщения без заголовка отправлены до появления кодеков и закодированы в JSON.
+func CodecFor(contentType string) (Codec, error) {
+	switch contentType {
+	case "", ContentTypeJSON:
+		return JSON, nil
+	case ContentTypeProtobuf:
+		return Protobuf, nil
+	}
+	return nil, fmt.Errorf("unsupported content type: %v", contentType)
+}
+
+// jsonCodec - кодирование в JSON
+type jsonCodec struct{}
+
+func (jsonCodec) ContentType() string {
+	return ContentTypeJSON
+}
+
+func (jsonCodec) Marshal(msg Message) ([]byte, error) {
+	*msg.schemaVersion() = SchemaVersion
+	return json.Marshal(msg)
+}
+
+func (jsonCodec) Unmarshal(data []byte, msg Message) error {
+	if err := json.Unmarshal(data, msg); err != nil {
+		return err
+	}
+	return checkVersion(msg)
+}
+
+// protobufCodec - кодирование в Protobuf по схеме messages.proto
+type protobufCodec struct{}
+
+func (protobufCodec) ContentType() string {
+	return ContentTypeProtobuf
+}
+
+func (protobufCodec) Marshal(msg Message) ([]byte, error) {
+	*msg.schemaVersion() = SchemaVersion
+	return msg.marshalProto(), nil
+}
+
+func (protobufCodec) Unmarshal(data []byte, msg Message) error {
+	if err := msg.unmarshalProto(data); err != nil {
+		return fmt.Errorf("failed to decode protobuf: %w", err)
+	}
+	return checkVersion(msg)
+}
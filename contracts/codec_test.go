@@ -0,0 +1,93 @@
+package contracts
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodecs_RoundTrip(t *testing.T) {
+	messages := []struct {
+		name    string
+		msg     Message
+		decoded func() Message
+	}{
+		{"NewsRequest", &NewsRequest{ID: "req", Name: "api-gateway", TypeQuery: TypeNews, Rubric: "sport", CountNews: 10, Filter: "cup", Page: 2, ReplyTo: "reply", Deadline: 1700000003000},
+			func() Message { return &NewsRequest{} }},
+		{"NewsReply", &NewsReply{ID: "req", TypeQuery: TypeOneNews, Reply: ReplyFail(StatusNotFound, "news not found", map[string]string{"id_news": "7"}),
+			News:     []News{{Id: 7, Title: "Title", PublicTime: 1700000000, Link: "https://example.com"}, {}},
+			Paginate: Paginate{PageCurr: 1, PageCount: 3, PageCountList: 10, PageCountTotal: 25}, IdNews: 7},
+			func() Message { return &NewsReply{} }},
+		{"CommentsRequest", &CommentsRequest{ID: "req", TypeQuery: TypeCommentUpdate, IdNews: 7, CommentTime: 1700000000, UserName: "gopher", Content: "привет", IdComment: 3, ParentId: 2, Deadline: -1},
+			func() Message { return &CommentsRequest{} }},
+		{"CommentsReply", &CommentsReply{ID: "req", TypeQuery: TypeCommentsByIdNews, Reply: ReplyOK(), IdNews: 7, Comments: []Comment{{Id: 1, IdNews: 7, UserName: "gopher", Content: "nice", EditedAt: 1700000005, ParentId: 2, Depth: 1}}},
+			func() Message { return &CommentsReply{} }},
+	}
+
+	for _, codec := range []Codec{JSON, Protobuf} {
+		for _, tt := range messages {
+			t.Run(codec.ContentType()+"/"+tt.name, func(t *testing.T) {
+				data, err := codec.Marshal(tt.msg)
+				require.NoError(t, err)
+
+				decoded := tt.decoded()
+				require.NoError(t, codec.Unmarshal(data, decoded))
+				assert.Equal(t, tt.msg, decoded)
+
+				// Общие поля читаются из любого сообщения
+				var header Header
+				require.NoError(t, codec.Unmarshal(data, &header))
+				assert.Equal(t, SchemaVersion, header.Version)
+				assert.Equal(t, "req", header.ID)
+				assert.NotEmpty(t, header.TypeQuery)
+			})
+		}
+	}
+}
+
+// Байты совпадают с кодированием protoc для той же схемы
+func TestProtobuf_WireFormat(t *testing.T) {
+	msg := Header{ID: "a", TypeQuery: "News"}
+	data, err := Protobuf.Marshal(&msg)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x08, 0x02, 0x12, 0x01, 'a', 0x22, 0x04, 'N', 'e', 'w', 's'}, data)
+}
+
+// Ответ любого сервиса на Ping декодируется как PingReply
+func TestPingReply_FromServiceReplies(t *testing.T) {
+	reply := ReplyFail(StatusInternal, "postgres unavailable", map[string]string{PingPostgres: "connection refused"})
+	replies := []Message{
+		&NewsReply{ID: "ping", TypeQuery: TypePing, Reply: reply, News: []News{{Id: 1}}},
+		&CommentsReply{ID: "ping", TypeQuery: TypePing, Reply: reply, Comments: []Comment{{Id: 1}}},
+	}
+
+	for _, codec := range []Codec{JSON, Protobuf} {
+		for _, msg := range replies {
+			data, err := codec.Marshal(msg)
+			require.NoError(t, err)
+
+			var ping PingReply
+			require.NoError(t, codec.Unmarshal(data, &ping))
+			assert.Equal(t, PingReply{Version: SchemaVersion, ID: "ping", TypeQuery: TypePing, Reply: reply}, ping)
+		}
+	}
+}
+
+func TestProtobuf_Malformed(t *testing.T) {
+	var msg NewsReply
+	assert.Error(t, Protobuf.Unmarshal([]byte{0x42, 0x05, 0x01}, &msg))
+}
+
+func TestCodecFor(t *testing.T) {
+	codec, err := CodecFor("")
+	require.NoError(t, err)
+	assert.Equal(t, JSON, codec)
+
+	codec, err = CodecFor(ContentTypeProtobuf)
+	require.NoError(t, err)
+	assert.Equal(t, Protobuf, codec)
+
+	_, err = CodecFor("text/xml")
+	assert.Error(t, err)
+}
@@ -0,0 +1,51 @@
+package contracts
+
+// Типы запросов к service-comments и service-censor
+const (
+	TypeCommentsByIdNews = "CommentsByIdNews" //Комментарии к статье
+	TypeCommentNew       = "CommentNew"       //Проверка цензурой и добавление комментария
+	TypeCommentUpdate    = "CommentUpdate"    //Проверка цензурой и изменение текста комментария
+	TypeCommentDelete    = "CommentDelete"    //Удаление комментария
+)
+
+// Комментарий к публикации
+type Comment struct {
+	Id          int    `json:"id"`
+	IdNews      int    `json:"id_news"`
+	CommentTime int64  `json:"comment_time"`
+	UserName    string `json:"user_name"`
+	Content     string `json:"content"`
+	EditedAt    int64  `json:"edited_at"` //Время последнего изменения текста, 0 - не изменялся
+	ParentId    int    `json:"parent_id"` //Комментарий, на который дан ответ, 0 - комментарий к статье
+	Depth       int    `json:"depth"`     //Уровень вложенности: 0 - комментарий к статье, 1 - ответ на него и т.д.
+}
+
+// CommentsRequest - запрос api-gateway -> service-comments, service-censor
+type CommentsRequest struct {
+	Version     int    `json:"version"` //Версия схемы сообщения
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	TypeQuery   string `json:"type_query"`
+	IdNews      int    `json:"id_news"`
+	CommentTime int64  `json:"comment_time"`
+	UserName    string `json:"user_name"`
+	Content     string `json:"content"`
+	IdComment   int    `json:"id_comment"` //Комментарий для CommentUpdate и CommentDelete
+	ParentId    int    `json:"parent_id"`  //Комментарий, на который отвечает CommentNew, 0 - комментарий к статье
+	ReplyTo     string `json:"reply_to"`   //Устарело: передается в заголовке reply-to, поле читается у отправителей предыдущих версий
+	Deadline    int64  `json:"deadline"`   //Устарело: передается в заголовке deadline
+}
+
+// CommentsReply - ответ service-comments, service-censor -> api-gateway
+type CommentsReply struct {
+	Version   int    `json:"version"` //Версия схемы сообщения
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	TypeQuery string `json:"type_query"`
+	Reply
+	IdNews   int       `json:"id_news"`
+	Comments []Comment `json:"comments"`
+}
+
+func (m *CommentsRequest) schemaVersion() *int { return &m.Version }
+func (m *CommentsReply) schemaVersion() *int   { return &m.Version }
@@ -0,0 +1,90 @@
+package contracts
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readTestdata(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	return data
+}
+
+// Сообщения SchemaV1 (без поля version, id новости как "Id") декодируются текущей схемой
+func TestUnmarshal_V1NewsRequest(t *testing.T) {
+	var msg NewsRequest
+	require.NoError(t, Unmarshal(readTestdata(t, "v1/news_request.json"), &msg))
+
+	assert.Equal(t, SchemaV1, msg.Version)
+	assert.Equal(t, "req-1", msg.ID)
+	assert.Equal(t, TypeOneNews, msg.TypeQuery)
+	assert.Equal(t, 7, msg.IdNews)
+	assert.Equal(t, "api-gateway-reply.host", msg.ReplyTo)
+	assert.Equal(t, int64(1700000003000), msg.Deadline)
+}
+
+func TestUnmarshal_V1NewsReply(t *testing.T) {
+	var msg NewsReply
+	require.NoError(t, Unmarshal(readTestdata(t, "v1/news_reply.json"), &msg))
+
+	assert.Equal(t, SchemaV1, msg.Version)
+	assert.True(t, msg.IsOK())
+	require.Len(t, msg.News, 1)
+	assert.Equal(t, 7, msg.News[0].Id)
+	assert.Equal(t, "Title", msg.News[0].Title)
+}
+
+func TestUnmarshal_V1CommentsRequest(t *testing.T) {
+	var msg CommentsRequest
+	require.NoError(t, Unmarshal(readTestdata(t, "v1/comments_request.json"), &msg))
+
+	assert.Equal(t, SchemaV1, msg.Version)
+	assert.Equal(t, TypeCommentNew, msg.TypeQuery)
+	assert.Equal(t, "gopher", msg.UserName)
+	assert.Equal(t, "nice", msg.Content)
+}
+
+func TestUnmarshal_V1CommentsReply(t *testing.T) {
+	var msg CommentsReply
+	require.NoError(t, Unmarshal(readTestdata(t, "v1/comments_reply.json"), &msg))
+
+	assert.Equal(t, SchemaV1, msg.Version)
+	require.Len(t, msg.Comments, 1)
+	assert.Equal(t, 3, msg.Comments[0].Id)
+	assert.Equal(t, 7, msg.Comments[0].IdNews)
+}
+
+func TestMarshal_SetsCurrentVersion(t *testing.T) {
+	reply := NewsReply{ID: "req", TypeQuery: TypeNews, Reply: ReplyOK(), News: []News{{Id: 1}}}
+
+	data, err := Marshal(&reply)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"version":2`)
+	assert.Contains(t, string(data), `"id":1`)
+
+	var decoded NewsReply
+	require.NoError(t, Unmarshal(data, &decoded))
+	assert.Equal(t, reply, decoded)
+}
+
+func TestUnmarshal_UnsupportedVersion(t *testing.T) {
+	var msg CommentsRequest
+	err := Unmarshal([]byte(`{"version":99,"id":"req"}`), &msg)
+	assert.ErrorIs(t, err, ErrUnsupportedVersion)
+}
+
+// Записи лога без поля version - записи SchemaV1, записи новее текущей схемы отклоняются
+func TestLogEntry_CheckVersion(t *testing.T) {
+	entry := LogEntry{RequestID: "req-1"}
+	require.NoError(t, entry.CheckVersion())
+	assert.Equal(t, SchemaV1, entry.Version)
+
+	entry.Version = SchemaVersion + 1
+	assert.ErrorIs(t, entry.CheckVersion(), ErrUnsupportedVersion)
+}
@@ -0,0 +1,28 @@
+package contracts
+
+import "time"
+
+// LogEntry - запись лога api-gateway или сервиса. Записи сохраняются в logs.json
+// каждого сервиса и публикуются в топик логов, откуда их забирает service-logs.
+// Ключ сообщения в топике логов - request_id.
+type LogEntry struct {
+	Version     int       `json:"version"` //Версия схемы сообщения
+	Timestamp   time.Time `json:"timestamp"`
+	ServiceID   string    `json:"service_id"`
+	RequestID   string    `json:"request_id"`
+	RemoteAddr  string    `json:"remote_addr"`
+	StatusCode  int       `json:"status_code"`
+	DataRequest string    `json:"data_request"`
+
+	// Поля структурированного лога
+	Level     string         `json:"level,omitempty"`      //Уровень записи
+	Topic     string         `json:"topic,omitempty"`      //Топик Kafka
+	LatencyMs float64        `json:"latency_ms,omitempty"` //Длительность обработки, мс
+	Fields    map[string]any `json:"fields,omitempty"`     //Остальные поля записи
+}
+
+// CheckVersion - проверка версии схемы записи, полученной из топика логов.
+// Записи без поля version считаются записями SchemaV1.
+func (e *LogEntry) CheckVersion() error {
+	return checkSchemaVersion(&e.Version)
+}
@@ -0,0 +1,57 @@
+package contracts
+
+// Типы запросов к service-news
+const (
+	TypeNews    = "News"    //Список новостей с учетом рубрики, фильтра и страницы
+	TypeOneNews = "OneNews" //Новость по ID
+)
+
+// Публикация, получаемая из RSS.
+type News struct {
+	Id         int    `json:"id"`
+	Title      string `json:"title"`
+	Content    string `json:"content"`
+	PublicTime int64  `json:"public_time"`
+	ImageLink  string `json:"image_link"`
+	Rubric     string `json:"rubric"`
+	Link       string `json:"link"`
+	LinkTitle  string `json:"link_title"`
+}
+
+// Пагинация.
+type Paginate struct {
+	PageCurr       int `json:"page_curr"`        //Номер текущей страницы
+	PageCount      int `json:"page_count"`       //Количество страниц
+	PageCountList  int `json:"page_count_list"`  //Количество новостей на странице
+	PageCountTotal int `json:"page_count_total"` //Количество всего новостей
+}
+
+// NewsRequest - запрос api-gateway -> service-news
+type NewsRequest struct {
+	Version   int    `json:"version"` //Версия схемы сообщения
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	TypeQuery string `json:"type_query"`
+	Rubric    string `json:"rubric"`
+	CountNews int    `json:"count_news"`
+	Filter    string `json:"filter"`
+	Page      int    `json:"page"`
+	IdNews    int    `json:"id_news"`
+	ReplyTo   string `json:"reply_to"` //Устарело: передается в заголовке reply-to, поле читается у отправителей предыдущих версий
+	Deadline  int64  `json:"deadline"` //Устарело: передается в заголовке deadline
+}
+
+// NewsReply - ответ service-news -> api-gateway
+type NewsReply struct {
+	Version   int    `json:"version"` //Версия схемы сообщения
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	TypeQuery string `json:"type_query"`
+	Reply
+	News     []News   `json:"news"`
+	Paginate Paginate `json:"paginate"`
+	IdNews   int      `json:"id_news"`
+}
+
+func (m *NewsRequest) schemaVersion() *int { return &m.Version }
+func (m *NewsReply) schemaVersion() *int   { return &m.Version }
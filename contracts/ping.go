@@ -0,0 +1,24 @@
+package contracts
+
+// TypePing - проверка состояния сервиса, на нее отвечает каждый сервис на своем топике запросов.
+// Запрос Ping содержит только общие поля (Header).
+const TypePing = "Ping"
+
+// Ключи Details ответа на Ping
+const (
+	PingPostgres       = "postgres"         //Состояние пула соединений PostgreSQL: ok или текст ошибки
+	PingRSSLastSuccess = "rss_last_success" //Время (RFC 3339) последней успешной загрузки RSS, только service-news
+)
+
+// PingReply - ответ сервиса на Ping. Сервисы отвечают своим типом ответа
+// (NewsReply, CommentsReply), у которых общие поля и конверт ответа совпадают,
+// поэтому api-gateway декодирует ответ любого сервиса как PingReply.
+type PingReply struct {
+	Version   int    `json:"version"` //Версия схемы сообщения
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	TypeQuery string `json:"type_query"`
+	Reply
+}
+
+func (m *PingReply) schemaVersion() *int { return &m.Version }
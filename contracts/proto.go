@@ -0,0 +1,459 @@
+package contracts
+
+import (
+	"errors"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Кодирование сообщений в Protobuf вручную через protowire по схеме messages.proto:
+// номера полей совпадают со схемой, поэтому сообщения совместимы с кодом,
+// сгенерированным protoc. Поля 1-4 (version, id, name, type_query) общие для всех
+// сообщений, поэтому любое сообщение можно декодировать как Header. Соответствие
+// схеме проверяет TestProtobuf_MatchesSchema через dynamicpb.
+
+// errProtoTruncated - некорректное или обрезанное сообщение
+var errProtoTruncated = errors.New("truncated or malformed message")
+
+// protoWriter - запись полей сообщения, нулевые значения не записываются
+type protoWriter struct {
+	b []byte
+}
+
+func (w *protoWriter) int(num protowire.Number, v int64) {
+	if v == 0 {
+		return
+	}
+	w.b = protowire.AppendTag(w.b, num, protowire.VarintType)
+	w.b = protowire.AppendVarint(w.b, uint64(v))
+}
+
+func (w *protoWriter) string(num protowire.Number, v string) {
+	if v == "" {
+		return
+	}
+	w.b = protowire.AppendTag(w.b, num, protowire.BytesType)
+	w.b = protowire.AppendString(w.b, v)
+}
+
+// message - вложенное сообщение, записывается и пустым (элемент repeated-поля)
+func (w *protoWriter) message(num protowire.Number, v []byte) {
+	w.b = protowire.AppendTag(w.b, num, protowire.BytesType)
+	w.b = protowire.AppendBytes(w.b, v)
+}
+
+// details - map<string, string> как repeated-поле записей key = 1, value = 2
+func (w *protoWriter) details(num protowire.Number, details map[string]string) {
+	for key, value := range details {
+		var entry protoWriter
+		entry.string(1, key)
+		entry.string(2, value)
+		w.message(num, entry.b)
+	}
+}
+
+// protoField - поле сообщения: varint для чисел, bytes для строк и вложенных сообщений
+type protoField struct {
+	num    protowire.Number
+	varint uint64
+	bytes  []byte
+}
+
+func (f protoField) int() int {
+	return int(int64(f.varint))
+}
+
+func (f protoField) int64() int64 {
+	return int64(f.varint)
+}
+
+func (f protoField) string() string {
+	return string(f.bytes)
+}
+
+// readProto - разбор полей сообщения; поля неизвестных типов пропускаются
+func readProto(data []byte, field func(f protoField) error) error {
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return errProtoTruncated
+		}
+		data = data[n:]
+
+		f := protoField{num: num}
+		switch typ {
+		case protowire.VarintType:
+			f.varint, n = protowire.ConsumeVarint(data)
+		case protowire.BytesType:
+			f.bytes, n = protowire.ConsumeBytes(data)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, data)
+			if n < 0 {
+				return errProtoTruncated
+			}
+			data = data[n:]
+			continue
+		}
+		if n < 0 {
+			return errProtoTruncated
+		}
+		data = data[n:]
+
+		if err := field(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readDetails - запись map<string, string> в details
+func readDetails(data []byte, details *map[string]string) error {
+	var key, value string
+	err := readProto(data, func(f protoField) error {
+		switch f.num {
+		case 1:
+			key = f.string()
+		case 2:
+			value = f.string()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if *details == nil {
+		*details = make(map[string]string)
+	}
+	(*details)[key] = value
+	return nil
+}
+
+// header - запись общих полей 1-4
+func (w *protoWriter) header(version int, id, name, typeQuery string) {
+	w.int(1, int64(version))
+	w.string(2, id)
+	w.string(3, name)
+	w.string(4, typeQuery)
+}
+
+// readHeader - разбор общих полей 1-4, возвращает false для остальных полей
+func readHeader(f protoField, version *int, id, name, typeQuery *string) bool {
+	switch f.num {
+	case 1:
+		*version = f.int()
+	case 2:
+		*id = f.string()
+	case 3:
+		*name = f.string()
+	case 4:
+		*typeQuery = f.string()
+	default:
+		return false
+	}
+	return true
+}
+
+func (m *Header) marshalProto() []byte {
+	var w protoWriter
+	w.header(m.Version, m.ID, m.Name, m.TypeQuery)
+	return w.b
+}
+
+func (m *Header) unmarshalProto(data []byte) error {
+	*m = Header{}
+	return readProto(data, func(f protoField) error {
+		readHeader(f, &m.Version, &m.ID, &m.Name, &m.TypeQuery)
+		return nil
+	})
+}
+
+func (m *News) marshalProto() []byte {
+	var w protoWriter
+	w.int(1, int64(m.Id))
+	w.string(2, m.Title)
+	w.string(3, m.Content)
+	w.int(4, m.PublicTime)
+	w.string(5, m.ImageLink)
+	w.string(6, m.Rubric)
+	w.string(7, m.Link)
+	w.string(8, m.LinkTitle)
+	return w.b
+}
+
+func (m *News) unmarshalProto(data []byte) error {
+	*m = News{}
+	return readProto(data, func(f protoField) error {
+		switch f.num {
+		case 1:
+			m.Id = f.int()
+		case 2:
+			m.Title = f.string()
+		case 3:
+			m.Content = f.string()
+		case 4:
+			m.PublicTime = f.int64()
+		case 5:
+			m.ImageLink = f.string()
+		case 6:
+			m.Rubric = f.string()
+		case 7:
+			m.Link = f.string()
+		case 8:
+			m.LinkTitle = f.string()
+		}
+		return nil
+	})
+}
+
+func (m *Paginate) marshalProto() []byte {
+	var w protoWriter
+	w.int(1, int64(m.PageCurr))
+	w.int(2, int64(m.PageCount))
+	w.int(3, int64(m.PageCountList))
+	w.int(4, int64(m.PageCountTotal))
+	return w.b
+}
+
+func (m *Paginate) unmarshalProto(data []byte) error {
+	*m = Paginate{}
+	return readProto(data, func(f protoField) error {
+		switch f.num {
+		case 1:
+			m.PageCurr = f.int()
+		case 2:
+			m.PageCount = f.int()
+		case 3:
+			m.PageCountList = f.int()
+		case 4:
+			m.PageCountTotal = f.int()
+		}
+		return nil
+	})
+}
+
+func (m *NewsRequest) marshalProto() []byte {
+	var w protoWriter
+	w.header(m.Version, m.ID, m.Name, m.TypeQuery)
+	w.string(5, m.Rubric)
+	w.int(6, int64(m.CountNews))
+	w.string(7, m.Filter)
+	w.int(8, int64(m.Page))
+	w.int(9, int64(m.IdNews))
+	w.string(10, m.ReplyTo)
+	w.int(11, m.Deadline)
+	return w.b
+}
+
+func (m *NewsRequest) unmarshalProto(data []byte) error {
+	*m = NewsRequest{}
+	return readProto(data, func(f protoField) error {
+		if readHeader(f, &m.Version, &m.ID, &m.Name, &m.TypeQuery) {
+			return nil
+		}
+		switch f.num {
+		case 5:
+			m.Rubric = f.string()
+		case 6:
+			m.CountNews = f.int()
+		case 7:
+			m.Filter = f.string()
+		case 8:
+			m.Page = f.int()
+		case 9:
+			m.IdNews = f.int()
+		case 10:
+			m.ReplyTo = f.string()
+		case 11:
+			m.Deadline = f.int64()
+		}
+		return nil
+	})
+}
+
+func (m *NewsReply) marshalProto() []byte {
+	var w protoWriter
+	w.header(m.Version, m.ID, m.Name, m.TypeQuery)
+	w.string(5, m.Status)
+	w.string(6, m.Message)
+	w.details(7, m.Details)
+	for i := range m.News {
+		w.message(8, m.News[i].marshalProto())
+	}
+	w.message(9, m.Paginate.marshalProto())
+	w.int(10, int64(m.IdNews))
+	return w.b
+}
+
+func (m *NewsReply) unmarshalProto(data []byte) error {
+	*m = NewsReply{}
+	return readProto(data, func(f protoField) error {
+		if readHeader(f, &m.Version, &m.ID, &m.Name, &m.TypeQuery) {
+			return nil
+		}
+		switch f.num {
+		case 5:
+			m.Status = f.string()
+		case 6:
+			m.Message = f.string()
+		case 7:
+			return readDetails(f.bytes, &m.Details)
+		case 8:
+			var news News
+			if err := news.unmarshalProto(f.bytes); err != nil {
+				return err
+			}
+			m.News = append(m.News, news)
+		case 9:
+			return m.Paginate.unmarshalProto(f.bytes)
+		case 10:
+			m.IdNews = f.int()
+		}
+		return nil
+	})
+}
+
+func (m *Comment) marshalProto() []byte {
+	var w protoWriter
+	w.int(1, int64(m.Id))
+	w.int(2, int64(m.IdNews))
+	w.int(3, m.CommentTime)
+	w.string(4, m.UserName)
+	w.string(5, m.Content)
+	w.int(6, m.EditedAt)
+	w.int(7, int64(m.ParentId))
+	w.int(8, int64(m.Depth))
+	return w.b
+}
+
+func (m *Comment) unmarshalProto(data []byte) error {
+	*m = Comment{}
+	return readProto(data, func(f protoField) error {
+		switch f.num {
+		case 1:
+			m.Id = f.int()
+		case 2:
+			m.IdNews = f.int()
+		case 3:
+			m.CommentTime = f.int64()
+		case 4:
+			m.UserName = f.string()
+		case 5:
+			m.Content = f.string()
+		case 6:
+			m.EditedAt = f.int64()
+		case 7:
+			m.ParentId = f.int()
+		case 8:
+			m.Depth = f.int()
+		}
+		return nil
+	})
+}
+
+func (m *CommentsRequest) marshalProto() []byte {
+	var w protoWriter
+	w.header(m.Version, m.ID, m.Name, m.TypeQuery)
+	w.int(5, int64(m.IdNews))
+	w.int(6, m.CommentTime)
+	w.string(7, m.UserName)
+	w.string(8, m.Content)
+	w.string(9, m.ReplyTo)
+	w.int(10, m.Deadline)
+	w.int(11, int64(m.IdComment))
+	w.int(12, int64(m.ParentId))
+	return w.b
+}
+
+func (m *CommentsRequest) unmarshalProto(data []byte) error {
+	*m = CommentsRequest{}
+	return readProto(data, func(f protoField) error {
+		if readHeader(f, &m.Version, &m.ID, &m.Name, &m.TypeQuery) {
+			return nil
+		}
+		switch f.num {
+		case 5:
+			m.IdNews = f.int()
+		case 6:
+			m.CommentTime = f.int64()
+		case 7:
+			m.UserName = f.string()
+		case 8:
+			m.Content = f.string()
+		case 9:
+			m.ReplyTo = f.string()
+		case 10:
+			m.Deadline = f.int64()
+		case 11:
+			m.IdComment = f.int()
+		case 12:
+			m.ParentId = f.int()
+		}
+		return nil
+	})
+}
+
+func (m *CommentsReply) marshalProto() []byte {
+	var w protoWriter
+	w.header(m.Version, m.ID, m.Name, m.TypeQuery)
+	w.string(5, m.Status)
+	w.string(6, m.Message)
+	w.details(7, m.Details)
+	w.int(8, int64(m.IdNews))
+	for i := range m.Comments {
+		w.message(9, m.Comments[i].marshalProto())
+	}
+	return w.b
+}
+
+func (m *CommentsReply) unmarshalProto(data []byte) error {
+	*m = CommentsReply{}
+	return readProto(data, func(f protoField) error {
+		if readHeader(f, &m.Version, &m.ID, &m.Name, &m.TypeQuery) {
+			return nil
+		}
+		switch f.num {
+		case 5:
+			m.Status = f.string()
+		case 6:
+			m.Message = f.string()
+		case 7:
+			return readDetails(f.bytes, &m.Details)
+		case 8:
+			m.IdNews = f.int()
+		case 9:
+			var comment Comment
+			if err := comment.unmarshalProto(f.bytes); err != nil {
+				return err
+			}
+			m.Comments = append(m.Comments, comment)
+		}
+		return nil
+	})
+}
+
+func (m *PingReply) marshalProto() []byte {
+	var w protoWriter
+	w.header(m.Version, m.ID, m.Name, m.TypeQuery)
+	w.string(5, m.Status)
+	w.string(6, m.Message)
+	w.details(7, m.Details)
+	return w.b
+}
+
+func (m *PingReply) unmarshalProto(data []byte) error {
+	*m = PingReply{}
+	return readProto(data, func(f protoField) error {
+		if readHeader(f, &m.Version, &m.ID, &m.Name, &m.TypeQuery) {
+			return nil
+		}
+		switch f.num {
+		case 5:
+			m.Status = f.string()
+		case 6:
+			m.Message = f.string()
+		case 7:
+			return readDetails(f.bytes, &m.Details)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,165 @@
+package contracts
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// Поле messages.proto: [repeated] тип имя = номер; или map<string, string> имя = номер;
+var protoFieldRe = regexp.MustCompile(`^(repeated\s+)?(map<\s*string\s*,\s*string\s*>|\w+)\s+(\w+)\s*=\s*(\d+)$`)
+
+// schemaFile - описание messages.proto для protodesc. protoc в сборке не используется,
+// поэтому схема разбирается здесь: она содержит только сообщения с полями int64, string,
+// вложенными сообщениями, repeated-полями и map<string, string>.
+func schemaFile(t *testing.T) protoreflect.FileDescriptor {
+	t.Helper()
+	data, err := os.ReadFile("messages.proto")
+	require.NoError(t, err)
+
+	// Комментарии удаляются, инструкции разделяются по ; и фигурным скобкам
+	src := regexp.MustCompile(`//[^\n]*`).ReplaceAllString(string(data), "")
+	src = strings.NewReplacer("{", ";{;", "}", ";};").Replace(src)
+
+	file := &descriptorpb.FileDescriptorProto{Name: proto.String("messages.proto"), Syntax: proto.String("proto3")}
+	var msg *descriptorpb.DescriptorProto
+	for _, stmt := range strings.Split(src, ";") {
+		stmt = strings.Join(strings.Fields(stmt), " ")
+		switch {
+		case stmt == "", stmt == "{", strings.HasPrefix(stmt, "syntax"):
+		case strings.HasPrefix(stmt, "package "):
+			file.Package = proto.String(strings.TrimPrefix(stmt, "package "))
+		case strings.HasPrefix(stmt, "message "):
+			msg = &descriptorpb.DescriptorProto{Name: proto.String(strings.TrimPrefix(stmt, "message "))}
+			file.MessageType = append(file.MessageType, msg)
+		case stmt == "}":
+			msg = nil
+		default:
+			m := protoFieldRe.FindStringSubmatch(stmt)
+			require.NotNil(t, m, "unsupported statement: %q", stmt)
+			require.NotNil(t, msg, "field outside of message: %q", stmt)
+			num, _ := strconv.Atoi(m[4])
+			msg.Field = append(msg.Field, schemaField(file.GetPackage(), msg, m[1] != "", m[2], m[3], int32(num)))
+		}
+	}
+
+	fd, err := protodesc.NewFile(file, nil)
+	require.NoError(t, err)
+	return fd
+}
+
+// schemaField - поле сообщения msg; для map добавляется вложенное сообщение записи
+func schemaField(pkg string, msg *descriptorpb.DescriptorProto, repeated bool, typ, name string, num int32) *descriptorpb.FieldDescriptorProto {
+	field := &descriptorpb.FieldDescriptorProto{
+		Name:     proto.String(name),
+		Number:   proto.Int32(num),
+		Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		JsonName: proto.String(name),
+	}
+	if repeated {
+		field.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+	}
+
+	switch {
+	case typ == "int64":
+		field.Type = descriptorpb.FieldDescriptorProto_TYPE_INT64.Enum()
+	case typ == "string":
+		field.Type = descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum()
+	case strings.HasPrefix(typ, "map<"):
+		entry := strings.ToUpper(name[:1]) + name[1:] + "Entry"
+		str := descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum()
+		optional := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()
+		msg.NestedType = append(msg.NestedType, &descriptorpb.DescriptorProto{
+			Name: proto.String(entry),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				{Name: proto.String("key"), Number: proto.Int32(1), Label: optional, Type: str, JsonName: proto.String("key")},
+				{Name: proto.String("value"), Number: proto.Int32(2), Label: optional, Type: str, JsonName: proto.String("value")},
+			},
+			Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+		})
+		field.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+		field.Type = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum()
+		field.TypeName = proto.String(fmt.Sprintf(".%s.%s.%s", pkg, msg.GetName(), entry))
+	default:
+		field.Type = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum()
+		field.TypeName = proto.String(fmt.Sprintf(".%s.%s", pkg, typ))
+	}
+	return field
+}
+
+// Кодирование каждого сообщения совпадает со схемой messages.proto: сообщение, закодированное
+// вручную, читается по схеме без неизвестных полей и с теми же значениями, что и в JSON,
+// а сообщение, закодированное по схеме, декодируется вручную без потерь
+func TestProtobuf_MatchesSchema(t *testing.T) {
+	schema := schemaFile(t)
+	details := map[string]string{"id_news": "7", PingPostgres: "ok"}
+
+	messages := []struct {
+		name    string
+		msg     Message
+		decoded func() Message
+	}{
+		{"Header", &Header{ID: "req", Name: "api-gateway", TypeQuery: TypePing},
+			func() Message { return &Header{} }},
+		{"NewsRequest", &NewsRequest{ID: "req", Name: "api-gateway", TypeQuery: TypeNews, Rubric: "sport", CountNews: 10, Filter: "cup", Page: 2, IdNews: 7, ReplyTo: "reply", Deadline: 1700000003000},
+			func() Message { return &NewsRequest{} }},
+		{"NewsReply", &NewsReply{ID: "req", Name: "service-news", TypeQuery: TypeNews, Reply: ReplyFail(StatusNotFound, "news not found", details),
+			News: []News{
+				{Id: 7, Title: "Title", Content: "Content", PublicTime: 1700000000, ImageLink: "https://example.com/i.png", Rubric: "sport", Link: "https://example.com", LinkTitle: "Example"},
+				{},
+			},
+			Paginate: Paginate{PageCurr: 1, PageCount: 3, PageCountList: 10, PageCountTotal: 25}, IdNews: 7},
+			func() Message { return &NewsReply{} }},
+		{"CommentsRequest", &CommentsRequest{ID: "req", Name: "api-gateway", TypeQuery: TypeCommentNew, IdNews: 7, CommentTime: 1700000000, UserName: "gopher", Content: "привет", ReplyTo: "reply", Deadline: -1, IdComment: 3, ParentId: 2},
+			func() Message { return &CommentsRequest{} }},
+		{"CommentsReply", &CommentsReply{ID: "req", Name: "service-comments", TypeQuery: TypeCommentsByIdNews, Reply: ReplyFail(StatusInvalid, "invalid", details), IdNews: 7,
+			Comments: []Comment{{Id: 1, IdNews: 7, CommentTime: 1700000000, UserName: "gopher", Content: "nice", EditedAt: 1700000005, ParentId: 2, Depth: 1}, {}}},
+			func() Message { return &CommentsReply{} }},
+		{"PingReply", &PingReply{ID: "req", Name: "service-censor", TypeQuery: TypePing, Reply: ReplyFail(StatusInternal, "postgres unavailable", details)},
+			func() Message { return &PingReply{} }},
+		{"PingReply/empty", &PingReply{Reply: ReplyOK()},
+			func() Message { return &PingReply{} }},
+	}
+
+	for _, tt := range messages {
+		t.Run(tt.name, func(t *testing.T) {
+			desc := schema.Messages().ByName(protoreflect.Name(strings.Split(tt.name, "/")[0]))
+			require.NotNil(t, desc, "message missing in messages.proto")
+
+			data, err := Protobuf.Marshal(tt.msg)
+			require.NoError(t, err)
+
+			// Ожидаемые значения - из JSON того же сообщения, имена полей JSON совпадают со схемой
+			jsonData, err := json.Marshal(tt.msg)
+			require.NoError(t, err)
+			want := dynamicpb.NewMessage(desc)
+			require.NoError(t, protojson.Unmarshal(jsonData, want))
+
+			// Кодирование вручную: номера и типы полей, без записи нулевых значений
+			got := dynamicpb.NewMessage(desc)
+			require.NoError(t, proto.Unmarshal(data, got))
+			assert.True(t, proto.Equal(want, got), "want %v\ngot  %v", want, got)
+			assert.Equal(t, proto.Size(want), len(data))
+
+			// Декодирование вручную сообщения, закодированного по схеме
+			schemaData, err := proto.MarshalOptions{Deterministic: true}.Marshal(want)
+			require.NoError(t, err)
+			decoded := tt.decoded()
+			require.NoError(t, Protobuf.Unmarshal(schemaData, decoded))
+			assert.Equal(t, tt.msg, decoded)
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package contracts
+
+// Коды статуса ответа сервиса
+const (
+	StatusOK        = "ok"        //Запрос выполнен
+	StatusNotFound  = "not_found" //Запрошенные данные не найдены
+	StatusInvalid   = "invalid"   //Некорректные параметры запроса
+	StatusForbidden = "forbidden" //Запрос не разрешен отправителю, например, изменение чужого комментария
+	StatusRejected  = "rejected"  //Запрос отклонен по бизнес-правилам, например, цензурой
+	StatusInternal  = "internal"  //Внутренняя ошибка сервиса
+	StatusTimeout   = "timeout"   //Ответ не получен до истечения дедлайна запроса
+)
+
+// Reply - конверт ответа сервиса: код статуса, сообщение и необязательные детали.
+// Встраивается в сообщения ответов, поля находятся на верхнем уровне JSON.
+type Reply struct {
+	Status  string            `json:"status"`
+	Message string            `json:"message,omitempty"`
+	Details map[string]string `json:"details,omitempty"`
+}
+
+// ReplyOK - успешный ответ
+func ReplyOK() Reply {
+	return Reply{Status: StatusOK}
+}
+
+// ReplyFail - ответ с ошибкой
+func ReplyFail(status, message string, details map[string]string) Reply {
+	return Reply{Status: status, Message: message, Details: details}
+}
+
+// IsOK - проверка успешности ответа
+func (r Reply) IsOK() bool {
+	return r.Status == StatusOK
+}
@@ -0,0 +1,24 @@
+package contracts
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReply_EmbeddedJSON(t *testing.T) {
+	message := struct {
+		ID string `json:"id"`
+		Reply
+	}{ID: "req", Reply: ReplyOK()}
+
+	data, err := json.Marshal(message)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"id":"req","status":"ok"}`, string(data))
+
+	message.Reply = ReplyFail(StatusNotFound, "news not found", map[string]string{"id_news": "7"})
+	data, err = json.Marshal(message)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"id":"req","status":"not_found","message":"news not found","details":{"id_news":"7"}}`, string(data))
+}
@@ -0,0 +1,66 @@
+// Package contracts - контракты сообщений, которыми api-gateway и сервисы
+// обмениваются через Kafka. Каждое сообщение содержит версию схемы.
+package contracts
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Версии схемы сообщений
+const (
+	// SchemaV1 - сообщения без поля version; id новости передавался как "Id"
+	SchemaV1 = 1
+	// SchemaV2 - поле version во всех сообщениях, id новости передается как "id"
+	SchemaV2 = 2
+
+	// SchemaVersion - текущая версия схемы, с которой сообщения отправляются
+	SchemaVersion = SchemaV2
+)
+
+// ErrUnsupportedVersion - версия схемы сообщения новее, чем известна получателю
+var ErrUnsupportedVersion = errors.New("unsupported schema version")
+
+// Header - общие поля всех сообщений, по ним api-gateway маршрутизирует ответы
+type Header struct {
+	Version   int    `json:"version"` //Версия схемы сообщения
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	TypeQuery string `json:"type_query"`
+}
+
+func (m *Header) schemaVersion() *int { return &m.Version }
+
+// Message - сообщение с версией схемы
+type Message interface {
+	schemaVersion() *int
+	marshalProto() []byte
+	unmarshalProto(data []byte) error
+}
+
+// Marshal - кодирование сообщения в JSON с текущей версией схемы
+func Marshal(msg Message) ([]byte, error) {
+	return JSON.Marshal(msg)
+}
+
+// Unmarshal - декодирование JSON сообщения любой поддерживаемой версии схемы.
+// Сообщения без поля version считаются сообщениями SchemaV1.
+func Unmarshal(data []byte, msg Message) error {
+	return JSON.Unmarshal(data, msg)
+}
+
+// checkVersion - проверка версии схемы декодированного сообщения
+func checkVersion(msg Message) error {
+	return checkSchemaVersion(msg.schemaVersion())
+}
+
+// checkSchemaVersion - проверка версии схемы, 0 - сообщение без поля version (SchemaV1)
+func checkSchemaVersion(version *int) error {
+	if *version == 0 {
+		*version = SchemaV1
+	}
+	if *version > SchemaVersion {
+		return fmt.Errorf("%w: %v", ErrUnsupportedVersion, *version)
+	}
+	return nil
+}
@@ -0,0 +1,216 @@
+// Package e2e - сквозные тесты: api-gateway, service-news, service-comments и
+// service-censor связываются в одном процессе через брокер сообщений в памяти.
+package e2e
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/IBM/sarama"
+
+	"news-kafka/e2e/pkg/memory"
+
+	gatewayapi "news-kafka/api-gateway/pkg/api"
+	gatewaykafka "news-kafka/api-gateway/pkg/kafka"
+
+	"news-kafka/service-censor/pkg/censor"
+	censorhandler "news-kafka/service-censor/pkg/handler"
+	censorkafka "news-kafka/service-censor/pkg/kafka"
+
+	commentshandler "news-kafka/service-comments/pkg/handler"
+	commentskafka "news-kafka/service-comments/pkg/kafka"
+	commentsmemdb "news-kafka/service-comments/pkg/storage/memdb"
+
+	newshandler "news-kafka/service-news/pkg/handler"
+	newskafka "news-kafka/service-news/pkg/kafka"
+	"news-kafka/service-news/pkg/rss"
+	newsmemdb "news-kafka/service-news/pkg/storage/memdb"
+)
+
+// Stack - запущенные в одном процессе api-gateway и сервисы
+type Stack struct {
+	Broker   *memory.Broker
+	News     *newsmemdb.Store     //Хранилище service-news
+	Comments *commentsmemdb.Store //Хранилище service-comments
+	Gateway  *gatewayapi.API      //API api-gateway
+	Server   *httptest.Server     //HTTP-сервер api-gateway
+
+	mu     sync.Mutex
+	errors []error
+}
+
+// Options - настройки Stack
+type Options struct {
+	OffensiveWords []string //Слова, запрещенные цензурой
+	ContentType    string   //Формат кодирования запросов api-gateway, по умолчанию из configKafka.json
+}
+
+// NewStack - запуск api-gateway и сервисов с конфигурацией из их каталогов.
+// Остановка выполняется в t.Cleanup.
+func NewStack(t testing.TB, opts Options) *Stack {
+	t.Helper()
+
+	s := &Stack{
+		Broker:   memory.NewBroker(),
+		News:     newsmemdb.New(),
+		Comments: commentsmemdb.New(),
+	}
+
+	// Записи лога уровня warn и выше сохраняются вместо записи в logs.json
+	logs := slog.New(&errorHandler{stack: s})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	t.Cleanup(func() {
+		if s.Server != nil {
+			s.Server.Close()
+		}
+		cancel()
+		s.Broker.Close()
+		wg.Wait()
+	})
+
+	// service-news
+	newsConfig, err := newskafka.ReadConfig("../service-news/configKafka.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	newsProducer := s.Broker.Producer()
+	newsPipeline := newskafka.Pipeline{
+		Policy:      newsConfig.Retry,
+		DeadLetters: newskafka.NewDeadLetterQueue(nil, newsConfig.TopicDeadLetter, newsProducer),
+		OnFailure:   newshandler.ReplyFailure(newsProducer, newsConfig, logs),
+		Logs:        logs,
+	}
+	var newsConsumer newskafka.ConsumerGroupInterface = s.Broker.GroupConsumer(newsConfig.ConsumerGroup, logs)
+	s.consume(ctx, &wg, func(ctx context.Context) error {
+		return newsConsumer.Consume(ctx, []string{newsConfig.TopicResponse}, newsPipeline.Handler(newshandler.New(s.News, &rss.Status{}, newsProducer, newsConfig, logs)))
+	})
+
+	// service-comments
+	commentsConfig, err := commentskafka.ReadConfig("../service-comments/configKafka.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	commentsProducer := s.Broker.Producer()
+	commentsPipeline := commentskafka.Pipeline{
+		Policy:      commentsConfig.Retry,
+		DeadLetters: commentskafka.NewDeadLetterQueue(nil, commentsConfig.TopicDeadLetter, commentsProducer),
+		OnFailure:   commentshandler.ReplyFailure(commentsProducer, commentsConfig, logs),
+		Logs:        logs,
+	}
+	var commentsConsumer commentskafka.ConsumerGroupInterface = s.Broker.GroupConsumer(commentsConfig.ConsumerGroup, logs)
+	s.consume(ctx, &wg, func(ctx context.Context) error {
+		return commentsConsumer.Consume(ctx, []string{commentsConfig.TopicResponse}, commentsPipeline.Handler(commentshandler.New(s.Comments, commentsProducer, commentsConfig, logs)))
+	})
+
+	// service-censor
+	censorConfig, err := censorkafka.ReadConfig("../service-censor/configKafka.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	censorProducer := s.Broker.Producer()
+	censorPipeline := censorkafka.Pipeline{
+		Policy:      censorConfig.Retry,
+		DeadLetters: censorkafka.NewDeadLetterQueue(nil, censorConfig.TopicDeadLetter, censorProducer),
+		OnFailure:   censorhandler.ReplyFailure(censorProducer, censorConfig, logs),
+		Logs:        logs,
+	}
+	var censorConsumer censorkafka.ConsumerGroupInterface = s.Broker.GroupConsumer(censorConfig.ConsumerGroup, logs)
+	s.consume(ctx, &wg, func(ctx context.Context) error {
+		return censorConsumer.Consume(ctx, []string{censorConfig.TopicResponse}, censorPipeline.Handler(censorhandler.New(censor.NewCensorFromWords(opts.OffensiveWords), censorProducer, censorConfig, logs)))
+	})
+
+	// api-gateway
+	gatewayConfig, err := gatewaykafka.ReadConfig("../api-gateway/configKafka.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	apiConfig, err := gatewayapi.ReadConfig("../api-gateway/configAPI.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.ContentType != "" {
+		gatewayConfig.ContentType = opts.ContentType
+	}
+	replyTopic := gatewayConfig.ReplyTopic("e2e")
+
+	var gatewayConsumer gatewaykafka.ConsumerInterface = s.Broker.Consumer()
+	responseCh, err := gatewayConsumer.Consume(replyTopic, 0, sarama.OffsetNewest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dispatcher := gatewaykafka.NewDispatcher(logs)
+	s.consume(ctx, &wg, func(ctx context.Context) error {
+		dispatcher.Listen(ctx, responseCh)
+		return nil
+	})
+
+	var gatewayProducer gatewaykafka.ProducerInterface = s.Broker.Producer()
+	s.Gateway = gatewayapi.New(gatewayProducer, gatewayConsumer, gatewayConfig, apiConfig, dispatcher, replyTopic, logs)
+	// Брокер в памяти всегда доступен. Ping сервисов не запускается, чтобы не добавлять
+	// сообщения в топики, которые проверяют тесты; его запускает тест готовности.
+	s.Gateway.SetBrokerCheck(func() error { return nil })
+	s.Server = httptest.NewServer(s.Gateway.Handler())
+
+	return s
+}
+
+// Errors - ошибки и предупреждения, которые api-gateway и сервисы записали в лог
+func (s *Stack) Errors() []error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]error(nil), s.errors...)
+}
+
+// consume - запуск чтения сообщений в отдельной горутине до остановки Stack
+func (s *Stack) consume(ctx context.Context, wg *sync.WaitGroup, run func(ctx context.Context) error) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := run(ctx); err != nil {
+			s.mu.Lock()
+			s.errors = append(s.errors, err)
+			s.mu.Unlock()
+		}
+	}()
+}
+
+// errorHandler - slog.Handler, сохраняющий записи уровня warn и выше в Stack.errors
+type errorHandler struct {
+	stack *Stack
+	attrs []slog.Attr
+}
+
+func (h *errorHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= slog.LevelWarn
+}
+
+func (h *errorHandler) Handle(_ context.Context, r slog.Record) error {
+	text := r.Message
+	add := func(a slog.Attr) bool {
+		text += " " + a.String()
+		return true
+	}
+	for _, a := range h.attrs {
+		add(a)
+	}
+	r.Attrs(add)
+
+	h.stack.mu.Lock()
+	h.stack.errors = append(h.stack.errors, errors.New(text))
+	h.stack.mu.Unlock()
+	return nil
+}
+
+func (h *errorHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &errorHandler{stack: h.stack, attrs: append(append([]slog.Attr(nil), h.attrs...), attrs...)}
+}
+
+func (h *errorHandler) WithGroup(string) slog.Handler {
+	return h
+}
@@ -0,0 +1,538 @@
+package e2e
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	gatewayapi "news-kafka/api-gateway/pkg/api"
+	"news-kafka/api-gateway/pkg/kafka"
+	"news-kafka/contracts"
+	commentskafka "news-kafka/service-comments/pkg/kafka"
+	newsstorage "news-kafka/service-news/pkg/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// Ответ api-gateway на /newsDetailed
+type newsDetailed struct {
+	News     []newsstorage.News `json:"news"`
+	Comments []struct {
+		IdNews   int    `json:"id_news"`
+		UserName string `json:"user_name"`
+		Content  string `json:"content"`
+	} `json:"comments"`
+}
+
+func addNews(t *testing.T, s *Stack, news ...newsstorage.News) {
+	t.Helper()
+	require.NoError(t, s.News.AddNew(context.Background(), news))
+}
+
+func postComment(t *testing.T, s *Stack, idNews, body string) *http.Response {
+	t.Helper()
+	resp, err := http.Post(s.Server.URL+"/comments?id_news="+idNews, "application/json", strings.NewReader(body))
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+// decodeProblem - ответ api-gateway с ошибкой в формате application/problem+json
+func decodeProblem(t *testing.T, resp *http.Response) gatewayapi.Problem {
+	t.Helper()
+	require.Equal(t, gatewayapi.ProblemContentType, resp.Header.Get("Content-Type"))
+	var problem gatewayapi.Problem
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&problem))
+	assert.Equal(t, resp.StatusCode, problem.Status)
+	return problem
+}
+
+func get(t *testing.T, s *Stack, path string) *http.Response {
+	t.Helper()
+	resp, err := http.Get(s.Server.URL + path)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+// send - запрос к api-gateway с произвольным методом
+func send(t *testing.T, s *Stack, method, path, body string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, s.Server.URL+path, strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+// Комментарий проходит цензуру, сохраняется и возвращается вместе со статьей
+func TestAddCommentThroughCensor(t *testing.T) {
+	s := NewStack(t, Options{OffensiveWords: []string{"bad"}})
+	addNews(t, s, newsstorage.News{Title: "Go 1.22", Rubric: "Technology", Link: "https://example.com/go"})
+
+	resp := postComment(t, s, "1", `{"comment_time":1,"user_name":"gopher","content":"nice release"}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = get(t, s, "/newsDetailed?id_news=1")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var detailed newsDetailed
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&detailed))
+	require.Len(t, detailed.News, 1)
+	assert.Equal(t, "Go 1.22", detailed.News[0].Title)
+	require.Len(t, detailed.Comments, 1)
+	assert.Equal(t, "gopher", detailed.Comments[0].UserName)
+	assert.Equal(t, "nice release", detailed.Comments[0].Content)
+}
+
+// Сервисы отвечают в формате запроса, поэтому api-gateway может перейти на Protobuf
+// независимо от них
+func TestProtobufContentType(t *testing.T) {
+	s := NewStack(t, Options{OffensiveWords: []string{"bad"}, ContentType: "application/x-protobuf"})
+	addNews(t, s, newsstorage.News{Title: "Go 1.22", Rubric: "Technology", Link: "https://example.com/go"})
+
+	resp := postComment(t, s, "1", `{"comment_time":1,"user_name":"gopher","content":"nice release"}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = postComment(t, s, "1", `{"comment_time":2,"user_name":"gopher","content":"bad release"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+
+	resp = get(t, s, "/newsDetailed?id_news=1")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var detailed newsDetailed
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&detailed))
+	require.Len(t, detailed.News, 1)
+	assert.Equal(t, "Go 1.22", detailed.News[0].Title)
+	require.Len(t, detailed.Comments, 1)
+	assert.Equal(t, "nice release", detailed.Comments[0].Content)
+
+	for _, msg := range s.Broker.Messages("comments-response") {
+		assert.Equal(t, "application/x-protobuf", kafka.MetadataFromMessage(msg).ContentType)
+	}
+}
+
+// Запрос /newsDetailed и обработка его сообщений сервисами входят в одну трассировку
+func TestTraceCoversFanOut(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(noop.NewTracerProvider()) })
+
+	s := NewStack(t, Options{})
+	addNews(t, s, newsstorage.News{Title: "Go 1.22", Rubric: "Technology", Link: "https://example.com/go"})
+
+	resp := get(t, s, "/newsDetailed?id_news=1")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	expected := []string{
+		"GET /newsDetailed",
+		"request news-response",
+		"request comments-response",
+		"news-response process",
+		"comments-response process",
+	}
+	// Спаны обработки завершаются после отправки ответа
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	require.Eventually(t, func() bool {
+		for _, span := range recorder.Ended() {
+			spans[span.Name()] = span
+		}
+		return len(spans) >= len(expected)
+	}, time.Second, 10*time.Millisecond)
+
+	traceID := spans["GET /newsDetailed"].SpanContext().TraceID()
+	for _, name := range expected {
+		require.Contains(t, spans, name)
+		assert.Equal(t, traceID, spans[name].SpanContext().TraceID(), name)
+	}
+	assert.Equal(t, spans["request news-response"].SpanContext().SpanID(), spans["news-response process"].Parent().SpanID())
+}
+
+// Метаданные запроса передаются в заголовках, traceparent клиента доходит до ответа сервиса и без экспорта трассировки
+func TestMetadataHeaders(t *testing.T) {
+	s := NewStack(t, Options{})
+	addNews(t, s, newsstorage.News{Title: "Go 1.22", Rubric: "Technology", Link: "https://example.com/go"})
+
+	const traceID = "0af7651916cd43dd8448eb211c80319c"
+	req, err := http.NewRequest(http.MethodGet, s.Server.URL+"/news/Technology/10?request_id=req-1", nil)
+	require.NoError(t, err)
+	req.Header.Set("traceparent", "00-"+traceID+"-b7ad6b7169203331-01")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	requests := s.Broker.Messages("news-response")
+	require.Len(t, requests, 1)
+	md := kafka.MetadataFromMessage(requests[0])
+	assert.Equal(t, "req-1", md.RequestID)
+	assert.Equal(t, "api-gateway-reply.e2e", md.ReplyTo)
+	assert.NotZero(t, md.Deadline)
+	assert.Contains(t, md.Traceparent, traceID)
+	assert.Equal(t, contracts.SchemaVersion, md.SchemaVersion)
+	assert.Equal(t, "application/json", md.ContentType)
+
+	// Топик ответа и дедлайн больше не передаются в теле
+	var body contracts.NewsRequest
+	require.NoError(t, json.Unmarshal(requests[0].Value, &body))
+	assert.Empty(t, body.ReplyTo)
+	assert.Zero(t, body.Deadline)
+
+	replies := s.Broker.Messages("api-gateway-reply.e2e")
+	require.Len(t, replies, 1)
+	replyMD := kafka.MetadataFromMessage(replies[0])
+	assert.Equal(t, "req-1", replyMD.RequestID)
+	assert.Empty(t, replyMD.ReplyTo)
+	assert.Contains(t, replyMD.Traceparent, traceID)
+}
+
+// Сообщение, повторно отправленное из dead-letter топика, обрабатывается, хотя дедлайн
+// исходного запроса истек, а ответ не отправляется в топик ответа исходного api-gateway
+func TestRedriveDeadLetter(t *testing.T) {
+	s := NewStack(t, Options{})
+
+	expired := strconv.FormatInt(time.Now().Add(-time.Minute).UnixMilli(), 10)
+	value, err := json.Marshal(contracts.CommentsRequest{
+		Version:     contracts.SchemaVersion,
+		ID:          "req-dlq",
+		TypeQuery:   contracts.TypeCommentNew,
+		IdNews:      1,
+		CommentTime: 1,
+		UserName:    "gopher",
+		Content:     "redriven",
+		ReplyTo:     "api-gateway-reply.gone",
+		Deadline:    time.Now().Add(-time.Minute).UnixMilli(),
+	})
+	require.NoError(t, err)
+
+	dlq := commentskafka.NewDeadLetterQueue(nil, "comments-response-dlq", s.Broker.Producer())
+	require.NoError(t, dlq.Redrive(commentskafka.DeadLetter{
+		OriginalTopic: "comments-response",
+		Key:           "req-dlq",
+		Value:         string(value),
+		Headers: map[string]string{
+			commentskafka.HeaderRequestID:   "req-dlq",
+			commentskafka.HeaderReplyTo:     "api-gateway-reply.gone",
+			commentskafka.HeaderDeadline:    expired,
+			commentskafka.HeaderContentType: "application/json",
+		},
+	}))
+
+	require.Eventually(t, func() bool {
+		comments, err := s.Comments.CommentsByIdNews(context.Background(), 1)
+		return err == nil && len(comments) == 1 && comments[0].Content == "redriven"
+	}, 5*time.Second, 20*time.Millisecond)
+	assert.Empty(t, s.Broker.Messages("api-gateway-reply.gone"))
+}
+
+// Комментарий с запрещенными словами отклоняется и не попадает в хранилище
+func TestAddCommentRejectedByCensor(t *testing.T) {
+	s := NewStack(t, Options{OffensiveWords: []string{"bad"}})
+
+	resp := postComment(t, s, "1", `{"comment_time":1,"user_name":"gopher","content":"bad words"}`)
+	require.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+
+	problem := decodeProblem(t, resp)
+	assert.Equal(t, gatewayapi.CodeRejected, problem.Code)
+	assert.False(t, problem.Retryable)
+	assert.Equal(t, "content", problem.Errors["field"])
+
+	comments, err := s.Comments.CommentsByIdNews(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Empty(t, comments)
+}
+
+// Комментарий без статьи отклоняется service-comments
+func TestReadiness(t *testing.T) {
+	s := NewStack(t, Options{})
+
+	resp := get(t, s, "/healthz")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// До первого ответа на Ping сервисы не готовы
+	resp = get(t, s, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Gateway.PingServices(ctx)
+
+	var readiness struct {
+		Status string `json:"status"`
+		Checks map[string]struct {
+			Status  string            `json:"status"`
+			Details map[string]string `json:"details"`
+		} `json:"checks"`
+	}
+	require.Eventually(t, func() bool {
+		resp, err := http.Get(s.Server.URL + "/readyz")
+		if err != nil {
+			return false
+		}
+		defer resp.Body.Close()
+		return resp.StatusCode == http.StatusOK && json.NewDecoder(resp.Body).Decode(&readiness) == nil
+	}, 5*time.Second, 50*time.Millisecond)
+
+	assert.Equal(t, "ok", readiness.Status)
+	assert.Equal(t, "ok", readiness.Checks["kafka"].Status)
+	assert.Equal(t, "ok", readiness.Checks["service-censor"].Status)
+	assert.Equal(t, "ok", readiness.Checks["service-news"].Details[contracts.PingPostgres])
+	assert.Equal(t, "ok", readiness.Checks["service-comments"].Details[contracts.PingPostgres])
+
+	// Недоступность брокеров делает api-gateway неготовым
+	s.Gateway.SetBrokerCheck(func() error { return errors.New("kafka down") })
+	resp = get(t, s, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+}
+
+func TestAddCommentInvalidNews(t *testing.T) {
+	s := NewStack(t, Options{})
+
+	resp := postComment(t, s, "0", `{"comment_time":1,"user_name":"gopher","content":"hello"}`)
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	// Запрос отклоняется api-gateway до отправки в сервисы
+	problem := decodeProblem(t, resp)
+	assert.Equal(t, gatewayapi.CodeInvalid, problem.Code)
+	assert.Contains(t, problem.Errors, "id_news")
+	assert.Empty(t, s.Broker.Messages("censor-response"))
+}
+
+func TestNewsList(t *testing.T) {
+	s := NewStack(t, Options{})
+	addNews(t, s,
+		newsstorage.News{Title: "First", Rubric: "Technology", Link: "l1", PublicTime: 1},
+		newsstorage.News{Title: "Second", Rubric: "Technology", Link: "l2", PublicTime: 2},
+		newsstorage.News{Title: "Other", Rubric: "Sport", Link: "l3", PublicTime: 3},
+	)
+
+	resp := get(t, s, "/news/Technology/1?page=2")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var list struct {
+		News     []newsstorage.News   `json:"news"`
+		Paginate newsstorage.Paginate `json:"paginate"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&list))
+	require.Len(t, list.News, 1)
+	assert.Equal(t, "First", list.News[0].Title)
+	assert.Equal(t, newsstorage.Paginate{PageCurr: 2, PageCount: 2, PageCountList: 1, PageCountTotal: 2}, list.Paginate)
+}
+
+func TestNewsDetailedNotFound(t *testing.T) {
+	s := NewStack(t, Options{})
+
+	resp := get(t, s, "/newsDetailed?id_news=42")
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	problem := decodeProblem(t, resp)
+	assert.Equal(t, gatewayapi.CodeNotFound, problem.Code)
+	assert.NotEmpty(t, problem.RequestID)
+}
+
+// Маршруты /api/v1 и маршруты без версии работают с одними и теми же данными
+func TestAPIv1(t *testing.T) {
+	s := NewStack(t, Options{OffensiveWords: []string{"bad"}})
+	addNews(t, s,
+		newsstorage.News{Title: "First", Rubric: "Technology", Link: "l1", PublicTime: 1},
+		newsstorage.News{Title: "Second", Rubric: "Technology", Link: "l2", PublicTime: 2},
+		newsstorage.News{Title: "Other", Rubric: "Sport", Link: "l3", PublicTime: 3},
+	)
+
+	// Без rubric - все рубрики
+	resp := get(t, s, "/api/v1/news?page_size=2&page=2")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var list struct {
+		News     []newsstorage.News   `json:"news"`
+		Paginate newsstorage.Paginate `json:"paginate"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&list))
+	require.Len(t, list.News, 1)
+	assert.Equal(t, "First", list.News[0].Title)
+	assert.Equal(t, 2, list.Paginate.PageCount)
+
+	resp = get(t, s, "/api/v1/news?rubric=Sport")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	list.News = nil
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&list))
+	require.Len(t, list.News, 1)
+	assert.Equal(t, "Other", list.News[0].Title)
+
+	resp = get(t, s, "/api/v1/news/1")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var item newsstorage.News
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&item))
+	assert.Equal(t, "First", item.Title)
+
+	resp = get(t, s, "/api/v1/news/42")
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Equal(t, gatewayapi.CodeNotFound, decodeProblem(t, resp).Code)
+
+	resp, err := http.Post(s.Server.URL+"/api/v1/news/1/comments", "application/json", strings.NewReader(`{"user_name":"gopher","content":"nice"}`))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	var created contracts.Comment
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+	assert.NotZero(t, created.Id)
+	assert.Equal(t, 1, created.IdNews)
+	assert.NotZero(t, created.CommentTime)
+	assert.Equal(t, "nice", created.Content)
+	assert.Equal(t, "/api/v1/news/1/comments/"+strconv.Itoa(created.Id), resp.Header.Get("Location"))
+
+	// Комментарий, добавленный через /api/v1, виден в маршруте без версии
+	resp = get(t, s, "/newsDetailed?id_news=1")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "true", resp.Header.Get("Deprecation"))
+	var detailed newsDetailed
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&detailed))
+	require.Len(t, detailed.Comments, 1)
+
+	resp = get(t, s, "/api/v1/news/1/comments")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var comments struct {
+		Comments []struct {
+			UserName string `json:"user_name"`
+		} `json:"comments"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&comments))
+	require.Len(t, comments.Comments, 1)
+	assert.Equal(t, "gopher", comments.Comments[0].UserName)
+
+	resp = get(t, s, "/api/v1/rubrics")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var rubrics struct {
+		Rubrics []string `json:"rubrics"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&rubrics))
+	assert.Contains(t, rubrics.Rubrics, "Technology")
+}
+
+// Изменение комментария проверяется цензурой и сохраняет время изменения,
+// удаленный комментарий не возвращается и не изменяется
+func TestEditAndDeleteComment(t *testing.T) {
+	s := NewStack(t, Options{OffensiveWords: []string{"bad"}})
+	resp := send(t, s, http.MethodPost, "/api/v1/news/1/comments", `{"user_name":"gopher","content":"helo"}`)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	var created contracts.Comment
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+	id := created.Id
+	path := resp.Header.Get("Location")
+	require.Equal(t, "/api/v1/news/1/comments/"+strconv.Itoa(id), path)
+
+	resp = send(t, s, http.MethodPut, path, `{"user_name":"gopher","content":"bad words"}`)
+	require.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+	assert.Equal(t, gatewayapi.CodeRejected, decodeProblem(t, resp).Code)
+
+	resp = send(t, s, http.MethodPut, path, `{"user_name":"gopher","content":"hello"}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var updated contracts.Comment
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&updated))
+	assert.Equal(t, id, updated.Id)
+	assert.Equal(t, "hello", updated.Content)
+	assert.Equal(t, "gopher", updated.UserName)
+	assert.Equal(t, created.CommentTime, updated.CommentTime)
+	assert.NotZero(t, updated.EditedAt)
+
+	resp = send(t, s, http.MethodPut, "/api/v1/news/2/comments/"+strconv.Itoa(id), `{"user_name":"gopher","content":"hello"}`)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Equal(t, gatewayapi.CodeNotFound, decodeProblem(t, resp).Code)
+
+	resp = send(t, s, http.MethodDelete, path, `{"user_name":"gopher"}`)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	comments, err := s.Comments.CommentsByIdNews(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Empty(t, comments)
+
+	resp = send(t, s, http.MethodDelete, path, `{"user_name":"gopher"}`)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Equal(t, gatewayapi.CodeNotFound, decodeProblem(t, resp).Code)
+}
+
+// Изменять и удалять комментарий может только его автор
+func TestEditCommentByAnotherUser(t *testing.T) {
+	s := NewStack(t, Options{})
+	resp := send(t, s, http.MethodPost, "/api/v1/news/1/comments", `{"user_name":"gopher","content":"hello"}`)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	path := resp.Header.Get("Location")
+
+	resp = send(t, s, http.MethodPut, path, `{"user_name":"mallory","content":"hacked"}`)
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+	problem := decodeProblem(t, resp)
+	assert.Equal(t, gatewayapi.CodeForbidden, problem.Code)
+	assert.Equal(t, "does not match the comment author", problem.Errors["user_name"])
+
+	resp = send(t, s, http.MethodDelete, path, `{"user_name":"mallory"}`)
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.Equal(t, gatewayapi.CodeForbidden, decodeProblem(t, resp).Code)
+
+	comments, err := s.Comments.CommentsByIdNews(context.Background(), 1)
+	require.NoError(t, err)
+	require.Len(t, comments, 1)
+	assert.Equal(t, "hello", comments[0].Content)
+}
+
+// Ответ на комментарий сохраняется с уровнем вложенности и возвращается в дереве;
+// родитель другой статьи и превышение вложенности отклоняются service-comments
+func TestCommentReplies(t *testing.T) {
+	s := NewStack(t, Options{})
+	ctx := context.Background()
+	root, err := s.Comments.CommentNew(ctx, contracts.Comment{IdNews: 1, CommentTime: 1, UserName: "gopher", Content: "first"})
+	require.NoError(t, err)
+	deep, err := s.Comments.CommentNew(ctx, contracts.Comment{IdNews: 1, CommentTime: 2, UserName: "gopher", Content: "deep", ParentId: root, Depth: 3})
+	require.NoError(t, err)
+	other, err := s.Comments.CommentNew(ctx, contracts.Comment{IdNews: 2, CommentTime: 3, UserName: "gopher", Content: "other"})
+	require.NoError(t, err)
+
+	resp := send(t, s, http.MethodPost, "/api/v1/news/1/comments", `{"user_name":"bob","content":"reply","parent_id":`+strconv.Itoa(root)+`}`)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	var created contracts.Comment
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+	assert.Equal(t, root, created.ParentId)
+	assert.Equal(t, 1, created.Depth)
+	assert.Equal(t, other+1, created.Id)
+
+	resp = send(t, s, http.MethodPost, "/api/v1/news/1/comments", `{"user_name":"bob","content":"too deep","parent_id":`+strconv.Itoa(deep)+`}`)
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	problem := decodeProblem(t, resp)
+	assert.Equal(t, gatewayapi.CodeInvalid, problem.Code)
+	assert.Equal(t, "replies are limited to depth 3", problem.Errors["parent_id"])
+
+	resp = send(t, s, http.MethodPost, "/api/v1/news/1/comments", `{"user_name":"bob","content":"wrong news","parent_id":`+strconv.Itoa(other)+`}`)
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, "belongs to news 2", decodeProblem(t, resp).Errors["parent_id"])
+
+	resp = get(t, s, "/api/v1/news/1/comments?view=tree")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var tree struct {
+		Comments []gatewayapi.CommentNode `json:"comments"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&tree))
+	require.Len(t, tree.Comments, 1)
+	assert.Equal(t, root, tree.Comments[0].Id)
+	require.Len(t, tree.Comments[0].Replies, 2)
+	assert.Equal(t, "reply", tree.Comments[0].Replies[0].Content)
+	assert.Equal(t, 1, tree.Comments[0].Replies[0].Depth)
+
+	resp = get(t, s, "/api/v1/news/1/comments")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var flat struct {
+		Comments []contracts.Comment `json:"comments"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&flat))
+	require.Len(t, flat.Comments, 3)
+	assert.Equal(t, root, flat.Comments[0].ParentId)
+}
@@ -0,0 +1,293 @@
+// Package memory - брокер сообщений в памяти, заменяющий Kafka в сквозных тестах.
+// Producer, Consumer и GroupConsumer реализуют интерфейсы пакетов pkg/kafka
+// всех сервисов, поэтому api-gateway и сервисы можно связать в одном процессе.
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// ErrBrokerClosed - брокер закрыт
+var ErrBrokerClosed = errors.New("memory broker closed")
+
+// MessageHandler - обработчик одного сообщения, совпадает с kafka.MessageHandler сервисов
+type MessageHandler = func(ctx context.Context, msg *sarama.ConsumerMessage) error
+
+// Broker - брокер сообщений в памяти.
+// Каждый топик состоит из одной партиции; сообщения хранятся до закрытия брокера.
+type Broker struct {
+	mu      sync.Mutex
+	topics  map[string][]*sarama.ConsumerMessage
+	offsets map[string]int64 // следующее смещение группы: group/topic -> offset
+	changed chan struct{}    // закрывается при появлении новых сообщений
+	closed  bool
+}
+
+// NewBroker - создание нового экземпляра Broker
+func NewBroker() *Broker {
+	return &Broker{
+		topics:  make(map[string][]*sarama.ConsumerMessage),
+		offsets: make(map[string]int64),
+		changed: make(chan struct{}),
+	}
+}
+
+// Publish - запись сообщения в конец топика
+func (b *Broker) Publish(msg *sarama.ProducerMessage) error {
+	var key, value []byte
+	var err error
+	if msg.Key != nil {
+		if key, err = msg.Key.Encode(); err != nil {
+			return fmt.Errorf("failed to encode key: %w", err)
+		}
+	}
+	if msg.Value != nil {
+		if value, err = msg.Value.Encode(); err != nil {
+			return fmt.Errorf("failed to encode value: %w", err)
+		}
+	}
+
+	headers := make([]*sarama.RecordHeader, 0, len(msg.Headers))
+	for i := range msg.Headers {
+		h := msg.Headers[i]
+		headers = append(headers, &h)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return ErrBrokerClosed
+	}
+
+	b.topics[msg.Topic] = append(b.topics[msg.Topic], &sarama.ConsumerMessage{
+		Topic:     msg.Topic,
+		Partition: 0,
+		Offset:    int64(len(b.topics[msg.Topic])),
+		Key:       key,
+		Value:     value,
+		Headers:   headers,
+		Timestamp: time.Now(),
+	})
+	b.notify()
+	return nil
+}
+
+// Messages - копия всех сообщений топика
+func (b *Broker) Messages(topic string) []*sarama.ConsumerMessage {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]*sarama.ConsumerMessage(nil), b.topics[topic]...)
+}
+
+// Close - закрытие брокера, ожидающие чтения потребители завершаются
+func (b *Broker) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.closed {
+		b.closed = true
+		close(b.changed)
+	}
+	return nil
+}
+
+// notify - пробуждение ожидающих потребителей, вызывается под блокировкой
+func (b *Broker) notify() {
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
+
+// fetch - ожидание сообщения топика по смещению, которое вычисляет next.
+// next вызывается под блокировкой и возвращает false, если сообщения еще нет.
+func (b *Broker) fetch(ctx context.Context, topic string, next func(log []*sarama.ConsumerMessage) (*sarama.ConsumerMessage, bool)) (*sarama.ConsumerMessage, error) {
+	for {
+		b.mu.Lock()
+		if b.closed {
+			b.mu.Unlock()
+			return nil, ErrBrokerClosed
+		}
+		if msg, ok := next(b.topics[topic]); ok {
+			b.mu.Unlock()
+			return msg, nil
+		}
+		changed := b.changed
+		b.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// Producer - отправка сообщений в брокер в памяти
+type Producer struct {
+	broker *Broker
+}
+
+// Producer - создание нового экземпляра Producer
+func (b *Broker) Producer() *Producer {
+	return &Producer{broker: b}
+}
+
+// SendMessage - отправка сообщения в топик
+func (p *Producer) SendMessage(topic string, key string, value []byte) error {
+	return p.broker.Publish(&sarama.ProducerMessage{
+		Topic: topic,
+		Key:   sarama.StringEncoder(key),
+		Value: sarama.ByteEncoder(value),
+	})
+}
+
+// Send - отправка подготовленного сообщения, например, с заголовками
+func (p *Producer) Send(ctx context.Context, msg *sarama.ProducerMessage) error {
+	return p.broker.Publish(msg)
+}
+
+// Close - закрытие Producer
+func (p *Producer) Close() error {
+	return nil
+}
+
+// Consumer - чтение партиции топика, аналог kafka.Consumer api-gateway
+type Consumer struct {
+	broker *Broker
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// Consumer - создание нового экземпляра Consumer
+func (b *Broker) Consumer() *Consumer {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Consumer{broker: b, ctx: ctx, cancel: cancel}
+}
+
+// Consume - чтение топика начиная с offset (sarama.OffsetNewest, sarama.OffsetOldest
+// или конкретное смещение) до закрытия Consumer
+func (c *Consumer) Consume(topic string, partition int32, offset int64) (<-chan *sarama.ConsumerMessage, error) {
+	if partition != 0 {
+		return nil, fmt.Errorf("topic %v has no partition %v", topic, partition)
+	}
+
+	c.broker.mu.Lock()
+	switch offset {
+	case sarama.OffsetNewest:
+		offset = int64(len(c.broker.topics[topic]))
+	case sarama.OffsetOldest:
+		offset = 0
+	}
+	c.broker.mu.Unlock()
+
+	messages := make(chan *sarama.ConsumerMessage)
+	go func() {
+		defer close(messages)
+		for {
+			msg, err := c.broker.fetch(c.ctx, topic, func(log []*sarama.ConsumerMessage) (*sarama.ConsumerMessage, bool) {
+				if offset >= int64(len(log)) {
+					return nil, false
+				}
+				return log[offset], true
+			})
+			if err != nil {
+				return
+			}
+			offset++
+
+			select {
+			case messages <- msg:
+			case <-c.ctx.Done():
+				return
+			}
+		}
+	}()
+	return messages, nil
+}
+
+// Close - закрытие Consumer
+func (c *Consumer) Close() error {
+	c.cancel()
+	return nil
+}
+
+// GroupConsumer - чтение топиков в составе группы, аналог kafka.GroupConsumer сервисов.
+// Экземпляры одной группы делят смещение, поэтому каждое сообщение обрабатывается один раз.
+type GroupConsumer struct {
+	broker  *Broker
+	groupID string
+	logs    *slog.Logger
+	ctx     context.Context
+	cancel  context.CancelFunc
+}
+
+// GroupConsumer - создание нового экземпляра GroupConsumer
+func (b *Broker) GroupConsumer(groupID string, logs *slog.Logger) *GroupConsumer {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &GroupConsumer{broker: b, groupID: groupID, logs: logs, ctx: ctx, cancel: cancel}
+}
+
+// Consume - обработка сообщений топиков до отмены контекста или закрытия GroupConsumer.
+// Новая группа читает топики с начала, поэтому запросы, отправленные до запуска
+// сервиса, не теряются.
+func (c *GroupConsumer) Consume(ctx context.Context, topics []string, handler MessageHandler) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-c.ctx.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for _, topic := range topics {
+		wg.Add(1)
+		go func(topic string) {
+			defer wg.Done()
+			key := c.groupID + "/" + topic
+			for {
+				msg, err := c.broker.fetch(ctx, topic, func(log []*sarama.ConsumerMessage) (*sarama.ConsumerMessage, bool) {
+					offset := c.broker.offsets[key]
+					if offset >= int64(len(log)) {
+						return nil, false
+					}
+					c.broker.offsets[key] = offset + 1
+					return log[offset], true
+				})
+				if err != nil {
+					return
+				}
+				// Как и в GroupConsumer сервисов, после ошибки обработчика смещение
+				// не фиксируется и сообщение читается снова
+				if err := handler(ctx, msg); err != nil {
+					if c.logs != nil {
+						c.logs.Error("failed to process message",
+							slog.String("topic", msg.Topic),
+							slog.Int64("offset", msg.Offset),
+							slog.Any("error", err),
+						)
+					}
+					c.broker.mu.Lock()
+					c.broker.offsets[key] = msg.Offset
+					c.broker.mu.Unlock()
+				}
+			}
+		}(topic)
+	}
+	wg.Wait()
+	return nil
+}
+
+// Close - закрытие GroupConsumer
+func (c *GroupConsumer) Close() error {
+	c.cancel()
+	return nil
+}
@@ -0,0 +1,88 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConsumer_ReadsFromNewest(t *testing.T) {
+	b := NewBroker()
+	defer b.Close()
+	p := b.Producer()
+
+	assert.NoError(t, p.SendMessage("topic", "old", []byte("old")))
+
+	c := b.Consumer()
+	defer c.Close()
+	messages, err := c.Consume("topic", 0, sarama.OffsetNewest)
+	assert.NoError(t, err)
+
+	assert.NoError(t, p.SendMessage("topic", "new", []byte("new")))
+
+	select {
+	case msg := <-messages:
+		assert.Equal(t, "new", string(msg.Key))
+		assert.Equal(t, int64(1), msg.Offset)
+	case <-time.After(time.Second):
+		t.Fatal("message not received")
+	}
+}
+
+// Сообщение обрабатывается одним экземпляром группы, но каждой группой
+func TestGroupConsumer_DeliversOncePerGroup(t *testing.T) {
+	b := NewBroker()
+	defer b.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mu sync.Mutex
+	received := make(map[string]int)
+	var wg sync.WaitGroup
+	wg.Add(4)
+	consume := func(group string) {
+		go b.GroupConsumer(group, nil).Consume(ctx, []string{"topic"}, func(ctx context.Context, msg *sarama.ConsumerMessage) error {
+			mu.Lock()
+			received[group]++
+			mu.Unlock()
+			wg.Done()
+			return nil
+		})
+	}
+	consume("a")
+	consume("a")
+	consume("b")
+
+	assert.NoError(t, b.Producer().SendMessage("topic", "key", []byte("value")))
+	assert.NoError(t, b.Producer().SendMessage("topic", "key", []byte("value")))
+
+	wg.Wait()
+	time.Sleep(10 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, map[string]int{"a": 2, "b": 2}, received)
+}
+
+func TestGroupConsumer_StopsOnClose(t *testing.T) {
+	b := NewBroker()
+	c := b.GroupConsumer("group", nil)
+
+	done := make(chan error)
+	go func() {
+		done <- c.Consume(context.Background(), []string{"topic"}, func(context.Context, *sarama.ConsumerMessage) error { return nil })
+	}()
+
+	c.Close()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("consumer not stopped")
+	}
+}
@@ -25,6 +25,11 @@ func NewCensor(filePathWords string) (*Censor, error) {
 	return c, nil
 }
 
+// NewCensorFromWords создает новый экземпляр Censor с заданным списком оскорбительных слов
+func NewCensorFromWords(words []string) *Censor {
+	return &Censor{offensiveWords: words}
+}
+
 // loadOffensiveWords загружает оскорбительные слова из JSON файла
 func (c *Censor) loadOffensiveWords(filePath string) error {
 	file, err := os.Open(filePath)
@@ -4,11 +4,6 @@ import (
 	"testing"
 )
 
-// Создадим структуру для тестирования Censor с использованием брутального списка слов
-func NewCensorFromWords(words []string) *Censor {
-	return &Censor{offensiveWords: words}
-}
-
 // TestIsOffensive проверяет метод IsOffensive на наличие оскорбительных слов.
 func TestIsOffensive(t *testing.T) {
 	// Создаем Censor с оскорбительными словами
@@ -0,0 +1,154 @@
+// Package handler - проверка комментариев на цензуру по запросам, полученным из Kafka
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"news-kafka/contracts"
+	"news-kafka/service-censor/pkg/censor"
+	"news-kafka/service-censor/pkg/kafka"
+	"news-kafka/service-censor/pkg/logger"
+	"news-kafka/service-censor/pkg/metrics"
+
+	"github.com/IBM/sarama"
+)
+
+// New - обработчик запросов на проверку комментариев, полученных из Kafka.
+// Возвращаемая ошибка приводит к повторной обработке сообщения.
+func New(censor *censor.Censor, producer kafka.ProducerInterface, config *kafka.Config, logs *slog.Logger) kafka.MessageHandler {
+	return func(ctx context.Context, msg *sarama.ConsumerMessage) error {
+		// Обработка входящего сообщения
+		// Ответ кодируется в том же формате, что и запрос
+		codec, err := kafka.MessageCodec(msg)
+		if err != nil {
+			return kafka.Permanent(err)
+		}
+		var receivedMessage contracts.CommentsRequest
+		err = codec.Unmarshal(msg.Value, &receivedMessage)
+		if err != nil {
+			return kafka.Permanent(fmt.Errorf("failed to decode message: %w", err))
+		}
+		md := requestMetadata(ctx, &receivedMessage)
+		ctx = kafka.ContextWithMetadata(ctx, md)
+		ctx = logger.ContextWith(ctx, logger.RequestID(md.RequestID))
+
+		//пишем запрос данных в лог, кроме периодических Ping
+		if receivedMessage.TypeQuery != contracts.TypePing {
+			logMessage(ctx, logs, config.Redactor(), receivedMessage)
+		}
+
+		// Ответ на просроченный запрос api-gateway уже не ждет
+		if kafka.Expired(md.Deadline) {
+			logs.WarnContext(ctx, "deadline exceeded, message skipped", slog.String("type_query", receivedMessage.TypeQuery))
+			return nil
+		}
+
+		responseMessage := contracts.CommentsReply{
+			ID:        md.RequestID,
+			Name:      logger.GetServiceName(),
+			TypeQuery: receivedMessage.TypeQuery,
+			Reply:     contracts.ReplyOK(),
+			IdNews:    receivedMessage.IdNews,
+			Comments:  nil,
+		}
+
+		switch receivedMessage.TypeQuery {
+		case contracts.TypeCommentNew, contracts.TypeCommentUpdate:
+
+			// Проверка комментария, при изменении - повторная проверка нового текста
+			if censor.IsOffensive(receivedMessage.UserName) {
+				responseMessage.Reply = contracts.ReplyFail(contracts.StatusRejected, "user name contains forbidden words", map[string]string{"field": "user_name"})
+			} else if censor.IsOffensive(receivedMessage.Content) {
+				responseMessage.Reply = contracts.ReplyFail(contracts.StatusRejected, "comment contains forbidden words", map[string]string{"field": "content"})
+			}
+			if !responseMessage.IsOK() {
+				metrics.CensorRejections.WithLabelValues(responseMessage.Details["field"]).Inc()
+				logs.InfoContext(ctx, "comment rejected", slog.String("reason", responseMessage.Message))
+			}
+
+			return sendReply(ctx, producer, codec, replyTopic(md.ReplyTo, config.TopicReceivedAddCensor), &responseMessage)
+
+		case contracts.TypePing:
+			// Сервис без внешних зависимостей: ответ означает, что сообщения обрабатываются
+			return sendReply(ctx, producer, codec, replyTopic(md.ReplyTo, config.TopicReceivedAddCensor), &responseMessage)
+		}
+
+		return kafka.Permanent(fmt.Errorf("unknown type_query: %v", receivedMessage.TypeQuery))
+	}
+}
+
+// ReplyFailure - ответ со статусом internal на запрос, который не удалось обработать,
+// чтобы api-gateway не ждал ответа до таймаута
+func ReplyFailure(producer kafka.ProducerInterface, config *kafka.Config, logs *slog.Logger) func(ctx context.Context, msg *sarama.ConsumerMessage, err error) {
+	return func(ctx context.Context, msg *sarama.ConsumerMessage, err error) {
+		codec, err := kafka.MessageCodec(msg)
+		if err != nil {
+			return
+		}
+		var receivedMessage contracts.CommentsRequest
+		if codec.Unmarshal(msg.Value, &receivedMessage) != nil {
+			return
+		}
+		md := requestMetadata(ctx, &receivedMessage)
+		ctx = kafka.ContextWithMetadata(ctx, md)
+		ctx = logger.ContextWith(ctx, logger.RequestID(md.RequestID))
+
+		responseMessage := contracts.CommentsReply{
+			ID:        md.RequestID,
+			Name:      logger.GetServiceName(),
+			TypeQuery: receivedMessage.TypeQuery,
+			Reply:     contracts.ReplyFail(contracts.StatusInternal, "internal service error", nil),
+			IdNews:    receivedMessage.IdNews,
+		}
+
+		if err := sendReply(ctx, producer, codec, replyTopic(md.ReplyTo, config.TopicReceivedAddCensor), &responseMessage); err != nil {
+			logs.ErrorContext(ctx, "failed to send failure reply", logger.Err(err))
+		}
+	}
+}
+
+// sendReply - отправка ответа в Kafka с идентификатором запроса и спаном обработки из контекста
+func sendReply(ctx context.Context, producer kafka.ProducerInterface, codec contracts.Codec, topic string, responseMessage *contracts.CommentsReply) error {
+	md := kafka.MetadataFromContext(ctx)
+	ctx = kafka.ContextWithMetadata(ctx, kafka.Metadata{
+		RequestID: md.RequestID,
+		Source:    logger.GetServiceName(),
+	})
+
+	msg, err := kafka.NewMessage(ctx, codec, topic, responseMessage)
+	if err != nil {
+		return kafka.Permanent(err)
+	}
+	return producer.Send(ctx, msg)
+}
+
+// requestMetadata - метаданные запроса из заголовков сообщения (контекст Pipeline).
+// Отправители предыдущих версий передают их только в теле сообщения.
+func requestMetadata(ctx context.Context, receivedMessage *contracts.CommentsRequest) kafka.Metadata {
+	return kafka.MetadataFromContext(ctx).WithDefaults(kafka.Metadata{
+		RequestID: receivedMessage.ID,
+		ReplyTo:   receivedMessage.ReplyTo,
+		Deadline:  receivedMessage.Deadline,
+		Source:    receivedMessage.Name,
+	})
+}
+
+// replyTopic - топик для ответа: reply_to из запроса или топик из конфигурации
+func replyTopic(replyTo, defaultTopic string) string {
+	if replyTo != "" {
+		return replyTo
+	}
+	return defaultTopic
+}
+
+// logMessage - запись запроса в лог, тело запроса в формате JSON передается в поле body
+// после скрытия данных по правилам redact
+func logMessage(ctx context.Context, logs *slog.Logger, redact *logger.Redactor, msg interface{}) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		data = []byte(fmt.Sprintf("%+v", msg))
+	}
+	logs.InfoContext(ctx, "request received", redact.Body(data))
+}
@@ -0,0 +1,19 @@
+package kafka
+
+import (
+	"context"
+	"time"
+)
+
+// DeadlineContext - контекст с дедлайном запроса (unix, мс); 0 - дедлайна нет
+func DeadlineContext(ctx context.Context, deadlineMs int64) (context.Context, context.CancelFunc) {
+	if deadlineMs <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithDeadline(ctx, time.UnixMilli(deadlineMs))
+}
+
+// Expired - проверка, истек ли дедлайн запроса
+func Expired(deadlineMs int64) bool {
+	return deadlineMs > 0 && time.Now().UnixMilli() >= deadlineMs
+}
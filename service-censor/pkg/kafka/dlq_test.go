@@ -0,0 +1,94 @@
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+)
+
+// Сообщение, прочитанное из dead-letter топика
+func consumedMessage(msg *sarama.ProducerMessage, partition int32, offset int64) *sarama.ConsumerMessage {
+	key, _ := msg.Key.Encode()
+	value, _ := msg.Value.Encode()
+
+	consumed := &sarama.ConsumerMessage{
+		Topic:     msg.Topic,
+		Partition: partition,
+		Offset:    offset,
+		Key:       key,
+		Value:     value,
+	}
+	for i := range msg.Headers {
+		consumed.Headers = append(consumed.Headers, &msg.Headers[i])
+	}
+	return consumed
+}
+
+func TestDeadLetterQueue_PublishAndRedrive(t *testing.T) {
+	sender := &testSender{}
+	q := NewDeadLetterQueue(nil, "dlq", sender)
+
+	original := &sarama.ConsumerMessage{
+		Topic:     "news-response",
+		Partition: 2,
+		Offset:    42,
+		Key:       []byte("req"),
+		Value:     []byte(`{"id":"req"}`),
+		Headers: []*sarama.RecordHeader{
+			{Key: []byte("request-id"), Value: []byte("req")},
+			{Key: []byte("reply-to"), Value: []byte("api-gateway-reply.1")},
+			{Key: []byte("deadline"), Value: []byte("1730100873000")},
+		},
+	}
+
+	err := q.Publish(context.Background(), original, fmt.Errorf("db error"), 3)
+	assert.NoError(t, err)
+	assert.Len(t, sender.messages, 1)
+
+	letter := deadLetterFromMessage(consumedMessage(sender.messages[0], 0, 5))
+	assert.Equal(t, "news-response", letter.OriginalTopic)
+	assert.Equal(t, int32(2), letter.OriginalPartition)
+	assert.Equal(t, int64(42), letter.OriginalOffset)
+	assert.Equal(t, "db error", letter.Error)
+	assert.Equal(t, 3, letter.Attempts)
+	assert.Equal(t, "req", letter.Key)
+	assert.Equal(t, `{"id":"req"}`, letter.Value)
+	assert.Equal(t, map[string]string{"request-id": "req", "reply-to": "api-gateway-reply.1", "deadline": "1730100873000"}, letter.Headers)
+	assert.False(t, letter.FailedAt.IsZero())
+
+	err = q.Redrive(letter)
+	assert.NoError(t, err)
+	assert.Len(t, sender.messages, 2)
+
+	redriven := consumedMessage(sender.messages[1], 0, 0)
+	assert.Equal(t, "news-response", redriven.Topic)
+	assert.Equal(t, []byte(`{"id":"req"}`), redriven.Value)
+
+	headers := make(map[string]string)
+	for _, h := range redriven.Headers {
+		headers[string(h.Key)] = string(h.Value)
+	}
+	assert.Equal(t, "req", headers["request-id"])
+	assert.Equal(t, "dlq/0/5", headers[HeaderDLQRedrivenFrom])
+	assert.NotContains(t, headers, HeaderDeadline)
+	assert.NotContains(t, headers, HeaderReplyTo)
+
+	// Дедлайн и топик ответа из тела сообщения предыдущих версий тоже не используются
+	md := MetadataFromMessage(redriven).WithDefaults(Metadata{ReplyTo: "api-gateway-reply.1", Deadline: 1730100873000})
+	assert.True(t, md.Redriven)
+	assert.Empty(t, md.ReplyTo)
+	assert.False(t, Expired(md.Deadline))
+}
+
+func TestDeadLetterQueue_RunCommandUsage(t *testing.T) {
+	q := NewDeadLetterQueue(nil, "dlq", &testSender{})
+	var out bytes.Buffer
+
+	assert.Error(t, q.RunCommand(nil, &out))
+	assert.Error(t, q.RunCommand([]string{"redrive"}, &out))
+	assert.Error(t, q.RunCommand([]string{"unknown"}, &out))
+}
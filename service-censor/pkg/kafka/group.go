@@ -0,0 +1,176 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"news-kafka/service-censor/pkg/logger"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// ErrAbandoned - обработка сообщения прервана при остановке сервиса по истечении
+// времени на завершение. Смещение такого сообщения не фиксируется, поэтому после
+// перезапуска оно будет обработано снова.
+var ErrAbandoned = errors.New("message processing abandoned on shutdown")
+
+// MessageHandler - обработчик одного сообщения Kafka
+type MessageHandler = func(ctx context.Context, msg *sarama.ConsumerMessage) error
+
+type ConsumerGroupInterface interface {
+	Consume(ctx context.Context, topics []string, handler MessageHandler) error
+	Close() error
+}
+
+// GroupConsumer - потребитель Kafka в составе группы.
+// Партиции топиков распределяются между экземплярами сервиса, а смещение
+// фиксируется только после обработки сообщения, поэтому после перезапуска
+// чтение продолжается с места остановки.
+type GroupConsumer struct {
+	group sarama.ConsumerGroup
+	grace time.Duration
+	logs  *slog.Logger
+}
+
+// NewGroupConsumer - создание нового экземпляра GroupConsumer.
+// grace - время на завершение обработки текущих сообщений после остановки потребления.
+func NewGroupConsumer(brokers []string, groupID string, grace time.Duration, logs *slog.Logger) (*GroupConsumer, error) {
+	config := sarama.NewConfig()
+	config.Consumer.Return.Errors = true
+	config.Consumer.Offsets.Initial = sarama.OffsetNewest
+	config.Consumer.Offsets.AutoCommit.Enable = false
+
+	group, err := sarama.NewConsumerGroup(brokers, groupID, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create consumer group: %w", err)
+	}
+
+	c := &GroupConsumer{
+		group: group,
+		grace: grace,
+		logs:  logs,
+	}
+	go c.forwardErrors()
+
+	return c, nil
+}
+
+// Consume - потребление сообщений из топиков до отмены контекста.
+// После перебалансировки группы чтение возобновляется автоматически.
+// Отмена контекста останавливает чтение новых сообщений, а обработка текущих
+// продолжается не дольше grace, после чего ее контекст отменяется.
+// Consume возвращается после завершения обработки всех текущих сообщений.
+func (c *GroupConsumer) Consume(ctx context.Context, topics []string, handler MessageHandler) error {
+	h := &groupHandler{handler: handler, grace: c.grace, logs: c.logs}
+	for {
+		err := c.group.Consume(ctx, topics, h)
+		if errors.Is(err, sarama.ErrClosedConsumerGroup) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to consume group: %w", err)
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+// Close - закрытие GroupConsumer
+func (c *GroupConsumer) Close() error {
+	return c.group.Close()
+}
+
+// forwardErrors - запись ошибок группы в лог
+func (c *GroupConsumer) forwardErrors() {
+	for err := range c.group.Errors() {
+		if c.logs != nil {
+			c.logs.Error("consumer group error", logger.Err(err))
+		}
+	}
+}
+
+// groupHandler - реализация sarama.ConsumerGroupHandler
+type groupHandler struct {
+	handler MessageHandler
+	grace   time.Duration
+	logs    *slog.Logger
+}
+
+func (h *groupHandler) Setup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+func (h *groupHandler) Cleanup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+// ConsumeClaim - обработка сообщений одной партиции с фиксацией смещения.
+// Ошибка обработчика означает, что сообщение не обработано и не сохранено в dead-letter
+// топике: смещение не фиксируется, а ConsumeClaim завершает сессию группы, после чего
+// чтение возобновляется с этого сообщения.
+func (h *groupHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	for {
+		select {
+		case msg, ok := <-claim.Messages():
+			if !ok {
+				return nil
+			}
+
+			ctx, cancel := drainContext(session.Context(), h.grace)
+			err := h.handler(ctx, msg)
+			abandoned := ctx.Err() != nil
+			cancel()
+
+			// Прерванное при остановке сообщение не фиксируется и будет прочитано снова
+			if abandoned {
+				h.report(slog.LevelWarn, msg, ErrAbandoned)
+				return nil
+			}
+			if err != nil {
+				err = fmt.Errorf("failed to process message: %w", err)
+				h.report(slog.LevelError, msg, err)
+				return err
+			}
+
+			session.MarkMessage(msg, "")
+			session.Commit()
+
+		case <-session.Context().Done():
+			return nil
+		}
+	}
+}
+
+// report - запись ошибки обработки сообщения в лог, если он задан
+func (h *groupHandler) report(level slog.Level, msg *sarama.ConsumerMessage, err error) {
+	if h.logs == nil {
+		return
+	}
+	h.logs.LogAttrs(context.Background(), level, err.Error(),
+		logger.Topic(msg.Topic),
+		slog.Int("partition", int(msg.Partition)),
+		slog.Int64("offset", msg.Offset),
+	)
+}
+
+// drainContext - контекст обработки сообщения. Отмена parent (остановка потребления)
+// не прерывает обработку сразу: контекст отменяется только через grace после нее.
+func drainContext(parent context.Context, grace time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.WithoutCancel(parent))
+	stop := context.AfterFunc(parent, func() {
+		timer := time.NewTimer(grace)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			cancel()
+		case <-ctx.Done():
+		}
+	})
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
@@ -0,0 +1,58 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestMetadata_Headers(t *testing.T) {
+	md := Metadata{
+		RequestID:     "req",
+		ReplyTo:       "reply",
+		Deadline:      1700000003000,
+		Traceparent:   "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+		SchemaVersion: 2,
+		ContentType:   "application/json",
+		Source:        "api-gateway",
+	}
+
+	headers := md.headers()
+	msg := &sarama.ConsumerMessage{}
+	for i := range headers {
+		msg.Headers = append(msg.Headers, &headers[i])
+	}
+
+	assert.Equal(t, md, MetadataFromMessage(msg))
+}
+
+func TestMetadata_EmptyFieldsNotSent(t *testing.T) {
+	headers := Metadata{RequestID: "req"}.headers()
+	assert.Len(t, headers, 1)
+}
+
+func TestMetadata_WithDefaults(t *testing.T) {
+	md := Metadata{RequestID: "header"}.WithDefaults(Metadata{RequestID: "body", ReplyTo: "reply"})
+	assert.Equal(t, Metadata{RequestID: "header", ReplyTo: "reply"}, md)
+}
+
+func TestMetadata_Context(t *testing.T) {
+	assert.Equal(t, Metadata{}, MetadataFromContext(context.Background()))
+
+	ctx := ContextWithMetadata(context.Background(), Metadata{RequestID: "req"})
+	assert.Equal(t, "req", MetadataFromContext(ctx).RequestID)
+}
+
+func TestContextWithTrace(t *testing.T) {
+	parent := "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+	ctx := ContextWithTrace(context.Background(), Metadata{Traceparent: parent})
+
+	assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", trace.SpanContextFromContext(ctx).TraceID().String())
+	assert.Equal(t, parent, traceparent(ctx))
+
+	// Некорректный traceparent не передается дальше
+	assert.Empty(t, traceparent(ContextWithTrace(context.Background(), Metadata{Traceparent: "invalid"})))
+}
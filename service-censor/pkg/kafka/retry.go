@@ -0,0 +1,179 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"news-kafka/service-censor/pkg/logger"
+	"news-kafka/service-censor/pkg/metrics"
+	"news-kafka/service-censor/pkg/tracing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RetryPolicy - политика повторной обработки сообщений
+type RetryPolicy struct {
+	MaxAttempts      int     `json:"max_attempts"`       //Количество попыток обработки
+	InitialBackoffMs int     `json:"initial_backoff_ms"` //Пауза перед второй попыткой
+	MaxBackoffMs     int     `json:"max_backoff_ms"`     //Максимальная пауза между попытками
+	Multiplier       float64 `json:"multiplier"`         //Множитель паузы для следующей попытки
+}
+
+// DefaultRetryPolicy - политика по умолчанию
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:      3,
+	InitialBackoffMs: 100,
+	MaxBackoffMs:     1000,
+	Multiplier:       2,
+}
+
+// Backoff - пауза перед попыткой с номером attempt (нумерация с 1)
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	if attempt <= 1 {
+		return 0
+	}
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+
+	backoff := float64(p.InitialBackoffMs)
+	for i := 2; i < attempt; i++ {
+		backoff *= multiplier
+	}
+	if p.MaxBackoffMs > 0 && backoff > float64(p.MaxBackoffMs) {
+		backoff = float64(p.MaxBackoffMs)
+	}
+	return time.Duration(backoff) * time.Millisecond
+}
+
+// permanentError - ошибка, повторять обработку после которой бессмысленно
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent - помечает ошибку как постоянную: сообщение сразу уходит в dead-letter топик
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent - проверка, является ли ошибка постоянной
+func IsPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
+
+// Pipeline - обработка сообщений с повторами и отправкой в dead-letter топик
+type Pipeline struct {
+	Policy      RetryPolicy
+	DeadLetters *DeadLetterQueue
+	// OnFailure вызывается, когда сообщение не удалось обработать,
+	// например, чтобы сразу сообщить об ошибке отправителю запроса
+	OnFailure func(ctx context.Context, msg *sarama.ConsumerMessage, err error)
+	Logs      *slog.Logger
+}
+
+// Handler - оборачивает обработчик повторами с экспоненциальной паузой.
+// Если все попытки неудачны, сообщение публикуется в dead-letter топик,
+// а ошибка возвращается только если не удалось и это.
+// Метаданные из заголовков сообщения передаются обработчику в контексте,
+// обработка со всеми попытками выполняется в спане, продолжающем трассировку отправителя.
+// Записи лога, сделанные с контекстом обработчика, содержат request_id и topic.
+func (p *Pipeline) Handler(handler MessageHandler) MessageHandler {
+	return func(ctx context.Context, msg *sarama.ConsumerMessage) error {
+		md := MetadataFromMessage(msg)
+		ctx = ContextWithMetadata(ContextWithTrace(ctx, md), md)
+		ctx = logger.ContextWith(ctx, logger.RequestID(md.RequestID), logger.Topic(msg.Topic))
+		ctx, span := startSpan(ctx, "process", trace.SpanKindConsumer, msg.Topic,
+			attribute.Int("messaging.kafka.destination.partition", int(msg.Partition)),
+			attribute.Int64("messaging.kafka.message.offset", msg.Offset),
+			attribute.String("request_id", md.RequestID),
+		)
+
+		start := time.Now()
+		attempts, err := p.process(ctx, handler, msg)
+		latency := time.Since(start)
+		defer tracing.End(span, err)
+		metrics.KafkaProcessing.WithLabelValues(msg.Topic).Observe(latency.Seconds())
+		metrics.KafkaConsumed.WithLabelValues(msg.Topic, metrics.Result(err)).Inc()
+		if err == nil {
+			p.log(ctx, slog.LevelDebug, "message processed", logger.Latency(latency), slog.Int("attempts", attempts))
+			return nil
+		}
+
+		// Обработка прервана остановкой сервиса: сообщение не считается ошибочным,
+		// не отправляется в dead-letter топик и будет обработано после перезапуска
+		if ctx.Err() != nil {
+			return fmt.Errorf("%w: %v", ErrAbandoned, err)
+		}
+
+		p.log(ctx, slog.LevelError, "failed to process message",
+			logger.Latency(latency),
+			slog.Int("attempts", attempts),
+			slog.Int("partition", int(msg.Partition)),
+			slog.Int64("offset", msg.Offset),
+			logger.Err(err),
+		)
+
+		if p.OnFailure != nil {
+			p.OnFailure(ctx, msg, err)
+		}
+
+		if p.DeadLetters == nil {
+			return err
+		}
+		if dlqErr := p.DeadLetters.Publish(ctx, msg, err, attempts); dlqErr != nil {
+			return fmt.Errorf("%v: %w", err, dlqErr)
+		}
+		return nil
+	}
+}
+
+// process - попытки обработки сообщения согласно политике
+func (p *Pipeline) process(ctx context.Context, handler MessageHandler, msg *sarama.ConsumerMessage) (int, error) {
+	maxAttempts := p.Policy.MaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	var err error
+	attempt := 1
+	for ; ; attempt++ {
+		err = handler(ctx, msg)
+		if err == nil || IsPermanent(err) || attempt >= maxAttempts {
+			return attempt, err
+		}
+
+		p.log(ctx, slog.LevelWarn, "message processing attempt failed", slog.Int("attempt", attempt), logger.Err(err))
+
+		timer := time.NewTimer(p.Policy.Backoff(attempt + 1))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return attempt, errors.Join(err, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// log - запись в лог, если он задан
+func (p *Pipeline) log(ctx context.Context, level slog.Level, msg string, attrs ...slog.Attr) {
+	if p.Logs != nil {
+		p.Logs.LogAttrs(ctx, level, msg, attrs...)
+	}
+}
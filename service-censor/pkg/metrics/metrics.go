@@ -0,0 +1,81 @@
+package metrics
+
+import (
+	"net/http"
+	"os"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Адрес HTTP-сервера метрик
+const (
+	EnvAddr     = "METRICSADDR" //Переменная окружения с адресом
+	DefaultAddr = ":9100"       //Адрес по умолчанию
+)
+
+// Метрики service-censor в формате Prometheus.
+// Реестр свой у каждого сервиса, поэтому сервисы можно запускать в одном процессе (e2e).
+var (
+	// Registry - реестр метрик сервиса вместе с метриками процесса и Go
+	Registry = newRegistry()
+	factory  = promauto.With(Registry)
+
+	// KafkaConsumed - количество обработанных сообщений Kafka по топику и результату
+	KafkaConsumed = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "kafka_messages_consumed_total",
+		Help: "Messages consumed from Kafka by topic and result.",
+	}, []string{"topic", "result"})
+
+	// KafkaProcessing - время обработки сообщений Kafka со всеми попытками по топику
+	KafkaProcessing = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "kafka_message_processing_seconds",
+		Help:    "Kafka message processing latency by topic, including retries.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"topic"})
+
+	// KafkaProduced - количество отправленных в Kafka сообщений по топику и результату
+	KafkaProduced = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "kafka_messages_produced_total",
+		Help: "Messages produced to Kafka by topic and result.",
+	}, []string{"topic", "result"})
+
+	// CensorRejections - количество отклоненных комментариев по полю с запрещенными словами
+	CensorRejections = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "censor_rejections_total",
+		Help: "Comments rejected by the censor by field.",
+	}, []string{"field"})
+)
+
+// Addr - адрес HTTP-сервера метрик из переменной окружения METRICSADDR
+func Addr() string {
+	if addr := os.Getenv(EnvAddr); addr != "" {
+		return addr
+	}
+	return DefaultAddr
+}
+
+// NewServer - HTTP-сервер, отдающий только /metrics.
+// Запускается через ListenAndServe и останавливается через Shutdown.
+func NewServer(addr string) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(Registry, promhttp.HandlerOpts{}))
+	return &http.Server{Addr: addr, Handler: mux}
+}
+
+// newRegistry - реестр со стандартными метриками процесса и Go
+func newRegistry() *prometheus.Registry {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	return registry
+}
+
+// Result - значение метки result по ошибке операции
+func Result(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}
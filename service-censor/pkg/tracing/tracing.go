@@ -0,0 +1,82 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Переменные окружения с настройками экспорта трассировки
+const (
+	EnvExporter = "OTEL_TRACES_EXPORTER" //otlp, stdout, file или none
+	EnvFile     = "OTEL_TRACES_FILE"     //Файл для экспорта file, по умолчанию traces.json
+)
+
+// Init - настройка глобального TracerProvider и W3C Trace Context.
+// otlp отправляет трассировку по OTLP/HTTP на OTEL_EXPORTER_OTLP_ENDPOINT,
+// stdout и file пишут спаны в JSON, без настройки трассировка отключена.
+// Возвращает функцию, которая выгружает оставшиеся спаны при остановке.
+func Init(ctx context.Context, serviceName string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch os.Getenv(EnvExporter) {
+	case "", "none":
+		return func(context.Context) error { return nil }, nil
+	case "otlp":
+		exporter, err = otlptracehttp.New(ctx)
+	case "stdout":
+		exporter, err = stdouttrace.New()
+	case "file":
+		exporter, err = fileExporter(os.Getenv(EnvFile))
+	default:
+		return nil, fmt.Errorf("unsupported %v: %v", EnvExporter, os.Getenv(EnvExporter))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", serviceName))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// fileExporter - экспорт спанов в файл, по одному JSON на строку
+func fileExporter(path string) (sdktrace.SpanExporter, error) {
+	if path == "" {
+		path = "traces.json"
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return stdouttrace.New(stdouttrace.WithWriter(file))
+}
+
+// Tracer - трассировщик сервиса из глобального TracerProvider
+func Tracer() trace.Tracer {
+	return otel.Tracer("news-kafka/service-censor")
+}
+
+// End - завершение спана с отметкой ошибки
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -2,9 +2,7 @@
 --1) create tables
 --++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
 
-DROP TABLE IF EXISTS comments;
-
-CREATE TABLE comments (
+CREATE TABLE IF NOT EXISTS comments (
     id BIGSERIAL PRIMARY KEY,
     id_news BIGSERIAL,
     comment_time INTEGER DEFAULT 0,
@@ -12,12 +10,39 @@ CREATE TABLE comments (
     content TEXT NOT NULL
 );
 
--- Заполнение таблицы данными
-INSERT INTO comments (id_news, comment_time,user_name,content) VALUES
-(1, 1730100873, 'user_name_001', 'content_001'),
-(1, 1730100874, 'user_name_002', 'content_002'),
-(1, 1730100875, 'user_name_003', 'content_003'),
-(2, 1730100876, 'user_name_004', 'content_004'),
-(2, 1730100877, 'user_name_005', 'content_005'),
-(2, 1730100878, 'user_name_006', 'content_006'),
-(3, 1730100879, 'user_name_007', 'content_007');
+--++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
+--2) migrations: повторный запуск не меняет существующую таблицу,
+--   те же команды service-comments выполняет при запуске
+--++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
+
+ALTER TABLE comments
+    ADD COLUMN IF NOT EXISTS edited_at INTEGER NOT NULL DEFAULT 0,     -- время последнего изменения, 0 - не изменялся
+    ADD COLUMN IF NOT EXISTS deleted_at INTEGER,                       -- время удаления, NULL - не удален
+    ADD COLUMN IF NOT EXISTS parent_id BIGINT REFERENCES comments(id), -- комментарий, на который дан ответ, NULL - комментарий к статье
+    ADD COLUMN IF NOT EXISTS depth INTEGER NOT NULL DEFAULT 0;         -- уровень вложенности: 0 - комментарий к статье
+
+CREATE INDEX IF NOT EXISTS comments_id_news_idx ON comments (id_news);
+
+--++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
+--3) test data: только в пустую таблицу
+--++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM comments) THEN
+        -- Заполнение таблицы данными
+        INSERT INTO comments (id_news, comment_time,user_name,content) VALUES
+        (1, 1730100873, 'user_name_001', 'content_001'),
+        (1, 1730100874, 'user_name_002', 'content_002'),
+        (1, 1730100875, 'user_name_003', 'content_003'),
+        (2, 1730100876, 'user_name_004', 'content_004'),
+        (2, 1730100877, 'user_name_005', 'content_005'),
+        (2, 1730100878, 'user_name_006', 'content_006'),
+        (3, 1730100879, 'user_name_007', 'content_007');
+
+        -- Ответы на комментарии
+        INSERT INTO comments (id_news, comment_time,user_name,content,parent_id,depth) VALUES
+        (1, 1730100880, 'user_name_002', 'reply_001', 1, 1),
+        (1, 1730100881, 'user_name_001', 'reply_002', 8, 2);
+    END IF;
+END $$;
@@ -2,19 +2,22 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
+	"net/http"
+	"news-kafka/service-comments/pkg/handler"
 	"news-kafka/service-comments/pkg/kafka"
 	"news-kafka/service-comments/pkg/logger"
+	"news-kafka/service-comments/pkg/metrics"
 	"news-kafka/service-comments/pkg/storage"
 	"news-kafka/service-comments/pkg/storage/postgres"
+	"news-kafka/service-comments/pkg/tracing"
 	"os"
-	"sync"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"fmt"
 	"log"
-
-	"github.com/IBM/sarama"
 )
 
 // Сервер
@@ -22,28 +25,6 @@ type server struct {
 	db storage.Interface
 }
 
-// Cтруктура для передачи данных в api-gateway
-type SendMessServiceComments struct {
-	ID        string            `json:"id"`
-	Name      string            `json:"name"`
-	Status    int               `json:"status"`
-	TypeQuery string            `json:"type_query"`
-	IdNews    int               `json:"id_news"`
-	Comments  []storage.Comment `json:"comments"`
-}
-
-// Cтруктура для получения данных от api-gateway
-type GetMessServiceComments struct {
-	ID          string `json:"id"`
-	Name        string `json:"name"`
-	Status      int    `json:"status"`
-	TypeQuery   string `json:"type_query"`
-	IdNews      int    `json:"id_news"`
-	CommentTime int64  `json:"comment_time"`
-	UserName    string `json:"user_name"`
-	Content     string `json:"content"`
-}
-
 func main() {
 
 	fmt.Println("service-comments:", logger.GetServiceName())
@@ -55,11 +36,24 @@ func main() {
 	//==============================================
 	//Logger
 	//==============================================
-	logs, err := logger.NewLogger("logs.json", 50)
+	// Записи в формате JSON Lines, уровень в переменной окружения LOGLEVEL.
+	// Буфер записывается каждые 5 с, файл ротируется по размеру и по дням
+	sink, err := logger.NewLoggerWithOptions("logs.json", logger.DefaultOptions)
+	if err != nil {
+		log.Fatalf("Error creating logger: %v", err)
+	}
+	defer sink.Close()
+	logs := logger.New(sink, logger.LevelFromEnv())
+
+	//==============================================
+	//Tracing
+	//==============================================
+	// Экспорт трассировки настраивается переменной окружения OTEL_TRACES_EXPORTER
+	shutdownTracing, err := tracing.Init(context.Background(), "service-comments")
 	if err != nil {
-		fmt.Printf("Error creating logger: %v", err)
+		log.Fatalf("Failed to init tracing: %v", err)
 	}
-	defer logs.Close()
+	defer shutdownTracing(context.Background())
 
 	//==============================================
 	//Kafka
@@ -77,17 +71,36 @@ func main() {
 	}
 	defer kafkaProducer.Close()
 
-	kafkaConsumer, err := kafka.NewConsumer(config.KafkaBrokers)
-	if err != nil {
-		log.Fatalf("Failed to create Kafka consumer: %v", err)
+	// Записи лога также публикуются в топик логов, из которого их забирает service-logs
+	if config.TopicLogs != "" {
+		logPublisher := kafka.NewLogPublisher(kafkaProducer, config.TopicLogs)
+		sink.SetPublisher(logPublisher)
+		defer func() {
+			// Записи, сделанные до остановки, отправляются до закрытия producer
+			sink.Flush()
+			sink.SetPublisher(nil)
+			logPublisher.Close()
+		}()
+	}
+
+	deadLetters := kafka.NewDeadLetterQueue(config.KafkaBrokers, config.TopicDeadLetter, kafkaProducer)
+
+	// Просмотр и повторная отправка сообщений из dead-letter топика:
+	// service-comments dlq list [limit] | dlq redrive all | dlq redrive partition:offset ...
+	if len(os.Args) > 1 && os.Args[1] == "dlq" {
+		if err := deadLetters.RunCommand(os.Args[2:], os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
 	}
-	defer kafkaConsumer.Close()
 
-	// канал для потребления сообщений
-	responseCh, err := kafkaConsumer.Consume(config.TopicResponse, 0, sarama.OffsetNewest)
+	// Потребитель в составе группы: смещения фиксируются после обработки,
+	// поэтому запросы, пришедшие во время перезапуска, не теряются
+	kafkaConsumer, err := kafka.NewGroupConsumer(config.KafkaBrokers, config.ConsumerGroup, config.ShutdownGrace(), logs)
 	if err != nil {
-		log.Fatalf("Failed to consume partition: %v", err)
+		log.Fatalf("Failed to create Kafka consumer: %v", err)
 	}
+	defer kafkaConsumer.Close()
 
 	//==============================================
 	//PostgreSQL
@@ -104,116 +117,52 @@ func main() {
 	srv.db = db_pg
 	defer srv.db.Close()
 
-	errorChannel := make(chan error)
-
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel() // cancel when we are finished consuming integers
-
-	var wg sync.WaitGroup
-	wg.Add(2)
+	// Остановка по SIGINT/SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
 	// обрабатываем данные полученные из kafak
-	go readNewsFromDB(ctx, srv.db, kafkaProducer, config, responseCh, errorChannel)
-	// выводим ошибки
-	go handleErrors(ctx, errorChannel, logs)
-
-	wg.Wait()
-	//select {}
-}
-
-func readNewsFromDB(ctx context.Context, db storage.Interface, producer *kafka.Producer, config *kafka.Config, responseCh <-chan *sarama.ConsumerMessage, errs chan<- error) {
-	for msg := range responseCh {
-		select {
-		case <-ctx.Done():
-			return
-		default:
-
-			// Обработка входящего сообщения
-			var receivedMessage GetMessServiceComments
-			err := json.Unmarshal(msg.Value, &receivedMessage)
-			if err != nil {
-				errs <- err
-			}
-
-			//пишем запрос данных в лог
-			var errMsg error = receivedMessage
-			errs <- errMsg
-
-			responseMessage := SendMessServiceComments{
-				ID:        receivedMessage.ID,
-				Name:      logger.GetServiceName(),
-				TypeQuery: receivedMessage.TypeQuery,
-				Status:    0,
-				IdNews:    receivedMessage.IdNews,
-				Comments:  nil,
-			}
-
-			switch receivedMessage.TypeQuery {
-			case "CommentsByIdNews":
-				comments, err := db.CommentsByIdNews(receivedMessage.IdNews)
-				if err != nil {
-					errs <- err
-				} else {
-					responseMessage.Status = 192
-					responseMessage.Comments = comments
-				}
-
-				bytesMessage, err := json.Marshal(responseMessage)
-				if err != nil {
-					errs <- err
-				}
-
-				err = producer.SendMessage(config.TopicReceived, responseMessage.ID, bytesMessage)
-				if err != nil {
-					errs <- err
-				}
-			case "CommentNew":
-				comment := storage.Comment{
-					Id:          0,
-					IdNews:      receivedMessage.IdNews,
-					CommentTime: receivedMessage.CommentTime,
-					UserName:    receivedMessage.UserName,
-					Content:     receivedMessage.Content,
-				}
-
-				_, err := db.CommentNew(comment)
-				if err != nil {
-					errs <- err
-				} else {
-					responseMessage.Status = 192
-				}
-
-				bytesMessage, err := json.Marshal(responseMessage)
-				if err != nil {
-					errs <- err
-				}
-
-				err = producer.SendMessage(config.TopicReceivedAddComments, responseMessage.ID, bytesMessage)
-				if err != nil {
-					errs <- err
-				}
-			}
-
-		}
+	// Повторная обработка с паузами, после исчерпания попыток - в dead-letter топик
+	pipeline := kafka.Pipeline{
+		Policy:      config.Retry,
+		DeadLetters: deadLetters,
+		OnFailure:   handler.ReplyFailure(kafkaProducer, config, logs),
+		Logs:        logs,
 	}
-}
-
-func handleErrors(ctx context.Context, errs <-chan error, logs *logger.Logger) {
-	for err := range errs {
-		select {
-		case <-ctx.Done():
-			return
-		default:
-			logs.LogRequest(logger.GetRequestId(), logger.GetLocalIP(), 500, err.Error())
+	consumed := make(chan struct{})
+	go func() {
+		defer close(consumed)
+		err := kafkaConsumer.Consume(ctx, []string{config.TopicResponse}, pipeline.Handler(handler.New(srv.db, kafkaProducer, config, logs)))
+		if err != nil {
+			logs.Error("kafka consumer stopped", logger.Err(err))
 		}
+	}()
+	// метрики Prometheus: http://<host>:9100/metrics (адрес в переменной окружения METRICSADDR)
+	metricsServer := metrics.NewServer(metrics.Addr())
+	go func() {
+		if err := metricsServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logs.Error("metrics server stopped", logger.Err(err))
+		}
+	}()
+
+	// Ожидание SIGINT/SIGTERM
+	<-ctx.Done()
+	stop()
+	logs.Info("shutting down")
+
+	// Чтение Kafka остановлено отменой ctx. Текущие сообщения обрабатываются
+	// не дольше shutdown_grace_ms, затем прерываются без фиксации смещения.
+	select {
+	case <-consumed:
+	case <-time.After(config.ShutdownGrace() + time.Second):
+		logs.Warn("kafka consumer did not stop in time")
 	}
-}
 
-// Метод для реализации интерфейса error
-func (g GetMessServiceComments) Error() string {
-	jsonData, err := json.Marshal(g)
-	if err != nil {
-		return "error convert to JSON"
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), config.ShutdownGrace())
+	defer cancelShutdown()
+	if err := metricsServer.Shutdown(shutdownCtx); err != nil {
+		logs.Warn("failed to shut down metrics server", logger.Err(err))
 	}
-	return string(jsonData)
+
+	// Далее в defer: закрытие пула БД, consumer и producer, запись буфера логгера
 }
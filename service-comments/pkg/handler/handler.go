@@ -0,0 +1,319 @@
+// Package handler - обработка запросов к комментариям, полученных из Kafka
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"news-kafka/contracts"
+	"news-kafka/service-comments/pkg/kafka"
+	"news-kafka/service-comments/pkg/logger"
+	"news-kafka/service-comments/pkg/storage"
+	"strconv"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// New - обработчик запросов к комментариям, полученных из Kafka.
+// Возвращаемая ошибка приводит к повторной обработке сообщения.
+func New(db storage.Interface, producer kafka.ProducerInterface, config *kafka.Config, logs *slog.Logger) kafka.MessageHandler {
+	return func(ctx context.Context, msg *sarama.ConsumerMessage) error {
+		// Обработка входящего сообщения
+		// Ответ кодируется в том же формате, что и запрос
+		codec, err := kafka.MessageCodec(msg)
+		if err != nil {
+			return kafka.Permanent(err)
+		}
+		var receivedMessage contracts.CommentsRequest
+		err = codec.Unmarshal(msg.Value, &receivedMessage)
+		if err != nil {
+			return kafka.Permanent(fmt.Errorf("failed to decode message: %w", err))
+		}
+		md := requestMetadata(ctx, &receivedMessage)
+		ctx = kafka.ContextWithMetadata(ctx, md)
+		ctx = logger.ContextWith(ctx, logger.RequestID(md.RequestID))
+
+		//пишем запрос данных в лог, кроме периодических Ping
+		if receivedMessage.TypeQuery != contracts.TypePing {
+			logMessage(ctx, logs, config.Redactor(), receivedMessage)
+		}
+
+		// Ответ на просроченный запрос api-gateway уже не ждет
+		if kafka.Expired(md.Deadline) {
+			logs.WarnContext(ctx, "deadline exceeded, message skipped", slog.String("type_query", receivedMessage.TypeQuery))
+			return nil
+		}
+		ctx, cancel := kafka.DeadlineContext(ctx, md.Deadline)
+		defer cancel()
+
+		responseMessage := contracts.CommentsReply{
+			ID:        md.RequestID,
+			Name:      logger.GetServiceName(),
+			TypeQuery: receivedMessage.TypeQuery,
+			Reply:     contracts.ReplyOK(),
+			IdNews:    receivedMessage.IdNews,
+			Comments:  nil,
+		}
+
+		switch receivedMessage.TypeQuery {
+		case contracts.TypeCommentsByIdNews:
+			comments, err := db.CommentsByIdNews(ctx, receivedMessage.IdNews)
+			if err != nil {
+				return dbError(ctx, err, logs)
+			}
+			responseMessage.Comments = comments
+
+			return sendReply(ctx, producer, codec, replyTopic(md.ReplyTo, config.TopicReceived), &responseMessage)
+
+		case contracts.TypeCommentNew:
+			if receivedMessage.IdNews <= 0 {
+				responseMessage.Reply = contracts.ReplyFail(contracts.StatusInvalid, "invalid id_news", map[string]string{"id_news": strconv.Itoa(receivedMessage.IdNews)})
+				return sendReply(ctx, producer, codec, replyTopic(md.ReplyTo, config.TopicReceivedAddComments), &responseMessage)
+			}
+
+			comment := contracts.Comment{
+				Id:          0,
+				IdNews:      receivedMessage.IdNews,
+				CommentTime: receivedMessage.CommentTime,
+				UserName:    receivedMessage.UserName,
+				Content:     receivedMessage.Content,
+				ParentId:    receivedMessage.ParentId,
+			}
+
+			// Ответ на комментарий: родитель той же статьи, вложенность не больше max_reply_depth
+			if comment.ParentId != 0 {
+				parent, err := db.CommentById(ctx, comment.ParentId)
+				if err != nil && !errors.Is(err, storage.ErrNotFound) {
+					return dbError(ctx, err, logs)
+				}
+				if reply := parentError(comment, parent, err, config.ReplyDepth()); !reply.IsOK() {
+					responseMessage.Reply = reply
+					return sendReply(ctx, producer, codec, replyTopic(md.ReplyTo, config.TopicReceivedAddComments), &responseMessage)
+				}
+				comment.Depth = parent.Depth + 1
+			}
+
+			comment.Id, err = db.CommentNew(ctx, comment)
+			if err != nil {
+				return dbError(ctx, err, logs)
+			}
+			responseMessage.Comments = []contracts.Comment{comment}
+
+			// Комментарий уже сохранен: повтор обработки создал бы дубликат
+			return sendWriteReply(ctx, producer, codec, replyTopic(md.ReplyTo, config.TopicReceivedAddComments), &responseMessage, config.Retry, logs)
+
+		case contracts.TypeCommentUpdate:
+			if reply := commentRequestError(receivedMessage); !reply.IsOK() {
+				responseMessage.Reply = reply
+				return sendReply(ctx, producer, codec, replyTopic(md.ReplyTo, config.TopicReceivedAddComments), &responseMessage)
+			}
+
+			// Изменять комментарий может только его автор: автор проверяется хранилищем при изменении.
+			// Время изменения назначается сервисом, время создания не меняется
+			comment, err := db.CommentUpdate(ctx, contracts.Comment{
+				Id:       receivedMessage.IdComment,
+				IdNews:   receivedMessage.IdNews,
+				UserName: receivedMessage.UserName,
+				Content:  receivedMessage.Content,
+				EditedAt: time.Now().Unix(),
+			})
+			switch {
+			case errors.Is(err, storage.ErrNotFound):
+				responseMessage.Reply = commentNotFound(receivedMessage)
+			case errors.Is(err, storage.ErrForbidden):
+				responseMessage.Reply = commentForbidden()
+			case err != nil:
+				return dbError(ctx, err, logs)
+			default:
+				responseMessage.Comments = []contracts.Comment{comment}
+			}
+
+			// Повтор изменения безопасен, но время изменения было бы другим
+			return sendWriteReply(ctx, producer, codec, replyTopic(md.ReplyTo, config.TopicReceivedAddComments), &responseMessage, config.Retry, logs)
+
+		case contracts.TypeCommentDelete:
+			if reply := commentRequestError(receivedMessage); !reply.IsOK() {
+				responseMessage.Reply = reply
+				return sendReply(ctx, producer, codec, replyTopic(md.ReplyTo, config.TopicReceivedAddComments), &responseMessage)
+			}
+
+			// Удалять комментарий может только его автор: автор проверяется хранилищем при удалении
+			err := db.CommentDelete(ctx, receivedMessage.IdNews, receivedMessage.IdComment, receivedMessage.UserName)
+			switch {
+			case errors.Is(err, storage.ErrNotFound):
+				responseMessage.Reply = commentNotFound(receivedMessage)
+			case errors.Is(err, storage.ErrForbidden):
+				responseMessage.Reply = commentForbidden()
+			case err != nil:
+				return dbError(ctx, err, logs)
+			}
+
+			// Комментарий уже удален: повтор обработки ответил бы not_found
+			return sendWriteReply(ctx, producer, codec, replyTopic(md.ReplyTo, config.TopicReceivedAddComments), &responseMessage, config.Retry, logs)
+
+		case contracts.TypePing:
+			responseMessage.Reply = ping(ctx, db)
+			return sendReply(ctx, producer, codec, replyTopic(md.ReplyTo, config.TopicReceived), &responseMessage)
+		}
+
+		return kafka.Permanent(fmt.Errorf("unknown type_query: %v", receivedMessage.TypeQuery))
+	}
+}
+
+// parentError - проверка комментария parent, на который отвечает comment; err - ошибка чтения parent
+func parentError(comment, parent contracts.Comment, err error, maxDepth int) contracts.Reply {
+	switch {
+	case comment.ParentId < 0:
+		return contracts.ReplyFail(contracts.StatusInvalid, "invalid parent_id", map[string]string{"parent_id": strconv.Itoa(comment.ParentId)})
+	case errors.Is(err, storage.ErrNotFound):
+		return contracts.ReplyFail(contracts.StatusInvalid, "parent comment not found", map[string]string{"parent_id": "not found"})
+	case parent.IdNews != comment.IdNews:
+		return contracts.ReplyFail(contracts.StatusInvalid, "parent comment belongs to another news", map[string]string{"parent_id": "belongs to news " + strconv.Itoa(parent.IdNews)})
+	case parent.Depth+1 > maxDepth:
+		return contracts.ReplyFail(contracts.StatusInvalid, "reply depth limit exceeded", map[string]string{"parent_id": "replies are limited to depth " + strconv.Itoa(maxDepth)})
+	}
+	return contracts.ReplyOK()
+}
+
+// commentRequestError - проверка идентификаторов статьи, комментария и автора в запросе на изменение или удаление
+func commentRequestError(msg contracts.CommentsRequest) contracts.Reply {
+	switch {
+	case msg.IdNews <= 0:
+		return contracts.ReplyFail(contracts.StatusInvalid, "invalid id_news", map[string]string{"id_news": strconv.Itoa(msg.IdNews)})
+	case msg.IdComment <= 0:
+		return contracts.ReplyFail(contracts.StatusInvalid, "invalid id_comment", map[string]string{"id_comment": strconv.Itoa(msg.IdComment)})
+	case msg.UserName == "":
+		return contracts.ReplyFail(contracts.StatusInvalid, "user_name is required", map[string]string{"user_name": "required"})
+	}
+	return contracts.ReplyOK()
+}
+
+// commentForbidden - ответ, если автор комментария не совпадает с user_name запроса
+func commentForbidden() contracts.Reply {
+	return contracts.ReplyFail(contracts.StatusForbidden, "comment belongs to another user", map[string]string{"user_name": "does not match the comment author"})
+}
+
+// commentNotFound - ответ, если комментария нет, он удален или относится к другой статье
+func commentNotFound(msg contracts.CommentsRequest) contracts.Reply {
+	return contracts.ReplyFail(contracts.StatusNotFound, "comment not found", map[string]string{
+		"id_news":    strconv.Itoa(msg.IdNews),
+		"id_comment": strconv.Itoa(msg.IdComment),
+	})
+}
+
+// ping - состояние сервиса для api-gateway: пул соединений PostgreSQL
+func ping(ctx context.Context, db storage.Interface) contracts.Reply {
+	if err := db.Ping(ctx); err != nil {
+		return contracts.ReplyFail(contracts.StatusInternal, "postgres unavailable", map[string]string{contracts.PingPostgres: err.Error()})
+	}
+	reply := contracts.ReplyOK()
+	reply.Details = map[string]string{contracts.PingPostgres: "ok"}
+	return reply
+}
+
+// dbError - ошибка БД приводит к повторной обработке сообщения,
+// кроме случая, когда истек дедлайн запроса и ответ уже никто не ждет
+func dbError(ctx context.Context, err error, logs *slog.Logger) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		logs.WarnContext(ctx, "deadline exceeded", logger.Err(err))
+		return nil
+	}
+	return err
+}
+
+// ReplyFailure - ответ со статусом internal на запрос, который не удалось обработать,
+// чтобы api-gateway не ждал ответа до таймаута
+func ReplyFailure(producer kafka.ProducerInterface, config *kafka.Config, logs *slog.Logger) func(ctx context.Context, msg *sarama.ConsumerMessage, err error) {
+	return func(ctx context.Context, msg *sarama.ConsumerMessage, err error) {
+		codec, err := kafka.MessageCodec(msg)
+		if err != nil {
+			return
+		}
+		var receivedMessage contracts.CommentsRequest
+		if codec.Unmarshal(msg.Value, &receivedMessage) != nil {
+			return
+		}
+		md := requestMetadata(ctx, &receivedMessage)
+		ctx = kafka.ContextWithMetadata(ctx, md)
+		ctx = logger.ContextWith(ctx, logger.RequestID(md.RequestID))
+
+		responseMessage := contracts.CommentsReply{
+			ID:        md.RequestID,
+			Name:      logger.GetServiceName(),
+			TypeQuery: receivedMessage.TypeQuery,
+			Reply:     contracts.ReplyFail(contracts.StatusInternal, "internal service error", nil),
+			IdNews:    receivedMessage.IdNews,
+		}
+
+		topic := config.TopicReceived
+		switch receivedMessage.TypeQuery {
+		case contracts.TypeCommentNew, contracts.TypeCommentUpdate, contracts.TypeCommentDelete:
+			topic = config.TopicReceivedAddComments
+		}
+
+		if err := sendReply(ctx, producer, codec, replyTopic(md.ReplyTo, topic), &responseMessage); err != nil {
+			logs.ErrorContext(ctx, "failed to send failure reply", logger.Err(err))
+		}
+	}
+}
+
+// sendReply - отправка ответа в Kafka с идентификатором запроса и спаном обработки из контекста
+func sendReply(ctx context.Context, producer kafka.ProducerInterface, codec contracts.Codec, topic string, responseMessage *contracts.CommentsReply) error {
+	md := kafka.MetadataFromContext(ctx)
+	ctx = kafka.ContextWithMetadata(ctx, kafka.Metadata{
+		RequestID: md.RequestID,
+		Source:    logger.GetServiceName(),
+	})
+
+	msg, err := kafka.NewMessage(ctx, codec, topic, responseMessage)
+	if err != nil {
+		return kafka.Permanent(err)
+	}
+	return producer.Send(ctx, msg)
+}
+
+// sendWriteReply - ответ на запрос, изменение по которому уже записано в БД: повторяется только
+// отправка ответа. Если ответ так и не отправлен, сообщение считается обработанным - не попадает
+// в dead-letter топик и не обрабатывается повторно, чтобы не повторить запись и не ответить internal
+// на выполненный запрос; api-gateway в этом случае ответит клиенту timeout.
+func sendWriteReply(ctx context.Context, producer kafka.ProducerInterface, codec contracts.Codec, topic string, responseMessage *contracts.CommentsReply, policy kafka.RetryPolicy, logs *slog.Logger) error {
+	err := kafka.Retry(ctx, policy, func() error {
+		return sendReply(ctx, producer, codec, topic, responseMessage)
+	})
+	if err != nil {
+		logs.ErrorContext(ctx, "failed to send reply, write is already committed", logger.Err(err))
+	}
+	return nil
+}
+
+// requestMetadata - метаданные запроса из заголовков сообщения (контекст Pipeline).
+// Отправители предыдущих версий передают их только в теле сообщения.
+func requestMetadata(ctx context.Context, receivedMessage *contracts.CommentsRequest) kafka.Metadata {
+	return kafka.MetadataFromContext(ctx).WithDefaults(kafka.Metadata{
+		RequestID: receivedMessage.ID,
+		ReplyTo:   receivedMessage.ReplyTo,
+		Deadline:  receivedMessage.Deadline,
+		Source:    receivedMessage.Name,
+	})
+}
+
+// replyTopic - топик для ответа: reply_to из запроса или топик из конфигурации
+func replyTopic(replyTo, defaultTopic string) string {
+	if replyTo != "" {
+		return replyTo
+	}
+	return defaultTopic
+}
+
+// logMessage - запись запроса в лог, тело запроса в формате JSON передается в поле body
+// после скрытия данных по правилам redact
+func logMessage(ctx context.Context, logs *slog.Logger, redact *logger.Redactor, msg interface{}) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		data = []byte(fmt.Sprintf("%+v", msg))
+	}
+	logs.InfoContext(ctx, "request received", redact.Body(data))
+}
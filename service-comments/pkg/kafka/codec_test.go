@@ -0,0 +1,53 @@
+package kafka
+
+import (
+	"context"
+	"news-kafka/contracts"
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// consumerMessage - полученное сообщение из отправленного
+func consumerMessage(t *testing.T, msg *sarama.ProducerMessage) *sarama.ConsumerMessage {
+	t.Helper()
+	value, err := msg.Value.Encode()
+	require.NoError(t, err)
+
+	received := &sarama.ConsumerMessage{Topic: msg.Topic, Value: value}
+	for i := range msg.Headers {
+		received.Headers = append(received.Headers, &msg.Headers[i])
+	}
+	return received
+}
+
+// Получатель декодирует оба формата, пока отправители переходят на Protobuf
+func TestDecode_BothContentTypes(t *testing.T) {
+	for _, codec := range []contracts.Codec{contracts.JSON, contracts.Protobuf} {
+		msg, err := NewMessage(ContextWithMetadata(context.Background(), Metadata{RequestID: "req"}), codec, "topic", &contracts.CommentsRequest{ID: "req", TypeQuery: contracts.TypeCommentNew, Content: "text"})
+		require.NoError(t, err)
+
+		var decoded contracts.CommentsRequest
+		require.NoError(t, Decode(consumerMessage(t, msg), &decoded))
+		assert.Equal(t, "text", decoded.Content)
+		assert.Equal(t, contracts.SchemaVersion, decoded.Version)
+	}
+}
+
+// Сообщения без заголовка content-type закодированы в JSON
+func TestDecode_WithoutContentType(t *testing.T) {
+	var decoded contracts.CommentsRequest
+	err := Decode(&sarama.ConsumerMessage{Value: []byte(`{"id":"req","content":"text"}`)}, &decoded)
+	require.NoError(t, err)
+	assert.Equal(t, "text", decoded.Content)
+	assert.Equal(t, contracts.SchemaV1, decoded.Version)
+}
+
+func TestDecode_UnsupportedContentType(t *testing.T) {
+	msg := &sarama.ConsumerMessage{Headers: []*sarama.RecordHeader{{Key: []byte(HeaderContentType), Value: []byte("text/xml")}}}
+
+	var decoded contracts.CommentsRequest
+	assert.Error(t, Decode(msg, &decoded))
+}
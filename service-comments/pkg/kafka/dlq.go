@@ -0,0 +1,285 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// Заголовки, которые добавляются к сообщению в dead-letter топике
+const (
+	HeaderDLQOriginalTopic     = "dlq-original-topic"
+	HeaderDLQOriginalPartition = "dlq-original-partition"
+	HeaderDLQOriginalOffset    = "dlq-original-offset"
+	HeaderDLQError             = "dlq-error"
+	HeaderDLQAttempts          = "dlq-attempts"
+	HeaderDLQFailedAt          = "dlq-failed-at"
+	HeaderDLQRedrivenFrom      = "dlq-redriven-from"
+)
+
+// messageSender - отправка подготовленного сообщения в Kafka
+type messageSender interface {
+	Send(ctx context.Context, msg *sarama.ProducerMessage) error
+}
+
+// DeadLetter - сообщение из dead-letter топика
+type DeadLetter struct {
+	Partition         int32             `json:"partition"`          //Партиция в dead-letter топике
+	Offset            int64             `json:"offset"`             //Смещение в dead-letter топике
+	OriginalTopic     string            `json:"original_topic"`     //Топик, из которого пришло сообщение
+	OriginalPartition int32             `json:"original_partition"` //Исходная партиция
+	OriginalOffset    int64             `json:"original_offset"`    //Исходное смещение
+	Key               string            `json:"key"`
+	Value             string            `json:"value"`
+	Error             string            `json:"error"`     //Ошибка последней попытки
+	Attempts          int               `json:"attempts"`  //Количество попыток обработки
+	FailedAt          time.Time         `json:"failed_at"` //Время отправки в dead-letter топик
+	Headers           map[string]string `json:"headers"`   //Исходные заголовки сообщения
+}
+
+// DeadLetterQueue - dead-letter топик для сообщений, которые не удалось обработать
+type DeadLetterQueue struct {
+	brokers  []string
+	topic    string
+	producer messageSender
+}
+
+// NewDeadLetterQueue - создание нового экземпляра DeadLetterQueue
+func NewDeadLetterQueue(brokers []string, topic string, producer messageSender) *DeadLetterQueue {
+	return &DeadLetterQueue{
+		brokers:  brokers,
+		topic:    topic,
+		producer: producer,
+	}
+}
+
+// Publish - отправка сообщения в dead-letter топик вместе с ошибкой и исходными заголовками
+func (q *DeadLetterQueue) Publish(ctx context.Context, msg *sarama.ConsumerMessage, cause error, attempts int) error {
+	headers := make([]sarama.RecordHeader, 0, len(msg.Headers)+6)
+	for _, h := range msg.Headers {
+		if h != nil {
+			headers = append(headers, *h)
+		}
+	}
+	headers = append(headers,
+		stringHeader(HeaderDLQOriginalTopic, msg.Topic),
+		stringHeader(HeaderDLQOriginalPartition, strconv.Itoa(int(msg.Partition))),
+		stringHeader(HeaderDLQOriginalOffset, strconv.FormatInt(msg.Offset, 10)),
+		stringHeader(HeaderDLQError, cause.Error()),
+		stringHeader(HeaderDLQAttempts, strconv.Itoa(attempts)),
+		stringHeader(HeaderDLQFailedAt, time.Now().UTC().Format(time.RFC3339Nano)),
+	)
+
+	err := q.producer.Send(ctx, &sarama.ProducerMessage{
+		Topic:   q.topic,
+		Key:     sarama.ByteEncoder(msg.Key),
+		Value:   sarama.ByteEncoder(msg.Value),
+		Headers: headers,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to publish to dead-letter topic: %w", err)
+	}
+	return nil
+}
+
+// List - чтение сообщений dead-letter топика, limit <= 0 - без ограничения
+func (q *DeadLetterQueue) List(limit int) ([]DeadLetter, error) {
+	client, err := sarama.NewClient(q.brokers, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client: %w", err)
+	}
+	defer client.Close()
+
+	consumer, err := sarama.NewConsumerFromClient(client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create consumer: %w", err)
+	}
+	defer consumer.Close()
+
+	partitions, err := client.Partitions(q.topic)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get partitions: %w", err)
+	}
+
+	var letters []DeadLetter
+	for _, partition := range partitions {
+		oldest, err := client.GetOffset(q.topic, partition, sarama.OffsetOldest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get offset: %w", err)
+		}
+		newest, err := client.GetOffset(q.topic, partition, sarama.OffsetNewest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get offset: %w", err)
+		}
+		if oldest >= newest {
+			continue
+		}
+
+		partConsumer, err := consumer.ConsumePartition(q.topic, partition, oldest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to consume partition: %w", err)
+		}
+
+		for done := false; !done; {
+			select {
+			case msg := <-partConsumer.Messages():
+				letters = append(letters, deadLetterFromMessage(msg))
+				done = msg.Offset >= newest-1 || (limit > 0 && len(letters) >= limit)
+			case <-time.After(5 * time.Second):
+				partConsumer.Close()
+				return nil, fmt.Errorf("timeout reading partition %v", partition)
+			}
+		}
+		partConsumer.Close()
+
+		if limit > 0 && len(letters) >= limit {
+			break
+		}
+	}
+
+	return letters, nil
+}
+
+// Redrive - повторная отправка сообщения в исходный топик с исходными заголовками,
+// кроме дедлайна и топика ответа: исходный запрос уже завершен по таймауту,
+// а без дедлайна сообщение не будет пропущено как просроченное
+func (q *DeadLetterQueue) Redrive(letter DeadLetter) error {
+	headers := make([]sarama.RecordHeader, 0, len(letter.Headers)+1)
+	for key, value := range letter.Headers {
+		switch key {
+		case HeaderDeadline, HeaderReplyTo, HeaderDLQRedrivenFrom:
+			continue
+		}
+		headers = append(headers, stringHeader(key, value))
+	}
+	headers = append(headers, stringHeader(HeaderDLQRedrivenFrom, fmt.Sprintf("%v/%v/%v", q.topic, letter.Partition, letter.Offset)))
+
+	// Повторная отправка продолжает трассировку исходного запроса
+	ctx := ContextWithTrace(context.Background(), Metadata{Traceparent: letter.Headers[HeaderTraceparent]})
+	err := q.producer.Send(ctx, &sarama.ProducerMessage{
+		Topic:   letter.OriginalTopic,
+		Key:     sarama.StringEncoder(letter.Key),
+		Value:   sarama.StringEncoder(letter.Value),
+		Headers: headers,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to redrive message %v/%v: %w", letter.Partition, letter.Offset, err)
+	}
+	return nil
+}
+
+// RunCommand - консольные команды для работы с dead-letter топиком:
+//
+//	list [limit]                  - вывод сообщений в формате JSON lines
+//	redrive all                   - повторная отправка всех сообщений
+//	redrive partition:offset ...  - повторная отправка выбранных сообщений
+func (q *DeadLetterQueue) RunCommand(args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New("usage: dlq list [limit] | dlq redrive all | dlq redrive partition:offset ...")
+	}
+
+	switch args[0] {
+	case "list":
+		limit := 0
+		if len(args) > 1 {
+			var err error
+			limit, err = strconv.Atoi(args[1])
+			if err != nil {
+				return fmt.Errorf("invalid limit: %w", err)
+			}
+		}
+
+		letters, err := q.List(limit)
+		if err != nil {
+			return err
+		}
+		encoder := json.NewEncoder(out)
+		for _, letter := range letters {
+			if err := encoder.Encode(letter); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case "redrive":
+		if len(args) < 2 {
+			return errors.New("usage: dlq redrive all | dlq redrive partition:offset ...")
+		}
+
+		letters, err := q.List(0)
+		if err != nil {
+			return err
+		}
+
+		selected := make(map[string]bool)
+		for _, arg := range args[1:] {
+			selected[arg] = true
+		}
+
+		count := 0
+		for _, letter := range letters {
+			if !selected["all"] && !selected[fmt.Sprintf("%v:%v", letter.Partition, letter.Offset)] {
+				continue
+			}
+			if err := q.Redrive(letter); err != nil {
+				return err
+			}
+			count++
+		}
+		fmt.Fprintf(out, "redriven %v messages\n", count)
+		return nil
+	}
+
+	return fmt.Errorf("unknown dlq command: %v", args[0])
+}
+
+// deadLetterFromMessage - разбор сообщения dead-letter топика
+func deadLetterFromMessage(msg *sarama.ConsumerMessage) DeadLetter {
+	letter := DeadLetter{
+		Partition: msg.Partition,
+		Offset:    msg.Offset,
+		Key:       string(msg.Key),
+		Value:     string(msg.Value),
+		Headers:   make(map[string]string),
+	}
+
+	for _, h := range msg.Headers {
+		if h == nil {
+			continue
+		}
+		key, value := string(h.Key), string(h.Value)
+		switch key {
+		case HeaderDLQOriginalTopic:
+			letter.OriginalTopic = value
+		case HeaderDLQOriginalPartition:
+			partition, _ := strconv.Atoi(value)
+			letter.OriginalPartition = int32(partition)
+		case HeaderDLQOriginalOffset:
+			letter.OriginalOffset, _ = strconv.ParseInt(value, 10, 64)
+		case HeaderDLQError:
+			letter.Error = value
+		case HeaderDLQAttempts:
+			letter.Attempts, _ = strconv.Atoi(value)
+		case HeaderDLQFailedAt:
+			letter.FailedAt, _ = time.Parse(time.RFC3339Nano, value)
+		default:
+			if !strings.HasPrefix(key, "dlq-") {
+				letter.Headers[key] = value
+			}
+		}
+	}
+
+	return letter
+}
+
+// stringHeader - заголовок сообщения Kafka
+func stringHeader(key, value string) sarama.RecordHeader {
+	return sarama.RecordHeader{Key: []byte(key), Value: []byte(value)}
+}
@@ -1,13 +1,25 @@
 package kafka
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"news-kafka/service-comments/pkg/logger"
+	"news-kafka/service-comments/pkg/metrics"
+	"news-kafka/service-comments/pkg/tracing"
+	"time"
 
 	"github.com/IBM/sarama"
+	"go.opentelemetry.io/otel/trace"
 )
 
+type ProducerInterface interface {
+	SendMessage(topic string, key string, value []byte) error
+	Send(ctx context.Context, msg *sarama.ProducerMessage) error
+	Close() error
+}
+
 // Producer - структура для работы с Kafka
 type Producer struct {
 	producer sarama.SyncProducer
@@ -39,6 +51,23 @@ func (p *Producer) SendMessage(topic string, key string, value []byte) error {
 	return nil
 }
 
+// Send - отправка подготовленного сообщения в Kafka, например, с заголовками.
+// Спан отправки передается получателю в заголовке traceparent.
+func (p *Producer) Send(ctx context.Context, msg *sarama.ProducerMessage) error {
+	ctx, span := startSpan(ctx, "publish", trace.SpanKindProducer, msg.Topic)
+	if tp := traceparent(ctx); tp != "" {
+		setHeader(msg, HeaderTraceparent, tp)
+	}
+
+	_, _, err := p.producer.SendMessage(msg)
+	if err != nil {
+		err = fmt.Errorf("failed to send message: %w", err)
+	}
+	metrics.KafkaProduced.WithLabelValues(msg.Topic, metrics.Result(err)).Inc()
+	tracing.End(span, err)
+	return err
+}
+
 // Close - закрытие Producer
 func (p *Producer) Close() error {
 	return p.producer.Close()
@@ -76,10 +105,41 @@ func (c *Consumer) Close() error {
 
 // Config - структура для хранения конфигурации
 type Config struct {
-	KafkaBrokers             []string `json:"kafka_brokers"`
-	TopicResponse            string   `json:"topic_response"`
-	TopicReceived            string   `json:"topic_received"`
-	TopicReceivedAddComments string   `json:"topic_received_add_comments"`
+	KafkaBrokers             []string            `json:"kafka_brokers"`
+	TopicResponse            string              `json:"topic_response"`
+	TopicReceived            string              `json:"topic_received"`
+	TopicReceivedAddComments string              `json:"topic_received_add_comments"`
+	ConsumerGroup            string              `json:"consumer_group"`
+	TopicDeadLetter          string              `json:"topic_dead_letter"`
+	Retry                    RetryPolicy         `json:"retry"`
+	ShutdownGraceMs          int                 `json:"shutdown_grace_ms"` //Время на завершение обработки сообщений при остановке
+	TopicLogs                string              `json:"topic_logs"`        //Топик записей лога для service-logs, пустой - только logs.json
+	Redact                   *logger.RedactRules `json:"redact"`            //Скрытие данных в логе запросов, по умолчанию logger.DefaultRedactRules
+	MaxReplyDepth            int                 `json:"max_reply_depth"`   //Наибольший уровень вложенности ответов на комментарии
+
+	redactor *logger.Redactor
+}
+
+// Время на завершение обработки сообщений при остановке, если оно не задано в конфигурации
+const defaultShutdownGrace = 10 * time.Second
+
+// ShutdownGrace - время на завершение обработки текущих сообщений при остановке сервиса
+func (c *Config) ShutdownGrace() time.Duration {
+	if c.ShutdownGraceMs > 0 {
+		return time.Duration(c.ShutdownGraceMs) * time.Millisecond
+	}
+	return defaultShutdownGrace
+}
+
+// Уровень вложенности ответов, если он не задан в конфигурации
+const defaultMaxReplyDepth = 3
+
+// ReplyDepth - наибольший уровень вложенности ответа: 1 - только ответы на комментарии к статье
+func (c *Config) ReplyDepth() int {
+	if c.MaxReplyDepth > 0 {
+		return c.MaxReplyDepth
+	}
+	return defaultMaxReplyDepth
 }
 
 // readConfig - функция для чтения конфигурации из файла
@@ -97,5 +157,24 @@ func ReadConfig(filePath string) (*Config, error) {
 		return nil, fmt.Errorf("failed to unmarshal config data: %w", err)
 	}
 
+	if config.Retry.MaxAttempts == 0 {
+		config.Retry = DefaultRetryPolicy
+	}
+
+	if config.Redact != nil {
+		config.redactor, err = logger.NewRedactor(*config.Redact)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redact rules: %w", err)
+		}
+	}
+
 	return &config, nil
 }
+
+// Redactor - правила скрытия данных в логе запросов
+func (c *Config) Redactor() *logger.Redactor {
+	if c.redactor != nil {
+		return c.redactor
+	}
+	return logger.DefaultRedactor()
+}
@@ -1,12 +1,17 @@
 package kafka
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
+	"news-kafka/service-comments/pkg/logger"
 	"os"
+	"path/filepath"
 
 	"testing"
+	"time"
 
+	"github.com/IBM/sarama"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -21,6 +26,10 @@ func (m *MockProducer) SendMessage(topic string, key string, value []byte) error
 	return args.Error(0)
 }
 
+func (m *MockProducer) Send(ctx context.Context, msg *sarama.ProducerMessage) error {
+	return m.Called(ctx, msg).Error(0)
+}
+
 func (m *MockProducer) Close() error {
 	return m.Called().Error(0)
 }
@@ -104,6 +113,16 @@ func TestReadConfig_Success(t *testing.T) {
 
 }
 
+func TestConfig_ReplyDepth(t *testing.T) {
+	assert.Equal(t, defaultMaxReplyDepth, (&Config{}).ReplyDepth())
+	assert.Equal(t, 5, (&Config{MaxReplyDepth: 5}).ReplyDepth())
+}
+
+func TestConfig_ShutdownGrace(t *testing.T) {
+	assert.Equal(t, defaultShutdownGrace, (&Config{}).ShutdownGrace())
+	assert.Equal(t, 2*time.Second, (&Config{ShutdownGraceMs: 2000}).ShutdownGrace())
+}
+
 func TestReadConfig_FileNotFound(t *testing.T) {
 	// Попробуем прочитать несуществующий файл
 	_, err := ReadConfig("non_existing_file.json")
@@ -132,3 +151,18 @@ func TestReadConfig_InvalidJSON(t *testing.T) {
 		t.Fatalf("expected error, got none")
 	}
 }
+
+func TestReadConfig_Redact(t *testing.T) {
+	config, err := ReadConfig("../../configKafka.json")
+	assert.NoError(t, err)
+	body := config.Redactor().Body([]byte(`{"user_name":"gopher","content":"mail gopher@example.com","id_news":7}`))
+	assert.JSONEq(t, `{"user_name":"[REDACTED]","content":"[REDACTED]","id_news":7}`, body.Value.String())
+
+	// Без правил в конфигурации используются правила по умолчанию
+	assert.Equal(t, logger.DefaultRedactor(), (&Config{}).Redactor())
+
+	tmpFile := filepath.Join(t.TempDir(), "configKafka.json")
+	assert.NoError(t, os.WriteFile(tmpFile, []byte(`{"redact": {"fields": [""]}}`), 0644))
+	_, err = ReadConfig(tmpFile)
+	assert.ErrorContains(t, err, "invalid redact rules")
+}
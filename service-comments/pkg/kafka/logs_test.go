@@ -0,0 +1,37 @@
+package kafka
+
+import (
+	"encoding/json"
+	"errors"
+	"news-kafka/service-comments/pkg/logger"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestLogPublisher(t *testing.T) {
+	var sent []logger.RequestLog
+	producer := new(MockProducer)
+	producer.On("SendMessage", "logs", "failed", mock.Anything).Return(errors.New("kafka is down")).Once()
+	producer.On("SendMessage", "logs", mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		var entry logger.RequestLog
+		assert.NoError(t, json.Unmarshal(args.Get(2).([]byte), &entry))
+		assert.Equal(t, entry.RequestID, args.String(1))
+		sent = append(sent, entry)
+	}).Twice()
+
+	publisher := NewLogPublisher(producer, "logs")
+	publisher.Publish([]logger.RequestLog{{RequestID: "failed"}, {RequestID: "req1"}, {RequestID: "req2", Level: "ERROR"}})
+
+	// Close отправляет записи из очереди, ошибка отправки не останавливает публикацию
+	publisher.Close()
+	publisher.Publish([]logger.RequestLog{{RequestID: "req3"}})
+	publisher.Close()
+
+	producer.AssertExpectations(t)
+	if assert.Len(t, sent, 2) {
+		assert.Equal(t, "req1", sent[0].RequestID)
+		assert.Equal(t, "ERROR", sent[1].Level)
+	}
+}
@@ -0,0 +1,180 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"news-kafka/service-comments/pkg/metrics"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// Отправка сообщений для теста
+type testSender struct {
+	messages []*sarama.ProducerMessage
+	err      error
+}
+
+func (s *testSender) Send(ctx context.Context, msg *sarama.ProducerMessage) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.messages = append(s.messages, msg)
+	return nil
+}
+
+// Политика без пауз между попытками
+var testRetryPolicy = RetryPolicy{MaxAttempts: 3, InitialBackoffMs: 1, MaxBackoffMs: 1, Multiplier: 2}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 5, InitialBackoffMs: 100, MaxBackoffMs: 300, Multiplier: 2}
+
+	assert.Equal(t, time.Duration(0), policy.Backoff(1))
+	assert.Equal(t, 100*time.Millisecond, policy.Backoff(2))
+	assert.Equal(t, 200*time.Millisecond, policy.Backoff(3))
+	assert.Equal(t, 300*time.Millisecond, policy.Backoff(4))
+	assert.Equal(t, 300*time.Millisecond, policy.Backoff(5))
+}
+
+func TestRetry(t *testing.T) {
+	calls := 0
+	err := Retry(context.Background(), testRetryPolicy, func() error {
+		calls++
+		if calls < 2 {
+			return fmt.Errorf("kafka error")
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, calls)
+
+	calls = 0
+	err = Retry(context.Background(), testRetryPolicy, func() error {
+		calls++
+		return fmt.Errorf("kafka error")
+	})
+	assert.EqualError(t, err, "kafka error")
+	assert.Equal(t, testRetryPolicy.MaxAttempts, calls)
+
+	calls = 0
+	err = Retry(context.Background(), testRetryPolicy, func() error {
+		calls++
+		return Permanent(fmt.Errorf("invalid message"))
+	})
+	assert.True(t, IsPermanent(err))
+	assert.Equal(t, 1, calls)
+}
+
+func TestPipeline_RetriesUntilSuccess(t *testing.T) {
+	sender := &testSender{}
+	pipeline := Pipeline{Policy: testRetryPolicy, DeadLetters: NewDeadLetterQueue(nil, "dlq", sender)}
+
+	calls := 0
+	handler := pipeline.Handler(func(ctx context.Context, msg *sarama.ConsumerMessage) error {
+		calls++
+		if calls < 3 {
+			return fmt.Errorf("db error")
+		}
+		return nil
+	})
+
+	err := handler(context.Background(), &sarama.ConsumerMessage{Topic: "test_topic"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, calls)
+	assert.Empty(t, sender.messages)
+}
+
+// Сообщение учитывается в метриках один раз со всеми попытками
+func TestPipeline_Metrics(t *testing.T) {
+	pipeline := Pipeline{Policy: testRetryPolicy, DeadLetters: NewDeadLetterQueue(nil, "dlq", &testSender{})}
+	handler := pipeline.Handler(func(ctx context.Context, msg *sarama.ConsumerMessage) error {
+		return fmt.Errorf("db error")
+	})
+
+	consumed := metrics.KafkaConsumed.WithLabelValues("metrics_topic", "error")
+	before := testutil.ToFloat64(consumed)
+
+	assert.NoError(t, handler(context.Background(), &sarama.ConsumerMessage{Topic: "metrics_topic"}))
+	assert.Equal(t, before+1, testutil.ToFloat64(consumed))
+}
+
+func TestPipeline_DeadLetterAfterRetries(t *testing.T) {
+	sender := &testSender{}
+	var failure error
+	pipeline := Pipeline{
+		Policy:      testRetryPolicy,
+		DeadLetters: NewDeadLetterQueue(nil, "dlq", sender),
+		OnFailure:   func(ctx context.Context, msg *sarama.ConsumerMessage, err error) { failure = err },
+	}
+
+	calls := 0
+	handler := pipeline.Handler(func(ctx context.Context, msg *sarama.ConsumerMessage) error {
+		calls++
+		return fmt.Errorf("db error")
+	})
+
+	err := handler(context.Background(), &sarama.ConsumerMessage{Topic: "test_topic", Value: []byte("value")})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, calls)
+	assert.EqualError(t, failure, "db error")
+	assert.Len(t, sender.messages, 1)
+	assert.Equal(t, "dlq", sender.messages[0].Topic)
+}
+
+func TestPipeline_PermanentErrorIsNotRetried(t *testing.T) {
+	sender := &testSender{}
+	pipeline := Pipeline{Policy: testRetryPolicy, DeadLetters: NewDeadLetterQueue(nil, "dlq", sender)}
+
+	calls := 0
+	handler := pipeline.Handler(func(ctx context.Context, msg *sarama.ConsumerMessage) error {
+		calls++
+		return Permanent(fmt.Errorf("invalid message"))
+	})
+
+	err := handler(context.Background(), &sarama.ConsumerMessage{Topic: "test_topic"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, calls)
+	assert.Len(t, sender.messages, 1)
+}
+
+func TestPipeline_DeadLetterError(t *testing.T) {
+	sender := &testSender{err: fmt.Errorf("kafka error")}
+	pipeline := Pipeline{Policy: testRetryPolicy, DeadLetters: NewDeadLetterQueue(nil, "dlq", sender)}
+
+	handler := pipeline.Handler(func(ctx context.Context, msg *sarama.ConsumerMessage) error {
+		return Permanent(fmt.Errorf("invalid message"))
+	})
+
+	err := handler(context.Background(), &sarama.ConsumerMessage{Topic: "test_topic"})
+
+	assert.ErrorContains(t, err, "kafka error")
+}
+
+// Сообщение, обработка которого прервана остановкой сервиса, не отправляется в dead-letter топик
+func TestPipeline_AbandonedOnShutdown(t *testing.T) {
+	sender := &testSender{}
+	failed := false
+	pipeline := Pipeline{
+		Policy:      testRetryPolicy,
+		DeadLetters: NewDeadLetterQueue(nil, "dlq", sender),
+		OnFailure:   func(ctx context.Context, msg *sarama.ConsumerMessage, err error) { failed = true },
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	handler := pipeline.Handler(func(ctx context.Context, msg *sarama.ConsumerMessage) error {
+		cancel()
+		return fmt.Errorf("db error")
+	})
+
+	err := handler(ctx, &sarama.ConsumerMessage{Topic: "test_topic"})
+
+	assert.ErrorIs(t, err, ErrAbandoned)
+	assert.False(t, failed)
+	assert.Empty(t, sender.messages)
+}
@@ -0,0 +1,125 @@
+// Package memdb - хранилище комментариев в памяти для тестов
+package memdb
+
+import (
+	"context"
+	"news-kafka/service-comments/pkg/storage"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Хранилище данных.
+type Store struct {
+	mu       sync.Mutex
+	comments []storage.Comment
+	deleted  map[int]int64 //Время удаления по ID комментария
+}
+
+// Конструктор объекта хранилища.
+func New() *Store {
+	return &Store{}
+}
+
+func (s *Store) GetInform() string {
+	return "Memory"
+}
+
+// Ping - хранилище в памяти всегда доступно
+func (s *Store) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (s *Store) Close() {}
+
+// CommentsByIdNews возвращает комментарии к статье, кроме удаленных, новые первыми.
+func (s *Store) CommentsByIdNews(ctx context.Context, idNews int) ([]storage.Comment, error) {
+	s.mu.Lock()
+	var comments []storage.Comment
+	for _, c := range s.comments {
+		if _, deleted := s.deleted[c.Id]; c.IdNews == idNews && !deleted {
+			comments = append(comments, c)
+		}
+	}
+	s.mu.Unlock()
+
+	sort.SliceStable(comments, func(i, j int) bool { return comments[i].CommentTime > comments[j].CommentTime })
+	return comments, nil
+}
+
+// CommentById возвращает комментарий, удаленный комментарий не возвращается.
+func (s *Store) CommentById(ctx context.Context, id int) (storage.Comment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, c := range s.comments {
+		if _, deleted := s.deleted[c.Id]; c.Id == id && !deleted {
+			return c, nil
+		}
+	}
+	return storage.Comment{}, storage.ErrNotFound
+}
+
+// CommentNew добавляет комментарий и возвращает его ID.
+func (s *Store) CommentNew(ctx context.Context, comment storage.Comment) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	comment.Id = len(s.comments) + 1
+	s.comments = append(s.comments, comment)
+	return comment.Id, nil
+}
+
+// CommentUpdate изменяет текст комментария и время изменения, если автор совпадает.
+func (s *Store) CommentUpdate(ctx context.Context, comment storage.Comment) (storage.Comment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i, err := s.findOwn(comment.IdNews, comment.Id, comment.UserName)
+	if err != nil {
+		return storage.Comment{}, err
+	}
+	s.comments[i].Content = comment.Content
+	s.comments[i].EditedAt = comment.EditedAt
+	return s.comments[i], nil
+}
+
+// CommentDelete помечает комментарий удаленным, если автор совпадает.
+func (s *Store) CommentDelete(ctx context.Context, idNews, id int, userName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.findOwn(idNews, id, userName); err != nil {
+		return err
+	}
+	if s.deleted == nil {
+		s.deleted = make(map[int]int64)
+	}
+	s.deleted[id] = time.Now().Unix()
+	return nil
+}
+
+// findOwn - индекс неудаленного комментария к статье, автор которого userName
+func (s *Store) findOwn(idNews, id int, userName string) (int, error) {
+	i, ok := s.find(idNews, id)
+	if !ok {
+		return 0, storage.ErrNotFound
+	}
+	if s.comments[i].UserName != userName {
+		return 0, storage.ErrForbidden
+	}
+	return i, nil
+}
+
+// find - индекс неудаленного комментария к статье
+func (s *Store) find(idNews, id int) (int, bool) {
+	if _, deleted := s.deleted[id]; deleted {
+		return 0, false
+	}
+	for i, c := range s.comments {
+		if c.Id == id && c.IdNews == idNews {
+			return i, true
+		}
+	}
+	return 0, false
+}
@@ -2,10 +2,17 @@ package postgres
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"news-kafka/service-comments/pkg/metrics"
 	"news-kafka/service-comments/pkg/storage"
+	"news-kafka/service-comments/pkg/tracing"
+	"time"
 
+	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Хранилище данных.
@@ -17,6 +24,17 @@ func (s *Store) GetInform() string {
 	return "PostgreSQL"
 }
 
+// migration - добавление колонок изменения, удаления и ответов в таблицу, созданную
+// предыдущими версиями init_comments.sql. Скрипт инициализации Docker выполняется только
+// для пустого тома, поэтому существующая БД обновляется при запуске сервиса.
+const migration = `
+	ALTER TABLE comments
+		ADD COLUMN IF NOT EXISTS edited_at INTEGER NOT NULL DEFAULT 0,
+		ADD COLUMN IF NOT EXISTS deleted_at INTEGER,
+		ADD COLUMN IF NOT EXISTS parent_id BIGINT REFERENCES comments(id),
+		ADD COLUMN IF NOT EXISTS depth INTEGER NOT NULL DEFAULT 0;
+	CREATE INDEX IF NOT EXISTS comments_id_news_idx ON comments (id_news);`
+
 // Конструктор объекта хранилища.
 func New(constr string) (*Store, error) {
 	db, err := pgxpool.Connect(context.Background(), constr)
@@ -27,6 +45,11 @@ func New(constr string) (*Store, error) {
 		db: db,
 	}
 
+	if _, err := db.Exec(context.Background(), migration); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate comments table: %w", err)
+	}
+
 	fmt.Println("Loaded bd: ", s.GetInform())
 
 	return &s, nil
@@ -36,13 +59,65 @@ func (s *Store) Close() {
 	s.db.Close()
 }
 
-// CommentsByIdNews возвращает комментарии к статье из БД.
-func (s *Store) CommentsByIdNews(idNews int) ([]storage.Comment, error) {
+// Ping проверяет доступность БД через пул соединений.
+func (s *Store) Ping(ctx context.Context) error {
+	return s.db.Ping(ctx)
+}
+
+// query - спан и время выполнения запроса к PostgreSQL
+type query struct {
+	method string
+	start  time.Time
+	span   trace.Span
+}
+
+// startQuery - начало запроса к PostgreSQL: спан трассировки и отсчет времени для метрик
+func startQuery(ctx context.Context, method, operation, table string) (context.Context, *query) {
+	ctx, span := tracing.Tracer().Start(ctx, "postgres "+method, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		attribute.String("db.system", "postgresql"),
+		attribute.String("db.operation", operation),
+		attribute.String("db.sql.table", table),
+	))
+	return ctx, &query{method: method, start: time.Now(), span: span}
+}
+
+// end - завершение запроса, отсутствие записи не считается ошибкой
+func (q *query) end(err error) {
+	if errors.Is(err, storage.ErrNotFound) {
+		err = nil
+	}
+	metrics.DBQueryDuration.WithLabelValues(q.method, metrics.Result(err)).Observe(time.Since(q.start).Seconds())
+	tracing.End(q.span, err)
+}
+
+// commentColumns - колонки комментария в порядке полей scanComment, parent_id комментария к статье - NULL
+const commentColumns = "id, id_news, comment_time, user_name, content, edited_at, COALESCE(parent_id, 0), depth"
+
+// scanComment - чтение строки с колонками commentColumns
+func scanComment(row pgx.Row) (storage.Comment, error) {
+	var p storage.Comment
+	err := row.Scan(
+		&p.Id,
+		&p.IdNews,
+		&p.CommentTime,
+		&p.UserName,
+		&p.Content,
+		&p.EditedAt,
+		&p.ParentId,
+		&p.Depth,
+	)
+	return p, err
+}
 
-	rows, err := s.db.Query(context.Background(), `
-	 SELECT id, id_news,comment_time, user_name, content 
+// CommentsByIdNews возвращает комментарии к статье из БД, кроме удаленных.
+func (s *Store) CommentsByIdNews(ctx context.Context, idNews int) (_ []storage.Comment, err error) {
+	ctx, q := startQuery(ctx, "CommentsByIdNews", "SELECT", "comments")
+	defer func() { q.end(err) }()
+
+	rows, err := s.db.Query(ctx, `
+	 SELECT `+commentColumns+`
 	 FROM comments
-	 WHERE id_news = $1
+	 WHERE id_news = $1 AND deleted_at IS NULL
 	 ORDER BY comment_time DESC
 	`, idNews)
 	if err != nil {
@@ -52,14 +127,7 @@ func (s *Store) CommentsByIdNews(idNews int) ([]storage.Comment, error) {
 
 	var comments []storage.Comment
 	for rows.Next() {
-		var p storage.Comment
-		err = rows.Scan(
-			&p.Id,
-			&p.IdNews,
-			&p.CommentTime,
-			&p.UserName,
-			&p.Content,
-		)
+		p, err := scanComment(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan row: %w", err)
 		}
@@ -69,17 +137,42 @@ func (s *Store) CommentsByIdNews(idNews int) ([]storage.Comment, error) {
 	return comments, nil
 }
 
+// CommentById возвращает комментарий по идентификатору, удаленный комментарий не возвращается.
+func (s *Store) CommentById(ctx context.Context, id int) (_ storage.Comment, err error) {
+	ctx, q := startQuery(ctx, "CommentById", "SELECT", "comments")
+	defer func() { q.end(err) }()
+
+	p, err := scanComment(s.db.QueryRow(ctx, `
+		SELECT `+commentColumns+`
+		FROM comments
+		WHERE id = $1 AND deleted_at IS NULL;`,
+		id,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return storage.Comment{}, storage.ErrNotFound
+	}
+	if err != nil {
+		return storage.Comment{}, fmt.Errorf("failed to query: %w", err)
+	}
+
+	return p, nil
+}
+
 // CommentNew добавляем комментарий в БД.
-func (s *Store) CommentNew(comment storage.Comment) (int, error) {
+func (s *Store) CommentNew(ctx context.Context, comment storage.Comment) (_ int, err error) {
+	ctx, q := startQuery(ctx, "CommentNew", "INSERT", "comments")
+	defer func() { q.end(err) }()
 
 	var id_rec int
-	err := s.db.QueryRow(context.Background(), `
-		INSERT INTO comments(id_news, comment_time, user_name, content)
-		VALUES ($1, $2, $3, $4) RETURNING id;`,
+	err = s.db.QueryRow(ctx, `
+		INSERT INTO comments(id_news, comment_time, user_name, content, parent_id, depth)
+		VALUES ($1, $2, $3, $4, NULLIF($5, 0), $6) RETURNING id;`,
 		comment.IdNews,
 		comment.CommentTime,
 		comment.UserName,
 		comment.Content,
+		comment.ParentId,
+		comment.Depth,
 	).Scan(&id_rec)
 
 	if err != nil {
@@ -88,3 +181,72 @@ func (s *Store) CommentNew(comment storage.Comment) (int, error) {
 
 	return id_rec, nil
 }
+
+// CommentUpdate изменяет текст комментария и время изменения. Автор проверяется в том же запросе
+// UPDATE, удаленные комментарии не изменяются.
+func (s *Store) CommentUpdate(ctx context.Context, comment storage.Comment) (_ storage.Comment, err error) {
+	ctx, q := startQuery(ctx, "CommentUpdate", "UPDATE", "comments")
+	defer func() { q.end(err) }()
+
+	p, err := scanComment(s.db.QueryRow(ctx, `
+		UPDATE comments SET content = $3, edited_at = $4
+		WHERE id = $1 AND id_news = $2 AND user_name = $5 AND deleted_at IS NULL
+		RETURNING `+commentColumns+`;`,
+		comment.Id,
+		comment.IdNews,
+		comment.Content,
+		comment.EditedAt,
+		comment.UserName,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return storage.Comment{}, s.notChanged(ctx, comment.IdNews, comment.Id)
+	}
+	if err != nil {
+		return storage.Comment{}, fmt.Errorf("failed to update row: %w", err)
+	}
+
+	return p, nil
+}
+
+// CommentDelete помечает комментарий удаленным: запись остается в БД с временем удаления deleted_at.
+// Автор проверяется в том же запросе UPDATE.
+func (s *Store) CommentDelete(ctx context.Context, idNews, id int, userName string) (err error) {
+	ctx, q := startQuery(ctx, "CommentDelete", "UPDATE", "comments")
+	defer func() { q.end(err) }()
+
+	tag, err := s.db.Exec(ctx, `
+		UPDATE comments SET deleted_at = $3
+		WHERE id = $1 AND id_news = $2 AND user_name = $4 AND deleted_at IS NULL;`,
+		id,
+		idNews,
+		time.Now().Unix(),
+		userName,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete row: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return s.notChanged(ctx, idNews, id)
+	}
+
+	return nil
+}
+
+// notChanged - причина, по которой UPDATE комментария не изменил строк: ErrForbidden, если
+// комментарий есть, но автор другой, иначе ErrNotFound. Изменение уже не выполнено, поэтому
+// чтение после UPDATE влияет только на ответ, а не на результат записи.
+func (s *Store) notChanged(ctx context.Context, idNews, id int) error {
+	var exists bool
+	err := s.db.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM comments WHERE id = $1 AND id_news = $2 AND deleted_at IS NULL);`,
+		id,
+		idNews,
+	).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to query: %w", err)
+	}
+	if exists {
+		return storage.ErrForbidden
+	}
+	return storage.ErrNotFound
+}
@@ -1,19 +1,29 @@
 package storage
 
+import (
+	"context"
+	"errors"
+	"news-kafka/contracts"
+)
+
+// ErrNotFound - комментарий отсутствует в БД или удален
+var ErrNotFound = errors.New("not found")
+
+// ErrForbidden - комментарий принадлежит другому пользователю
+var ErrForbidden = errors.New("comment belongs to another user")
+
 // Комментарий к публикации
-type Comment struct {
-	Id          int
-	IdNews      int    `json:"id_news"`
-	CommentTime int64  `json:"comment_time"`
-	UserName    string `json:"user_name"`
-	Content     string `json:"content"`
-}
+type Comment = contracts.Comment
 
 // Interface задаёт контракт на работу с БД.
 type Interface interface {
 	GetInform() string
 	Close()
+	Ping(ctx context.Context) error // Проверка доступности БД.
 
-	CommentsByIdNews(idNews int) ([]Comment, error) // Возвращает комментарии по статье.
-	CommentNew(comment Comment) (int, error)        // Добавляем комментарий в БД.
+	CommentsByIdNews(ctx context.Context, idNews int) ([]Comment, error)      // Возвращает комментарии по статье, кроме удаленных.
+	CommentById(ctx context.Context, id int) (Comment, error)                 // Возвращает комментарий, ErrNotFound если его нет или он удален.
+	CommentNew(ctx context.Context, comment Comment) (int, error)             // Добавляем комментарий в БД с comment.ParentId и comment.Depth, ответ - ID комментария.
+	CommentUpdate(ctx context.Context, comment Comment) (Comment, error)      // Изменяет текст и время изменения комментария comment.Id к статье comment.IdNews автора comment.UserName, ErrNotFound если его нет, ErrForbidden если автор другой.
+	CommentDelete(ctx context.Context, idNews, id int, userName string) error // Помечает удаленным комментарий автора userName, ErrNotFound если его нет, ErrForbidden если автор другой.
 }
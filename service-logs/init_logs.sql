@@ -0,0 +1,25 @@
+--++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
+--1) create tables
+--++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
+
+DROP TABLE IF EXISTS logs;
+
+-- Записи лога api-gateway и сервисов из топика логов
+CREATE TABLE logs (
+    id BIGSERIAL PRIMARY KEY,
+    timestamp TIMESTAMPTZ NOT NULL,
+    service_id TEXT NOT NULL DEFAULT '',
+    request_id TEXT NOT NULL DEFAULT '',
+    remote_addr TEXT NOT NULL DEFAULT '',
+    status_code INTEGER NOT NULL DEFAULT 0,
+    data_request TEXT NOT NULL DEFAULT '',
+    level TEXT NOT NULL DEFAULT '',
+    topic TEXT NOT NULL DEFAULT '',
+    latency_ms DOUBLE PRECISION NOT NULL DEFAULT 0,
+    fields JSONB
+);
+
+-- Хронология запроса и поиск по сервису и времени
+CREATE INDEX logs_request_id_idx ON logs (request_id, timestamp);
+CREATE INDEX logs_service_id_idx ON logs (service_id text_pattern_ops, timestamp);
+CREATE INDEX logs_timestamp_idx ON logs (timestamp);
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"news-kafka/service-logs/pkg/api"
+	"news-kafka/service-logs/pkg/handler"
+	"news-kafka/service-logs/pkg/kafka"
+	"news-kafka/service-logs/pkg/logger"
+	"news-kafka/service-logs/pkg/storage"
+	"news-kafka/service-logs/pkg/storage/postgres"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"fmt"
+	"log"
+)
+
+// http://127.0.0.1:8081/logs/{request_id}
+// http://127.0.0.1:8081/logs?service=service-news&status=500&from=2024-10-28T00:00:00Z
+
+// Сервер
+type server struct {
+	db  storage.Interface
+	api *api.API
+}
+
+func main() {
+
+	fmt.Println("service-logs:", logger.GetServiceName())
+	fmt.Println("service-logs:", logger.GetLocalIP())
+
+	// Создаём объект сервера.
+	var srv server
+
+	//==============================================
+	//Logger
+	//==============================================
+	// Собственные записи service-logs только в logs.json: публикация в топик логов
+	// привела бы к сохранению записей о сохранении записей
+	sink, err := logger.NewLoggerWithOptions("logs.json", logger.DefaultOptions)
+	if err != nil {
+		log.Fatalf("Error creating logger: %v", err)
+	}
+	defer sink.Close()
+	logs := logger.New(sink, logger.LevelFromEnv())
+
+	//==============================================
+	//Kafka
+	//==============================================
+	config, err := kafka.ReadConfig("configKafka.json")
+	if err != nil {
+		log.Fatalf("Failed to read config: %v", err)
+	}
+
+	// Потребитель в составе группы: записи, опубликованные во время перезапуска, не теряются
+	kafkaConsumer, err := kafka.NewGroupConsumer(config.KafkaBrokers, config.ConsumerGroup, config.ShutdownGrace(), logs)
+	if err != nil {
+		log.Fatalf("Failed to create Kafka consumer: %v", err)
+	}
+	defer kafkaConsumer.Close()
+
+	//==============================================
+	//PostgreSQL
+	//==============================================
+	// Реляционная БД PostgreSQL.
+	connstr := os.Getenv("LOGSDBPG")
+	if connstr == "" {
+		log.Fatal(errors.New("no connection to pg bd"))
+	}
+	db_pg, err := postgres.New(connstr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	srv.db = db_pg
+	defer srv.db.Close()
+
+	// Остановка по SIGINT/SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// сохраняем записи лога из топика логов
+	consumed := make(chan struct{})
+	go func() {
+		defer close(consumed)
+		err := kafkaConsumer.Consume(ctx, []string{config.TopicLogs}, handler.New(srv.db, logs))
+		if err != nil {
+			logs.Error("kafka consumer stopped", logger.Err(err))
+		}
+	}()
+
+	//==============================================
+	//API
+	//==============================================
+	srv.api = api.New(srv.db, logs)
+
+	fmt.Println("Запуск веб-сервера на http://127.0.0.1:8081 ...")
+	httpServer := &http.Server{Addr: ":8081", Handler: srv.api.Router()}
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- httpServer.ListenAndServe()
+	}()
+
+	// Ожидание SIGINT/SIGTERM
+	select {
+	case err := <-serveErr:
+		log.Fatalf("Failed to start web server: %v", err)
+	case <-ctx.Done():
+	}
+	stop()
+	logs.Info("shutting down")
+
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), config.ShutdownGrace())
+	defer cancelShutdown()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		logs.Warn("failed to shut down web server", logger.Err(err))
+	}
+
+	// Чтение Kafka остановлено отменой ctx, текущие записи сохраняются не дольше shutdown_grace_ms
+	select {
+	case <-consumed:
+	case <-time.After(config.ShutdownGrace() + time.Second):
+		logs.Warn("kafka consumer did not stop in time")
+	}
+
+	// Далее в defer: закрытие пула БД и consumer, запись буфера логгера
+}
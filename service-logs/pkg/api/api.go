@@ -0,0 +1,161 @@
+// Package api - HTTP API service-logs: хронология запроса по request_id и поиск записей лога
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"news-kafka/service-logs/pkg/logger"
+	"news-kafka/service-logs/pkg/storage"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Программный интерфейс service-logs
+type API struct {
+	db     storage.Interface
+	router *mux.Router
+	logs   *slog.Logger
+}
+
+// Timeline - записи всех сервисов по одному запросу в порядке времени
+type Timeline struct {
+	RequestID string          `json:"request_id"`
+	Services  []string        `json:"services"` //Сервисы в порядке первой записи
+	Entries   []storage.Entry `json:"entries"`
+}
+
+// SearchResult - записи, найденные по условиям поиска, новые первыми
+type SearchResult struct {
+	Entries []storage.Entry `json:"entries"`
+}
+
+// Конструктор объекта API
+func New(db storage.Interface, logs *slog.Logger) *API {
+	api := API{
+		db:     db,
+		router: mux.NewRouter(),
+		logs:   logs,
+	}
+	api.endpoints()
+	return &api
+}
+
+// Получение маршрутизатора запросов.
+// Требуется для передачи маршрутизатора веб-серверу.
+func (api *API) Router() *mux.Router {
+	return api.router
+}
+
+// Регистрация обработчиков API.
+func (api *API) endpoints() {
+	// хронология запроса: http://127.0.0.1:8081/logs/{request_id}
+	api.router.HandleFunc("/logs/{request_id}", api.timelineHandler).Methods(http.MethodGet)
+	// поиск: http://127.0.0.1:8081/logs?service=service-news&status=500&level=error&from=2024-10-28T00:00:00Z&to=2024-10-29T00:00:00Z&limit=100
+	api.router.HandleFunc("/logs", api.searchHandler).Methods(http.MethodGet)
+	api.router.HandleFunc("/healthz", api.healthzHandler).Methods(http.MethodGet)
+	api.router.HandleFunc("/readyz", api.readyzHandler).Methods(http.MethodGet)
+}
+
+// timelineHandler - записи api-gateway и сервисов по request_id
+func (api *API) timelineHandler(w http.ResponseWriter, r *http.Request) {
+	requestID := mux.Vars(r)["request_id"]
+
+	entries, err := api.db.LogsByRequestID(r.Context(), requestID)
+	if err != nil {
+		api.logs.ErrorContext(r.Context(), "failed to read request timeline", logger.RequestID(requestID), logger.Err(err))
+		http.Error(w, "failed to read logs", http.StatusInternalServerError)
+		return
+	}
+	if len(entries) == 0 {
+		http.Error(w, "no log entries for request", http.StatusNotFound)
+		return
+	}
+
+	timeline := Timeline{RequestID: requestID, Services: []string{}, Entries: entries}
+	seen := make(map[string]bool)
+	for _, e := range entries {
+		if !seen[e.ServiceID] {
+			seen[e.ServiceID] = true
+			timeline.Services = append(timeline.Services, e.ServiceID)
+		}
+	}
+
+	writeJSON(w, timeline)
+}
+
+// searchHandler - поиск записей по сервису, статусу, уровню и интервалу времени
+func (api *API) searchHandler(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	entries, err := api.db.SearchLogs(r.Context(), filter)
+	if err != nil {
+		api.logs.ErrorContext(r.Context(), "failed to search logs", logger.Err(err))
+		http.Error(w, "failed to read logs", http.StatusInternalServerError)
+		return
+	}
+	if entries == nil {
+		entries = []storage.Entry{}
+	}
+
+	writeJSON(w, SearchResult{Entries: entries})
+}
+
+// parseFilter - условия поиска из параметров запроса, время в формате RFC 3339
+func parseFilter(r *http.Request) (storage.Filter, error) {
+	query := r.URL.Query()
+	filter := storage.Filter{
+		Service: query.Get("service"),
+		Level:   query.Get("level"),
+	}
+
+	var err error
+	if s := query.Get("status"); s != "" {
+		if filter.StatusCode, err = strconv.Atoi(s); err != nil {
+			return filter, fmt.Errorf("invalid status parameter: %v", s)
+		}
+	}
+	if s := query.Get("limit"); s != "" {
+		if filter.Limit, err = strconv.Atoi(s); err != nil || filter.Limit < 1 {
+			return filter, fmt.Errorf("invalid limit parameter: %v", s)
+		}
+	}
+	if s := query.Get("from"); s != "" {
+		if filter.From, err = time.Parse(time.RFC3339, s); err != nil {
+			return filter, fmt.Errorf("invalid from parameter: %v", s)
+		}
+	}
+	if s := query.Get("to"); s != "" {
+		if filter.To, err = time.Parse(time.RFC3339, s); err != nil {
+			return filter, fmt.Errorf("invalid to parameter: %v", s)
+		}
+	}
+	return filter, nil
+}
+
+// healthzHandler - процесс запущен и обслуживает HTTP
+func (api *API) healthzHandler(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
+
+// readyzHandler - БД доступна, иначе 503
+func (api *API) readyzHandler(w http.ResponseWriter, r *http.Request) {
+	if err := api.db.Ping(r.Context()); err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// writeJSON - ответ в формате JSON
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
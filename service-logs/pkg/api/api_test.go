@@ -0,0 +1,90 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"news-kafka/service-logs/pkg/storage"
+	"news-kafka/service-logs/pkg/storage/memdb"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Записи одного запроса от api-gateway и service-news и запись другого запроса
+func newTestAPI(t *testing.T) *API {
+	db := memdb.New()
+	start := time.Date(2024, 10, 28, 10, 0, 0, 0, time.UTC)
+	for _, e := range []storage.Entry{
+		{Timestamp: start.Add(30 * time.Millisecond), ServiceID: "api-gateway-001", RequestID: "req1", StatusCode: 200, DataRequest: "http request", Level: "INFO"},
+		{Timestamp: start.Add(10 * time.Millisecond), ServiceID: "service-news-001", RequestID: "req1", StatusCode: 200, DataRequest: "request received", Level: "INFO"},
+		{Timestamp: start, ServiceID: "api-gateway-001", RequestID: "req1", StatusCode: 200, DataRequest: "request sent", Level: "DEBUG"},
+		{Timestamp: start.Add(time.Hour), ServiceID: "service-news-002", RequestID: "req2", StatusCode: 500, DataRequest: "failed to process message", Level: "ERROR"},
+	} {
+		require.NoError(t, db.AddLog(context.Background(), e))
+	}
+	return New(db, slog.New(slog.NewJSONHandler(io.Discard, nil)))
+}
+
+func get(t *testing.T, api *API, target string, v any) int {
+	rec := httptest.NewRecorder()
+	api.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	if rec.Code == http.StatusOK && v != nil {
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(v))
+	}
+	return rec.Code
+}
+
+func TestTimeline(t *testing.T) {
+	api := newTestAPI(t)
+
+	var timeline Timeline
+	assert.Equal(t, http.StatusOK, get(t, api, "/logs/req1", &timeline))
+	assert.Equal(t, "req1", timeline.RequestID)
+	assert.Equal(t, []string{"api-gateway-001", "service-news-001"}, timeline.Services)
+
+	var messages []string
+	for _, e := range timeline.Entries {
+		messages = append(messages, e.DataRequest)
+	}
+	assert.Equal(t, []string{"request sent", "request received", "http request"}, messages)
+
+	assert.Equal(t, http.StatusNotFound, get(t, api, "/logs/unknown", nil))
+}
+
+func TestSearch(t *testing.T) {
+	api := newTestAPI(t)
+
+	tests := []struct {
+		query    string
+		requests []string
+	}{
+		{"", []string{"req2", "req1", "req1", "req1"}},
+		{"?service=service-news", []string{"req2", "req1"}},
+		{"?status=500", []string{"req2"}},
+		{"?level=debug", []string{"req1"}},
+		{"?from=2024-10-28T10:00:00.02Z&to=2024-10-28T11:00:00Z", []string{"req1"}},
+		{"?limit=1", []string{"req2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			var result SearchResult
+			assert.Equal(t, http.StatusOK, get(t, api, "/logs"+tt.query, &result))
+
+			requests := []string{}
+			for _, e := range result.Entries {
+				requests = append(requests, e.RequestID)
+			}
+			assert.Equal(t, tt.requests, requests)
+		})
+	}
+
+	for _, query := range []string{"?status=abc", "?limit=0", "?from=yesterday"} {
+		assert.Equal(t, http.StatusBadRequest, get(t, api, "/logs"+query, nil), query)
+	}
+}
@@ -0,0 +1,66 @@
+// Package handler - сохранение записей лога из топика логов в БД
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"news-kafka/service-logs/pkg/kafka"
+	"news-kafka/service-logs/pkg/logger"
+	"news-kafka/service-logs/pkg/storage"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// Пауза между попытками сохранения записи, пока БД недоступна
+var (
+	initialBackoff = 100 * time.Millisecond
+	maxBackoff     = 5 * time.Second
+)
+
+// New - обработчик сообщений топика логов: каждое сообщение - одна запись RequestLog в JSON.
+// Запись, которую невозможно разобрать, записывается в лог группой потребителей, смещение
+// сообщения при этом фиксируется. Ошибка БД не пропускает запись: сохранение повторяется
+// с экспоненциальной паузой, пока не завершится успешно или не будет остановлено потребление,
+// и тогда смещение не фиксируется, а сообщение будет прочитано снова после перезапуска.
+func New(db storage.Interface, logs *slog.Logger) kafka.MessageHandler {
+	return func(ctx context.Context, msg *sarama.ConsumerMessage) error {
+		var entry storage.Entry
+		if err := json.Unmarshal(msg.Value, &entry); err != nil {
+			return fmt.Errorf("failed to decode log entry: %w", err)
+		}
+		if err := entry.CheckVersion(); err != nil {
+			return fmt.Errorf("failed to decode log entry: %w", err)
+		}
+
+		if err := storeLog(ctx, db, entry, logs); err != nil {
+			return fmt.Errorf("failed to store log entry: %w", err)
+		}
+
+		logs.DebugContext(ctx, "log entry stored", logger.RequestID(entry.RequestID), slog.String(logger.KeyService, entry.ServiceID))
+		return nil
+	}
+}
+
+// storeLog - сохранение записи с повторами, ошибка возвращается только при отмене ctx
+func storeLog(ctx context.Context, db storage.Interface, entry storage.Entry, logs *slog.Logger) error {
+	backoff := initialBackoff
+	for attempt := 1; ; attempt++ {
+		err := db.AddLog(ctx, entry)
+		if err == nil {
+			return nil
+		}
+		logs.WarnContext(ctx, "failed to store log entry, retrying", logger.RequestID(entry.RequestID), slog.Int("attempt", attempt), logger.Err(err))
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%w: %v", ctx.Err(), err)
+		case <-timer.C:
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
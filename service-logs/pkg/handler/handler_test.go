@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"news-kafka/contracts"
+	"news-kafka/service-logs/pkg/storage"
+	"news-kafka/service-logs/pkg/storage/memdb"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler(t *testing.T) {
+	db := memdb.New()
+	handle := New(db, slog.New(slog.NewJSONHandler(io.Discard, nil)))
+
+	err := handle(context.Background(), &sarama.ConsumerMessage{
+		Topic: "logs",
+		Key:   []byte("req1"),
+		Value: []byte(`{"timestamp":"2024-10-28T10:00:00Z","service_id":"service-news-001","request_id":"req1","status_code":200,"data_request":"request received","level":"INFO","fields":{"body":"{}"}}`),
+	})
+	assert.NoError(t, err)
+
+	entries, err := db.LogsByRequestID(context.Background(), "req1")
+	assert.NoError(t, err)
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, "service-news-001", entries[0].ServiceID)
+		assert.Equal(t, time.Date(2024, 10, 28, 10, 0, 0, 0, time.UTC), entries[0].Timestamp)
+		assert.Equal(t, map[string]any{"body": "{}"}, entries[0].Fields)
+	}
+
+	err = handle(context.Background(), &sarama.ConsumerMessage{Topic: "logs", Value: []byte(`not json`)})
+	assert.ErrorContains(t, err, "failed to decode log entry")
+}
+
+// Хранилище, недоступное первые failures попыток
+type flakyStore struct {
+	*memdb.Store
+	failures int
+	calls    int
+}
+
+func (s *flakyStore) AddLog(ctx context.Context, entry storage.Entry) error {
+	s.calls++
+	if s.calls <= s.failures {
+		return errors.New("connection refused")
+	}
+	return s.Store.AddLog(ctx, entry)
+}
+
+// Ошибка БД не пропускает запись: сохранение повторяется до успеха или остановки
+func TestHandler_RetriesStore(t *testing.T) {
+	initial, limit := initialBackoff, maxBackoff
+	initialBackoff, maxBackoff = time.Millisecond, time.Millisecond
+	t.Cleanup(func() { initialBackoff, maxBackoff = initial, limit })
+	msg := &sarama.ConsumerMessage{Topic: "logs", Value: []byte(`{"version":2,"request_id":"req1"}`)}
+
+	db := &flakyStore{Store: memdb.New(), failures: 2}
+	handle := New(db, slog.New(slog.NewJSONHandler(io.Discard, nil)))
+	require.NoError(t, handle(context.Background(), msg))
+	assert.Equal(t, 3, db.calls)
+	entries, err := db.LogsByRequestID(context.Background(), "req1")
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	handle = New(&flakyStore{Store: memdb.New(), failures: 1 << 30}, slog.New(slog.NewJSONHandler(io.Discard, nil)))
+	assert.ErrorIs(t, handle(ctx, msg), context.DeadlineExceeded)
+}
+
+func TestHandler_UnsupportedVersion(t *testing.T) {
+	handle := New(memdb.New(), slog.New(slog.NewJSONHandler(io.Discard, nil)))
+	err := handle(context.Background(), &sarama.ConsumerMessage{Topic: "logs", Value: []byte(`{"version":99,"request_id":"req1"}`)})
+	assert.ErrorIs(t, err, contracts.ErrUnsupportedVersion)
+}
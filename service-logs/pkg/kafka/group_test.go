@@ -0,0 +1,127 @@
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+)
+
+// Сессия группы для теста
+type testSession struct {
+	ctx       context.Context
+	marked    []int64
+	committed int
+}
+
+func (s *testSession) Claims() map[string][]int32               { return nil }
+func (s *testSession) MemberID() string                         { return "member" }
+func (s *testSession) GenerationID() int32                      { return 1 }
+func (s *testSession) MarkOffset(string, int32, int64, string)  {}
+func (s *testSession) ResetOffset(string, int32, int64, string) {}
+func (s *testSession) Context() context.Context                 { return s.ctx }
+func (s *testSession) Commit()                                  { s.committed++ }
+func (s *testSession) MarkMessage(msg *sarama.ConsumerMessage, _ string) {
+	s.marked = append(s.marked, msg.Offset)
+}
+
+// Партиция группы для теста
+type testClaim struct {
+	messages chan *sarama.ConsumerMessage
+}
+
+func (c *testClaim) Topic() string                            { return "test_topic" }
+func (c *testClaim) Partition() int32                         { return 0 }
+func (c *testClaim) InitialOffset() int64                     { return 0 }
+func (c *testClaim) HighWaterMarkOffset() int64               { return 0 }
+func (c *testClaim) Messages() <-chan *sarama.ConsumerMessage { return c.messages }
+
+func TestGroupHandler_CommitsAfterProcessing(t *testing.T) {
+	session := &testSession{ctx: context.Background()}
+	claim := &testClaim{messages: make(chan *sarama.ConsumerMessage, 3)}
+	for i := int64(0); i < 3; i++ {
+		claim.messages <- &sarama.ConsumerMessage{Topic: "test_topic", Offset: i}
+	}
+	close(claim.messages)
+
+	var processed []int64
+	h := &groupHandler{handler: func(ctx context.Context, msg *sarama.ConsumerMessage) error {
+		// Смещение еще не зафиксировано на момент обработки
+		assert.Len(t, session.marked, int(msg.Offset))
+		processed = append(processed, msg.Offset)
+		return nil
+	}}
+
+	err := h.ConsumeClaim(session, claim)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{0, 1, 2}, processed)
+	assert.Equal(t, []int64{0, 1, 2}, session.marked)
+	assert.Equal(t, 3, session.committed)
+}
+
+func TestGroupHandler_ReportsErrors(t *testing.T) {
+	session := &testSession{ctx: context.Background()}
+	claim := &testClaim{messages: make(chan *sarama.ConsumerMessage, 1)}
+	claim.messages <- &sarama.ConsumerMessage{Topic: "test_topic", Offset: 7}
+	close(claim.messages)
+
+	var logs bytes.Buffer
+	h := &groupHandler{
+		handler: func(ctx context.Context, msg *sarama.ConsumerMessage) error { return fmt.Errorf("some error") },
+		logs:    slog.New(slog.NewJSONHandler(&logs, nil)),
+	}
+
+	err := h.ConsumeClaim(session, claim)
+
+	assert.NoError(t, err)
+	assert.Contains(t, logs.String(), "some error")
+	assert.Contains(t, logs.String(), `"offset":7`)
+	assert.Equal(t, []int64{7}, session.marked)
+}
+
+// Остановка потребления не прерывает обработку текущего сообщения
+func TestGroupHandler_FinishesMessageOnShutdown(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	session := &testSession{ctx: ctx}
+	claim := &testClaim{messages: make(chan *sarama.ConsumerMessage, 1)}
+	claim.messages <- &sarama.ConsumerMessage{Topic: "test_topic", Offset: 3}
+
+	h := &groupHandler{grace: time.Second, handler: func(ctx context.Context, msg *sarama.ConsumerMessage) error {
+		cancel()
+		time.Sleep(10 * time.Millisecond)
+		return ctx.Err()
+	}}
+
+	err := h.ConsumeClaim(session, claim)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{3}, session.marked)
+}
+
+// Обработка, не завершенная за grace, прерывается без фиксации смещения
+func TestGroupHandler_AbandonsAfterGrace(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	session := &testSession{ctx: ctx}
+	claim := &testClaim{messages: make(chan *sarama.ConsumerMessage, 1)}
+	claim.messages <- &sarama.ConsumerMessage{Topic: "test_topic", Offset: 3}
+
+	var logs bytes.Buffer
+	h := &groupHandler{grace: 10 * time.Millisecond, logs: slog.New(slog.NewJSONHandler(&logs, nil)), handler: func(ctx context.Context, msg *sarama.ConsumerMessage) error {
+		cancel()
+		<-ctx.Done()
+		return ctx.Err()
+	}}
+
+	err := h.ConsumeClaim(session, claim)
+
+	assert.NoError(t, err)
+	assert.Contains(t, logs.String(), ErrAbandoned.Error())
+	assert.Empty(t, session.marked)
+	assert.Zero(t, session.committed)
+}
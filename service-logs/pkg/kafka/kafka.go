@@ -0,0 +1,45 @@
+package kafka
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"time"
+)
+
+// Config - структура для хранения конфигурации
+type Config struct {
+	KafkaBrokers    []string `json:"kafka_brokers"`
+	TopicLogs       string   `json:"topic_logs"`        //Топик, в который api-gateway и сервисы публикуют записи лога
+	ConsumerGroup   string   `json:"consumer_group"`    //Группа потребителей service-logs
+	ShutdownGraceMs int      `json:"shutdown_grace_ms"` //Время на завершение обработки сообщений при остановке
+}
+
+// Время на завершение обработки сообщений при остановке, если оно не задано в конфигурации
+const defaultShutdownGrace = 10 * time.Second
+
+// ShutdownGrace - время на завершение обработки текущих сообщений при остановке сервиса
+func (c *Config) ShutdownGrace() time.Duration {
+	if c.ShutdownGraceMs > 0 {
+		return time.Duration(c.ShutdownGraceMs) * time.Millisecond
+	}
+	return defaultShutdownGrace
+}
+
+// ReadConfig - функция для чтения конфигурации из файла
+func ReadConfig(filePath string) (*Config, error) {
+	// Чтение содержимого файла
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	// Декодирование JSON данных
+	var config Config
+	err = json.Unmarshal(data, &config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config data: %w", err)
+	}
+
+	return &config, nil
+}
@@ -0,0 +1,34 @@
+package kafka
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadConfig(t *testing.T) {
+	config, err := ReadConfig("../../configKafka.json")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"kafka:9092"}, config.KafkaBrokers)
+	assert.Equal(t, "logs", config.TopicLogs)
+	assert.Equal(t, "service-logs", config.ConsumerGroup)
+}
+
+func TestReadConfig_Errors(t *testing.T) {
+	_, err := ReadConfig("non_existing_file.json")
+	assert.Error(t, err)
+
+	invalid := filepath.Join(t.TempDir(), "invalid_config.json")
+	assert.NoError(t, os.WriteFile(invalid, []byte(`{ "kafka_brokers": ["localhost:9092"] "topic_logs": "logs" }`), 0644))
+	_, err = ReadConfig(invalid)
+	assert.Error(t, err)
+}
+
+func TestConfig_ShutdownGrace(t *testing.T) {
+	assert.Equal(t, defaultShutdownGrace, (&Config{}).ShutdownGrace())
+	assert.Equal(t, 2*time.Second, (&Config{ShutdownGraceMs: 2000}).ShutdownGrace())
+}
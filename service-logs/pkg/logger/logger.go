@@ -0,0 +1,266 @@
+// Package logger - Пакет для логирования.
+
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"news-kafka/contracts"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RequestLog структура для лога запроса, формат общий для всех сервисов
+type RequestLog = contracts.LogEntry
+
+// Publisher - дополнительный получатель записанных в файл записей, например, топик логов Kafka.
+// Publish вызывается под блокировкой логгера и не должен ждать отправки.
+type Publisher interface {
+	Publish(entries []RequestLog)
+}
+
+// Options - настройки записи логов в файл
+type Options struct {
+	BufferSize    int           //Количество записей в буфере, при заполнении буфер записывается в файл
+	FlushInterval time.Duration //Интервал записи буфера в файл, 0 - только при заполнении и Close
+	MaxSizeBytes  int64         //Размер файла, после которого он ротируется, 0 - без ротации по размеру
+	Daily         bool          //Ротация файла при смене дня
+	MaxBackups    int           //Количество хранимых сжатых файлов, 0 - хранить все
+}
+
+// DefaultOptions - настройки по умолчанию
+var DefaultOptions = Options{
+	BufferSize:    50,
+	FlushInterval: 5 * time.Second,
+	MaxSizeBytes:  10 << 20,
+	Daily:         true,
+	MaxBackups:    7,
+}
+
+// Logger для записи запросов
+type Logger struct {
+	mu         sync.Mutex
+	logs       []RequestLog
+	bufferSize int
+	opts       Options
+	path       string
+	file       *os.File
+	size       int64  //Размер текущего файла
+	day        string //День записей текущего файла
+	stop       chan struct{}
+	publisher  Publisher
+	wg         sync.WaitGroup //Периодическая запись и сжатие ротированных файлов
+}
+
+// NewLogger создает новый экземпляр логгера, буфер записывается в файл только при заполнении и Close
+func NewLogger(filePath string, bufferSize int) (*Logger, error) {
+	return NewLoggerWithOptions(filePath, Options{BufferSize: bufferSize})
+}
+
+// NewLoggerWithOptions создает новый экземпляр логгера с периодической записью буфера,
+// ротацией файла по размеру и по дням и сжатием ротированных файлов в gzip
+func NewLoggerWithOptions(filePath string, opts Options) (*Logger, error) {
+	if opts.BufferSize < 1 {
+		opts.BufferSize = 1
+	}
+
+	l := &Logger{
+		logs:       make([]RequestLog, 0, opts.BufferSize),
+		bufferSize: opts.BufferSize,
+		opts:       opts,
+		path:       filePath,
+		stop:       make(chan struct{}),
+	}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+
+	if opts.FlushInterval > 0 {
+		l.wg.Add(1)
+		go l.flushEvery(opts.FlushInterval)
+	}
+
+	return l, nil
+}
+
+// LogRequest логирует запрос
+func (l *Logger) LogRequest(requestID, remoteAddr string, statusCode int, dataRequest string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.write(RequestLog{
+		Timestamp:   time.Now(),
+		ServiceID:   GetServiceName(),
+		RequestID:   requestID,
+		RemoteAddr:  remoteAddr,
+		StatusCode:  statusCode,
+		DataRequest: dataRequest,
+	})
+}
+
+// Log записывает подготовленную запись
+func (l *Logger) Log(entry RequestLog) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.write(entry)
+}
+
+// write добавляет запись в буфер, вызывается под блокировкой
+func (l *Logger) write(logEntry RequestLog) {
+	logEntry.Version = contracts.SchemaVersion
+	l.logs = append(l.logs, logEntry)
+
+	// Проверяем, если буфер заполнен. Ошибки записываются сразу,
+	// чтобы не потерять их при аварийном завершении процесса
+	if len(l.logs) >= l.bufferSize || logEntry.Level == slog.LevelError.String() {
+		l.flush()
+	}
+}
+
+// flush записывает логи в файл, ошибки записи выводятся в stderr
+func (l *Logger) flush() {
+	if len(l.logs) == 0 {
+		return
+	}
+
+	for _, log := range l.logs {
+		line, err := json.Marshal(log)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "logger: failed to encode log entry: %v\n", err)
+			continue
+		}
+		line = append(line, '\n')
+
+		if l.file == nil {
+			fmt.Fprintf(os.Stderr, "logger: log file is closed, entry lost: %s", line)
+			continue
+		}
+		if l.needRotate(log.Timestamp, len(line)) {
+			if err := l.rotate(); err != nil {
+				fmt.Fprintf(os.Stderr, "logger: failed to rotate %v: %v\n", l.path, err)
+			}
+		}
+
+		n, err := l.file.Write(line)
+		l.size += int64(n)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "logger: failed to write %v: %v\n", l.path, err)
+		}
+	}
+
+	if l.publisher != nil {
+		l.publisher.Publish(append([]RequestLog(nil), l.logs...))
+	}
+
+	// Очищаем буфер
+	l.logs = l.logs[:0]
+}
+
+// SetPublisher задает получателя записей, nil - только запись в файл
+func (l *Logger) SetPublisher(p Publisher) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.publisher = p
+}
+
+// Flush записывает буфер в файл и передает записи получателю
+func (l *Logger) Flush() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.flush()
+}
+
+// flushEvery - периодическая запись буфера в файл до Close
+func (l *Logger) flushEvery(interval time.Duration) {
+	defer l.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			l.mu.Lock()
+			l.flush()
+			l.mu.Unlock()
+		}
+	}
+}
+
+// Close закрывает логгер и записывает оставшиеся логи.
+// Ожидает завершения сжатия ротированных файлов.
+func (l *Logger) Close() error {
+	l.mu.Lock()
+	if l.file == nil {
+		l.mu.Unlock()
+		return nil
+	}
+	close(l.stop)
+	l.flush() // Записываем оставшиеся записи
+	err := l.file.Close()
+	l.file = nil
+	l.mu.Unlock()
+
+	l.wg.Wait()
+	return err
+}
+
+// GetRequestId возвращает id запроса
+func GetRequestId() string {
+	return uuid.New().String()
+}
+
+// GetServiceName возвращает имя сервиса
+func GetServiceName() string {
+	//Переменные окружения
+	//os.Setenv("NEWSNAMESERVISE", "service-news-001")
+	//fmt.Println("NEWSNAMESERVISE:", os.Getenv("NEWSNAMESERVISE"))
+	return os.Getenv("LOGSNAMESERVISE")
+}
+
+// GetLocalIP возвращает локальный IP-адрес в виде строки
+func GetLocalIP() string {
+	// Получаем список всех адаптеров
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return fmt.Sprintf("%v", err)
+	}
+
+	for _, iface := range interfaces {
+		// Игнорируем отключенные интерфейсы и петлевые интерфейсы
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+
+		// Получаем адреса интерфейса
+		addrs, err := iface.Addrs()
+		if err != nil {
+			return fmt.Sprintf("%v", err)
+		}
+
+		for _, addr := range addrs {
+			var ip net.IP
+			// Получаем IP адрес
+			switch v := addr.(type) {
+			case *net.IPAddr:
+				ip = v.IP
+			case *net.IPNet:
+				ip = v.IP
+			}
+
+			if ip != nil && ip.To4() != nil { // Проверка на IPv4
+				return ip.String()
+			}
+		}
+	}
+	return fmt.Sprintf("%v", "no valid IP address found")
+}
@@ -0,0 +1,183 @@
+package logger
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogger(t *testing.T) {
+	logFile := "test_log.json"
+	logger, err := NewLogger(logFile, 2)
+	if err != nil {
+		t.Fatalf("Error creating logger: %v", err)
+	}
+	defer os.Remove(logFile) // Удаляем файл после тестирования
+	defer logger.Close()
+
+	logger.LogRequest("req1", "192.168.1.1", 200, "{\"key\":\"value1\"}")
+	logger.LogRequest("req2", "192.168.1.1", 404, "{\"key\":\"value2\"}")
+
+	// Вызов potentail flush через закрытие
+	logger.Close()
+
+	// Проверяем, что файл был создан и содержит лог записи
+	if _, err := os.Stat(logFile); os.IsNotExist(err) {
+		t.Fatalf("Log file does not exist: %v", err)
+	}
+}
+
+func TestHandler_RequestLogFormat(t *testing.T) {
+	logFile := "test_slog.json"
+	sink, err := NewLogger(logFile, 10)
+	if err != nil {
+		t.Fatalf("Error creating logger: %v", err)
+	}
+	defer os.Remove(logFile)
+
+	logs := New(sink, slog.LevelInfo)
+	ctx := ContextWith(context.Background(), RequestID("req1"), Topic("news"))
+	logs.DebugContext(ctx, "skipped")
+	logs.ErrorContext(ctx, "failed to process message", Latency(1500*time.Microsecond), Err(errors.New("db error")), slog.Int("attempts", 3))
+	logs.Info("http request", slog.Int(KeyStatusCode, 404), slog.String(KeyRemoteAddr, "10.0.0.1"))
+	sink.Close()
+
+	data, err := os.ReadFile(logFile)
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	assert.Len(t, lines, 2)
+
+	var entry RequestLog
+	assert.NoError(t, json.Unmarshal([]byte(lines[0]), &entry))
+	assert.Equal(t, "req1", entry.RequestID)
+	assert.Equal(t, "news", entry.Topic)
+	assert.Equal(t, "ERROR", entry.Level)
+	assert.Equal(t, 500, entry.StatusCode)
+	assert.Equal(t, 1.5, entry.LatencyMs)
+	assert.Equal(t, "failed to process message: db error", entry.DataRequest)
+	assert.Equal(t, float64(3), entry.Fields["attempts"])
+
+	entry = RequestLog{}
+	assert.NoError(t, json.Unmarshal([]byte(lines[1]), &entry))
+	assert.Empty(t, entry.RequestID)
+	assert.Equal(t, 404, entry.StatusCode)
+	assert.Equal(t, "10.0.0.1", entry.RemoteAddr)
+}
+
+func TestLevelFromEnv(t *testing.T) {
+	t.Setenv(EnvLevel, "debug")
+	assert.Equal(t, slog.LevelDebug, LevelFromEnv())
+
+	t.Setenv(EnvLevel, "")
+	assert.Equal(t, slog.LevelInfo, LevelFromEnv())
+}
+
+func TestLogger_FlushInterval(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "logs.json")
+	logger, err := NewLoggerWithOptions(logFile, Options{BufferSize: 50, FlushInterval: 10 * time.Millisecond})
+	assert.NoError(t, err)
+	defer logger.Close()
+
+	logger.LogRequest("req1", "192.168.1.1", 200, "data")
+
+	// Запись появляется в файле без заполнения буфера и Close
+	assert.Eventually(t, func() bool {
+		data, err := os.ReadFile(logFile)
+		return err == nil && strings.Contains(string(data), "req1")
+	}, time.Second, 10*time.Millisecond)
+}
+
+// publisherFunc - получатель записей для тестов
+type publisherFunc func(entries []RequestLog)
+
+func (f publisherFunc) Publish(entries []RequestLog) { f(entries) }
+
+func TestLogger_Publisher(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "logs.json")
+	logger, err := NewLogger(logFile, 10)
+	assert.NoError(t, err)
+
+	var published []RequestLog
+	logger.SetPublisher(publisherFunc(func(entries []RequestLog) {
+		published = append(published, entries...)
+	}))
+
+	logger.LogRequest("req1", "192.168.1.1", 200, "")
+	assert.Empty(t, published)
+
+	// Записи передаются получателю вместе с записью буфера в файл
+	logger.Flush()
+	logger.LogRequest("req2", "192.168.1.1", 200, "")
+	assert.NoError(t, logger.Close())
+
+	if assert.Len(t, published, 2) {
+		assert.Equal(t, "req1", published[0].RequestID)
+		assert.Equal(t, "req2", published[1].RequestID)
+	}
+}
+
+func TestLogger_RotateBySize(t *testing.T) {
+	dir := t.TempDir()
+	logFile := filepath.Join(dir, "logs.json")
+	logger, err := NewLoggerWithOptions(logFile, Options{BufferSize: 1, MaxSizeBytes: 200, MaxBackups: 2})
+	assert.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		logger.LogRequest(fmt.Sprintf("req%v", i), "192.168.1.1", 200, strings.Repeat("x", 100))
+	}
+	assert.NoError(t, logger.Close())
+
+	// Хранятся только MaxBackups сжатых файлов, последний из них содержит предпоследнюю запись
+	backups, err := filepath.Glob(filepath.Join(dir, "logs-*.json.gz"))
+	assert.NoError(t, err)
+	assert.Len(t, backups, 2)
+	assert.Contains(t, gunzip(t, backups[1]), "req8")
+
+	data, err := os.ReadFile(logFile)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "req9")
+}
+
+func TestLogger_RotateByDay(t *testing.T) {
+	dir := t.TempDir()
+	logFile := filepath.Join(dir, "logs.json")
+	logger, err := NewLoggerWithOptions(logFile, Options{BufferSize: 1, Daily: true})
+	assert.NoError(t, err)
+
+	logger.Log(RequestLog{Timestamp: time.Now().AddDate(0, 0, -1), RequestID: "yesterday"})
+	logger.Log(RequestLog{Timestamp: time.Now(), RequestID: "today"})
+	assert.NoError(t, logger.Close())
+
+	backups, err := filepath.Glob(filepath.Join(dir, "logs-*.json.gz"))
+	assert.NoError(t, err)
+	assert.Len(t, backups, 1)
+	assert.Contains(t, gunzip(t, backups[0]), "yesterday")
+
+	data, err := os.ReadFile(logFile)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "today")
+	assert.NotContains(t, string(data), "yesterday")
+}
+
+func gunzip(t *testing.T, path string) string {
+	t.Helper()
+	f, err := os.Open(path)
+	assert.NoError(t, err)
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	assert.NoError(t, err)
+	data, err := io.ReadAll(zr)
+	assert.NoError(t, err)
+	return string(data)
+}
@@ -0,0 +1,150 @@
+package logger
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Формат дня записей и времени ротации в имени сжатого файла
+const (
+	dayLayout    = "2006-01-02"
+	backupLayout = "2006-01-02T15-04-05.000"
+)
+
+// open - открытие файла логов для дозаписи
+func (l *Logger) open() error {
+	file, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	l.file = file
+	l.size = info.Size()
+	l.day = time.Now().Format(dayLayout)
+	if l.size > 0 {
+		l.day = info.ModTime().Format(dayLayout)
+	}
+	return nil
+}
+
+// needRotate - нужна ли ротация перед записью строки размера n с временем t
+func (l *Logger) needRotate(t time.Time, n int) bool {
+	if l.size == 0 {
+		l.day = t.Format(dayLayout)
+		return false
+	}
+	if l.opts.MaxSizeBytes > 0 && l.size+int64(n) > l.opts.MaxSizeBytes {
+		return true
+	}
+	return l.opts.Daily && t.Format(dayLayout) != l.day
+}
+
+// rotate - переименование текущего файла, сжатие его в фоне и открытие нового файла
+func (l *Logger) rotate() error {
+	if err := l.file.Close(); err != nil {
+		return err
+	}
+	l.file = nil
+
+	backup := l.backupName(time.Now())
+	if err := os.Rename(l.path, backup); err != nil {
+		// Продолжаем запись в прежний файл
+		if openErr := l.open(); openErr != nil {
+			return fmt.Errorf("%v: %w", err, openErr)
+		}
+		return err
+	}
+
+	l.wg.Add(1)
+	go func() {
+		defer l.wg.Done()
+		if err := compress(backup); err != nil {
+			fmt.Fprintf(os.Stderr, "logger: failed to compress %v: %v\n", backup, err)
+			return
+		}
+		l.removeOldBackups()
+	}()
+
+	return l.open()
+}
+
+// backupName - имя ротированного файла: logs.json -> logs-2006-01-02T15-04-05.000.json.
+// При повторной ротации в ту же миллисекунду время увеличивается, чтобы имена
+// оставались уникальными и упорядоченными.
+func (l *Logger) backupName(t time.Time) string {
+	ext := filepath.Ext(l.path)
+	for {
+		name := strings.TrimSuffix(l.path, ext) + "-" + t.Format(backupLayout) + ext
+		if !exists(name) && !exists(name+".gz") {
+			return name
+		}
+		t = t.Add(time.Millisecond)
+	}
+}
+
+// exists - проверка существования файла
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// removeOldBackups - удаление сжатых файлов сверх MaxBackups, начиная с самых старых
+func (l *Logger) removeOldBackups() {
+	if l.opts.MaxBackups <= 0 {
+		return
+	}
+
+	ext := filepath.Ext(l.path)
+	backups, err := filepath.Glob(strings.TrimSuffix(l.path, ext) + "-*" + ext + ".gz")
+	if err != nil || len(backups) <= l.opts.MaxBackups {
+		return
+	}
+
+	// Время ротации в имени файла упорядочено как строка
+	sort.Strings(backups)
+	for _, backup := range backups[:len(backups)-l.opts.MaxBackups] {
+		if err := os.Remove(backup); err != nil && !os.IsNotExist(err) {
+			fmt.Fprintf(os.Stderr, "logger: failed to remove %v: %v\n", backup, err)
+		}
+	}
+}
+
+// compress - сжатие файла в gzip и удаление исходного файла
+func compress(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	zw := gzip.NewWriter(dst)
+	if _, err := io.Copy(zw, src); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+
+	return os.Remove(path)
+}
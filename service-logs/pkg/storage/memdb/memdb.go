@@ -0,0 +1,91 @@
+// Package memdb - хранилище записей лога в памяти для тестов
+package memdb
+
+import (
+	"context"
+	"news-kafka/service-logs/pkg/storage"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Хранилище данных.
+type Store struct {
+	mu      sync.Mutex
+	entries []storage.Entry
+}
+
+// Конструктор объекта хранилища.
+func New() *Store {
+	return &Store{}
+}
+
+func (s *Store) GetInform() string {
+	return "Memory"
+}
+
+// Ping - хранилище в памяти всегда доступно
+func (s *Store) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (s *Store) Close() {}
+
+// AddLog сохраняет запись лога.
+func (s *Store) AddLog(ctx context.Context, entry storage.Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries = append(s.entries, entry)
+	return nil
+}
+
+// LogsByRequestID возвращает записи всех сервисов по запросу в порядке времени.
+func (s *Store) LogsByRequestID(ctx context.Context, requestID string) ([]storage.Entry, error) {
+	s.mu.Lock()
+	var entries []storage.Entry
+	for _, e := range s.entries {
+		if e.RequestID == requestID {
+			entries = append(entries, e)
+		}
+	}
+	s.mu.Unlock()
+
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Timestamp.Before(entries[j].Timestamp) })
+	return entries, nil
+}
+
+// SearchLogs возвращает записи по условиям поиска, новые первыми.
+func (s *Store) SearchLogs(ctx context.Context, filter storage.Filter) ([]storage.Entry, error) {
+	s.mu.Lock()
+	var entries []storage.Entry
+	for _, e := range s.entries {
+		if match(e, filter) {
+			entries = append(entries, e)
+		}
+	}
+	s.mu.Unlock()
+
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Timestamp.After(entries[j].Timestamp) })
+	if len(entries) > filter.PageLimit() {
+		entries = entries[:filter.PageLimit()]
+	}
+	return entries, nil
+}
+
+// match - проверка записи на соответствие условиям поиска
+func match(e storage.Entry, f storage.Filter) bool {
+	switch {
+	case f.Service != "" && !strings.HasPrefix(e.ServiceID, f.Service):
+		return false
+	case f.StatusCode != 0 && e.StatusCode != f.StatusCode:
+		return false
+	case f.Level != "" && !strings.EqualFold(e.Level, f.Level):
+		return false
+	case !f.From.IsZero() && e.Timestamp.Before(f.From):
+		return false
+	case !f.To.IsZero() && !e.Timestamp.Before(f.To):
+		return false
+	}
+	return true
+}
@@ -0,0 +1,179 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"news-kafka/service-logs/pkg/storage"
+	"strings"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// Хранилище данных.
+type Store struct {
+	db *pgxpool.Pool
+}
+
+func (s *Store) GetInform() string {
+	return "PostgreSQL"
+}
+
+// Конструктор объекта хранилища.
+func New(constr string) (*Store, error) {
+	db, err := pgxpool.Connect(context.Background(), constr)
+	if err != nil {
+		return nil, err
+	}
+	s := Store{
+		db: db,
+	}
+
+	fmt.Println("Loaded bd: ", s.GetInform())
+
+	return &s, nil
+}
+
+func (s *Store) Close() {
+	s.db.Close()
+}
+
+// Ping проверяет доступность БД через пул соединений.
+func (s *Store) Ping(ctx context.Context) error {
+	return s.db.Ping(ctx)
+}
+
+// Поля записи в порядке сканирования
+const columns = `timestamp, service_id, request_id, remote_addr, status_code, data_request, level, topic, latency_ms, fields`
+
+// AddLog сохраняет запись лога в БД.
+func (s *Store) AddLog(ctx context.Context, entry storage.Entry) error {
+	// Пустые поля сохраняются как NULL
+	var fields []byte
+	if len(entry.Fields) > 0 {
+		var err error
+		fields, err = json.Marshal(entry.Fields)
+		if err != nil {
+			return fmt.Errorf("failed to encode fields: %w", err)
+		}
+	}
+
+	_, err := s.db.Exec(ctx, `
+		INSERT INTO logs(`+columns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10);`,
+		entry.Timestamp,
+		entry.ServiceID,
+		entry.RequestID,
+		entry.RemoteAddr,
+		entry.StatusCode,
+		entry.DataRequest,
+		entry.Level,
+		entry.Topic,
+		entry.LatencyMs,
+		fields,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert row: %w", err)
+	}
+	return nil
+}
+
+// LogsByRequestID возвращает записи всех сервисов по запросу в порядке времени.
+func (s *Store) LogsByRequestID(ctx context.Context, requestID string) ([]storage.Entry, error) {
+	rows, err := s.db.Query(ctx, `
+	 SELECT `+columns+`
+	 FROM logs
+	 WHERE request_id = $1
+	 ORDER BY timestamp, id
+	`, requestID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query: %w", err)
+	}
+	return scanEntries(rows)
+}
+
+// SearchLogs возвращает записи по условиям поиска, новые первыми.
+func (s *Store) SearchLogs(ctx context.Context, filter storage.Filter) ([]storage.Entry, error) {
+	var (
+		where []string
+		args  []any
+	)
+	add := func(cond string, value any) {
+		args = append(args, value)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+
+	if filter.Service != "" {
+		add(`service_id LIKE $%d`, likePrefix(filter.Service))
+	}
+	if filter.StatusCode != 0 {
+		add(`status_code = $%d`, filter.StatusCode)
+	}
+	if filter.Level != "" {
+		add(`level = upper($%d)`, filter.Level)
+	}
+	if !filter.From.IsZero() {
+		add(`timestamp >= $%d`, filter.From)
+	}
+	if !filter.To.IsZero() {
+		add(`timestamp < $%d`, filter.To)
+	}
+
+	query := `SELECT ` + columns + ` FROM logs`
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, ` AND `)
+	}
+	args = append(args, filter.PageLimit())
+	query += fmt.Sprintf(` ORDER BY timestamp DESC, id DESC LIMIT $%d`, len(args))
+
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query: %w", err)
+	}
+	return scanEntries(rows)
+}
+
+// scanEntries - чтение записей из результата запроса
+func scanEntries(rows pgx.Rows) ([]storage.Entry, error) {
+	defer rows.Close()
+
+	var entries []storage.Entry
+	for rows.Next() {
+		var (
+			e      storage.Entry
+			fields []byte
+		)
+		err := rows.Scan(
+			&e.Timestamp,
+			&e.ServiceID,
+			&e.RequestID,
+			&e.RemoteAddr,
+			&e.StatusCode,
+			&e.DataRequest,
+			&e.Level,
+			&e.Topic,
+			&e.LatencyMs,
+			&fields,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		if len(fields) > 0 {
+			if err := json.Unmarshal(fields, &e.Fields); err != nil {
+				return nil, fmt.Errorf("failed to decode fields: %w", err)
+			}
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rows: %w", err)
+	}
+
+	return entries, nil
+}
+
+// likePrefix - шаблон LIKE для поиска по началу строки
+func likePrefix(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s) + "%"
+}
@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"context"
+	"news-kafka/contracts"
+	"time"
+)
+
+// Запись лога api-gateway или сервиса
+type Entry = contracts.LogEntry
+
+// Количество записей в ответе поиска
+const (
+	DefaultLimit = 100
+	MaxLimit     = 1000
+)
+
+// Filter - условия поиска записей, пустые поля не ограничивают поиск
+type Filter struct {
+	Service    string    //Имя сервиса или экземпляра: service-news находит записи service-news-001
+	StatusCode int       //Код статуса записи
+	Level      string    //Уровень записи: DEBUG, INFO, WARN, ERROR
+	From       time.Time //Начало интервала, включительно
+	To         time.Time //Конец интервала, не включая
+	Limit      int       //Количество записей, по умолчанию DefaultLimit, не более MaxLimit
+}
+
+// PageLimit - количество записей в ответе с учетом значения по умолчанию и ограничения
+func (f Filter) PageLimit() int {
+	if f.Limit <= 0 {
+		return DefaultLimit
+	}
+	if f.Limit > MaxLimit {
+		return MaxLimit
+	}
+	return f.Limit
+}
+
+// Interface задаёт контракт на работу с БД.
+type Interface interface {
+	GetInform() string
+	Close()
+	Ping(ctx context.Context) error // Проверка доступности БД.
+
+	AddLog(ctx context.Context, entry Entry) error                          // Сохраняет запись лога.
+	LogsByRequestID(ctx context.Context, requestID string) ([]Entry, error) // Записи всех сервисов по запросу, в порядке времени.
+	SearchLogs(ctx context.Context, filter Filter) ([]Entry, error)         // Поиск записей, новые первыми.
+}
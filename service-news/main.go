@@ -5,19 +5,24 @@ import (
 	"encoding/json"
 	"errors"
 	"io/ioutil"
+	"log/slog"
+	"net/http"
+	"news-kafka/service-news/pkg/handler"
 	"news-kafka/service-news/pkg/kafka"
 	"news-kafka/service-news/pkg/logger"
+	"news-kafka/service-news/pkg/metrics"
 	"news-kafka/service-news/pkg/rss"
 	"news-kafka/service-news/pkg/storage"
 	"news-kafka/service-news/pkg/storage/postgres"
+	"news-kafka/service-news/pkg/tracing"
 	"os"
+	"os/signal"
 	"sync"
+	"syscall"
 	"time"
 
 	"fmt"
 	"log"
-
-	"github.com/IBM/sarama"
 )
 
 type ConfigRSS struct {
@@ -33,31 +38,6 @@ type server struct {
 	db storage.Interface
 }
 
-// service-news
-// Cтруктура для отправки данных -> service-news
-type SendMessServiceNews struct {
-	ID        string           `json:"id"`
-	Name      string           `json:"name"`
-	Status    int              `json:"status"`
-	TypeQuery string           `json:"type_query"`
-	News      []storage.News   `json:"news"`
-	Paginate  storage.Paginate `json:"paginate"`
-	IdNews    int              `json:"id_news"`
-}
-
-// Cтруктура для получения данных <- service-news
-type GetMessServiceNews struct {
-	ID        string `json:"id"`
-	Name      string `json:"name"`
-	Status    int    `json:"status"`
-	TypeQuery string `json:"type_query"`
-	Rubric    string `json:"rubric"`
-	CountNews int    `json:"count_news"`
-	Filter    string `json:"filter"`
-	Page      int    `json:"page"`
-	IdNews    int    `json:"id_news"`
-}
-
 func main() {
 
 	fmt.Println("service-news:", logger.GetServiceName())
@@ -69,11 +49,24 @@ func main() {
 	//==============================================
 	//Logger
 	//==============================================
-	logs, err := logger.NewLogger("logs.json", 50)
+	// Записи в формате JSON Lines, уровень в переменной окружения LOGLEVEL.
+	// Буфер записывается каждые 5 с, файл ротируется по размеру и по дням
+	sink, err := logger.NewLoggerWithOptions("logs.json", logger.DefaultOptions)
 	if err != nil {
-		fmt.Printf("Error creating logger: %v", err)
+		log.Fatalf("Error creating logger: %v", err)
 	}
-	defer logs.Close()
+	defer sink.Close()
+	logs := logger.New(sink, logger.LevelFromEnv())
+
+	//==============================================
+	//Tracing
+	//==============================================
+	// Экспорт трассировки настраивается переменной окружения OTEL_TRACES_EXPORTER
+	shutdownTracing, err := tracing.Init(context.Background(), "service-news")
+	if err != nil {
+		log.Fatalf("Failed to init tracing: %v", err)
+	}
+	defer shutdownTracing(context.Background())
 
 	//==============================================
 	//Kafka
@@ -91,17 +84,36 @@ func main() {
 	}
 	defer kafkaProducer.Close()
 
-	kafkaConsumer, err := kafka.NewConsumer(config.KafkaBrokers)
-	if err != nil {
-		log.Fatalf("Failed to create Kafka consumer: %v", err)
+	// Записи лога также публикуются в топик логов, из которого их забирает service-logs
+	if config.TopicLogs != "" {
+		logPublisher := kafka.NewLogPublisher(kafkaProducer, config.TopicLogs)
+		sink.SetPublisher(logPublisher)
+		defer func() {
+			// Записи, сделанные до остановки, отправляются до закрытия producer
+			sink.Flush()
+			sink.SetPublisher(nil)
+			logPublisher.Close()
+		}()
+	}
+
+	deadLetters := kafka.NewDeadLetterQueue(config.KafkaBrokers, config.TopicDeadLetter, kafkaProducer)
+
+	// Просмотр и повторная отправка сообщений из dead-letter топика:
+	// service-news dlq list [limit] | dlq redrive all | dlq redrive partition:offset ...
+	if len(os.Args) > 1 && os.Args[1] == "dlq" {
+		if err := deadLetters.RunCommand(os.Args[2:], os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
 	}
-	defer kafkaConsumer.Close()
 
-	// Запуск гоурутины для потребления сообщений
-	responseCh, err := kafkaConsumer.Consume(config.TopicResponse, 0, sarama.OffsetNewest)
+	// Потребитель в составе группы: смещения фиксируются после обработки,
+	// поэтому запросы, пришедшие во время перезапуска, не теряются
+	kafkaConsumer, err := kafka.NewGroupConsumer(config.KafkaBrokers, config.ConsumerGroup, config.ShutdownGrace(), logs)
 	if err != nil {
-		log.Fatalf("Failed to consume partition: %v", err)
+		log.Fatalf("Failed to create Kafka consumer: %v", err)
 	}
+	defer kafkaConsumer.Close()
 
 	//==============================================
 	//PostgreSQL
@@ -133,44 +145,118 @@ func main() {
 	}
 
 	newsChannel := make(chan []storage.News)
-	errorChannel := make(chan error)
+	// время последней успешной загрузки RSS для ответа на Ping
+	feeds := &rss.Status{}
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel() // cancel when we are finished consuming integers
+	// Остановка по SIGINT/SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
-	var wg sync.WaitGroup
-	wg.Add(4)
+	// Запись в БД, начатая до остановки, прерывается не сразу с ctx, а по истечении shutdown_grace_ms
+	writeCtx, cancelWrites := context.WithCancel(context.Background())
+	defer cancelWrites()
+	// Потоки чтения RSS и записи в БД, пул БД закрывается только после их завершения
+	var workers sync.WaitGroup
 
 	// парсим rss, каждую ссылку в отдельном потоке
-	go getNewsFromAllRSS(ctx, configRSS, newsChannel, errorChannel)
+	getNewsFromAllRSS(ctx, &workers, configRSS, feeds, newsChannel, logs)
 	// записываем информацию по каждой ссылке в бд
-	go writeNewsToDB(ctx, srv.db, newsChannel, errorChannel)
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		writeNewsToDB(ctx, writeCtx, srv.db, newsChannel, logs)
+	}()
 	// обрабатываем данные полученные из kafak
-	go readNewsFromDB(ctx, srv.db, kafkaProducer, config, responseCh, errorChannel)
-	// выводим ошибки
-	go handleErrors(ctx, errorChannel, logs)
+	// Повторная обработка с паузами, после исчерпания попыток - в dead-letter топик
+	pipeline := kafka.Pipeline{
+		Policy:      config.Retry,
+		DeadLetters: deadLetters,
+		OnFailure:   handler.ReplyFailure(kafkaProducer, config, logs),
+		Logs:        logs,
+	}
+	consumed := make(chan struct{})
+	go func() {
+		defer close(consumed)
+		err := kafkaConsumer.Consume(ctx, []string{config.TopicResponse}, pipeline.Handler(handler.New(srv.db, feeds, kafkaProducer, config, logs)))
+		if err != nil {
+			logs.Error("kafka consumer stopped", logger.Err(err))
+		}
+	}()
+	// метрики Prometheus: http://<host>:9100/metrics (адрес в переменной окружения METRICSADDR)
+	metricsServer := metrics.NewServer(metrics.Addr())
+	go func() {
+		if err := metricsServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logs.Error("metrics server stopped", logger.Err(err))
+		}
+	}()
+
+	// Ожидание SIGINT/SIGTERM
+	<-ctx.Done()
+	stop()
+	logs.Info("shutting down")
+	stopWrites := time.AfterFunc(config.ShutdownGrace(), cancelWrites)
+	defer stopWrites.Stop()
+
+	// Чтение Kafka остановлено отменой ctx. Текущие сообщения обрабатываются
+	// не дольше shutdown_grace_ms, затем прерываются без фиксации смещения.
+	select {
+	case <-consumed:
+	case <-time.After(config.ShutdownGrace() + time.Second):
+		logs.Warn("kafka consumer did not stop in time")
+	}
+
+	// Чтение RSS останавливается отменой ctx, текущая запись в БД - не позже shutdown_grace_ms
+	workersDone := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(workersDone)
+	}()
+	select {
+	case <-workersDone:
+	case <-time.After(config.ShutdownGrace() + time.Second):
+		logs.Warn("rss workers did not stop in time")
+	}
 
-	wg.Wait()
-	//select {}
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), config.ShutdownGrace())
+	defer cancelShutdown()
+	if err := metricsServer.Shutdown(shutdownCtx); err != nil {
+		logs.Warn("failed to shut down metrics server", logger.Err(err))
+	}
+
+	// Далее в defer: закрытие пула БД, consumer и producer, запись буфера логгера
 }
 
-func getNewsFromAllRSS(ctx context.Context, configRSS ConfigRSS, news chan<- []storage.News, errs chan<- error) {
+// getNewsFromAllRSS - запуск чтения каждой ссылки в отдельном потоке, учтённом в workers.
+// Потоки завершаются при отмене ctx, в том числе во время отправки в news.
+func getNewsFromAllRSS(ctx context.Context, workers *sync.WaitGroup, configRSS ConfigRSS, feeds *rss.Status, news chan<- []storage.News, logs *slog.Logger) {
 	for rubric, value := range configRSS.RSS {
 		for _, link := range value.Link {
+			workers.Add(1)
 			go func(url, rubric, image string) {
+				defer workers.Done()
 				for {
 					select {
 					case <-ctx.Done(): // context checking
 						return // returning not to leak the goroutine
 					default:
 						newsResp, err := rss.GetNewsFromRss(url, rubric, image)
+						metrics.RSSFetches.WithLabelValues(url, metrics.Result(err)).Inc()
 						if err != nil {
-							errs <- err
+							logs.Error("failed to fetch rss", slog.String("feed", url), logger.Err(err))
 						} else {
-							news <- newsResp
+							feeds.Success(time.Now())
+							select {
+							case news <- newsResp:
+							case <-ctx.Done():
+								return
+							}
 						}
 
-						time.Sleep(time.Minute * time.Duration(configRSS.Duration))
+						select {
+						case <-ctx.Done():
+							return
+						case <-time.After(time.Minute * time.Duration(configRSS.Duration)):
+						}
 					}
 				}
 			}(link, rubric, value.Image)
@@ -178,115 +264,17 @@ func getNewsFromAllRSS(ctx context.Context, configRSS ConfigRSS, news chan<- []s
 	}
 }
 
-func readNewsFromDB(ctx context.Context, db storage.Interface, producer *kafka.Producer, config *kafka.Config, responseCh <-chan *sarama.ConsumerMessage, errs chan<- error) {
-	for msg := range responseCh {
-		select {
-		case <-ctx.Done():
-			return
-		default:
-
-			// Обработка входящего сообщения
-			var receivedMessage GetMessServiceNews
-			err := json.Unmarshal(msg.Value, &receivedMessage)
-			if err != nil {
-				errs <- err
-			}
-
-			//пишем запрос данных в лог
-			var errMsg error = receivedMessage
-			errs <- errMsg
-
-			responseMessage := SendMessServiceNews{
-				ID:        receivedMessage.ID,
-				Name:      logger.GetServiceName(),
-				TypeQuery: receivedMessage.TypeQuery,
-				Status:    0,
-				News:      nil,
-				Paginate:  storage.Paginate{},
-				IdNews:    receivedMessage.IdNews,
-			}
-
-			switch receivedMessage.TypeQuery {
-			case "News":
-				// Обработка запроса, например, запрос к БД
-				news, paginate, err := db.News(receivedMessage.Rubric, receivedMessage.CountNews, receivedMessage.Filter, receivedMessage.Page)
-				if err != nil {
-					errs <- err
-				} else {
-					responseMessage.Status = 192
-					responseMessage.News = news
-					responseMessage.Paginate = paginate
-				}
-
-				bytesMessage, err := json.Marshal(responseMessage)
-				if err != nil {
-					errs <- err
-				}
-
-				err = producer.SendMessage(config.TopicReceived, responseMessage.ID, bytesMessage)
-				if err != nil {
-					errs <- err
-				}
-
-			case "OneNews":
-				// Обработка запроса, например, запрос к БД
-				var news []storage.News
-				newsOne, err := db.NewsOne(receivedMessage.IdNews)
-				if err != nil {
-					errs <- err
-				} else {
-					news = append(news, newsOne)
-					responseMessage.Status = 192
-					responseMessage.News = news
-				}
-
-				bytesMessage, err := json.Marshal(responseMessage)
-				if err != nil {
-					errs <- err
-				}
-
-				err = producer.SendMessage(config.TopicReceivedOneNews, responseMessage.ID, bytesMessage)
-				if err != nil {
-					errs <- err
-				}
-
-			}
-
-		}
-	}
-}
-
-func writeNewsToDB(ctx context.Context, db storage.Interface, news <-chan []storage.News, errs chan<- error) {
-	for newsBatch := range news {
+// writeNewsToDB - запись новостей из news в БД до отмены ctx. Запись выполняется с writeCtx,
+// поэтому начатая запись не прерывается отменой ctx, а завершается до отмены writeCtx.
+func writeNewsToDB(ctx, writeCtx context.Context, db storage.Interface, news <-chan []storage.News, logs *slog.Logger) {
+	for {
 		select {
 		case <-ctx.Done():
 			return
-		default:
-			err := db.AddNew(newsBatch)
-			if err != nil {
-				errs <- err
-				continue
+		case newsBatch := <-news:
+			if err := db.AddNew(writeCtx, newsBatch); err != nil {
+				logs.Error("failed to write news", logger.Err(err))
 			}
 		}
 	}
 }
-
-func handleErrors(ctx context.Context, errs <-chan error, logs *logger.Logger) {
-	for err := range errs {
-		select {
-		case <-ctx.Done():
-			return
-		default:
-			logs.LogRequest(logger.GetRequestId(), logger.GetLocalIP(), 500, err.Error())
-		}
-	}
-}
-
-// Метод для реализации интерфейса error
-func (g GetMessServiceNews) Error() string {
-	jsonData, err := json.Marshal(g)
-	if err != nil {
-		return "Ошибка при преобразовании в JSON"
-	}
-	return string(jsonData)
-}
@@ -0,0 +1,200 @@
+// Package handler - обработка запросов к новостям, полученных из Kafka
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"news-kafka/contracts"
+	"news-kafka/service-news/pkg/kafka"
+	"news-kafka/service-news/pkg/logger"
+	"news-kafka/service-news/pkg/rss"
+	"news-kafka/service-news/pkg/storage"
+	"strconv"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// New - обработчик запросов к новостям, полученных из Kafka.
+// Возвращаемая ошибка приводит к повторной обработке сообщения.
+func New(db storage.Interface, feeds *rss.Status, producer kafka.ProducerInterface, config *kafka.Config, logs *slog.Logger) kafka.MessageHandler {
+	return func(ctx context.Context, msg *sarama.ConsumerMessage) error {
+		// Обработка входящего сообщения
+		// Ответ кодируется в том же формате, что и запрос
+		codec, err := kafka.MessageCodec(msg)
+		if err != nil {
+			return kafka.Permanent(err)
+		}
+		var receivedMessage contracts.NewsRequest
+		err = codec.Unmarshal(msg.Value, &receivedMessage)
+		if err != nil {
+			return kafka.Permanent(fmt.Errorf("failed to decode message: %w", err))
+		}
+		md := requestMetadata(ctx, &receivedMessage)
+		ctx = kafka.ContextWithMetadata(ctx, md)
+		ctx = logger.ContextWith(ctx, logger.RequestID(md.RequestID))
+
+		//пишем запрос данных в лог, кроме периодических Ping
+		if receivedMessage.TypeQuery != contracts.TypePing {
+			logMessage(ctx, logs, config.Redactor(), receivedMessage)
+		}
+
+		// Ответ на просроченный запрос api-gateway уже не ждет
+		if kafka.Expired(md.Deadline) {
+			logs.WarnContext(ctx, "deadline exceeded, message skipped", slog.String("type_query", receivedMessage.TypeQuery))
+			return nil
+		}
+		ctx, cancel := kafka.DeadlineContext(ctx, md.Deadline)
+		defer cancel()
+
+		responseMessage := contracts.NewsReply{
+			ID:        md.RequestID,
+			Name:      logger.GetServiceName(),
+			TypeQuery: receivedMessage.TypeQuery,
+			Reply:     contracts.ReplyOK(),
+			News:      nil,
+			Paginate:  contracts.Paginate{},
+			IdNews:    receivedMessage.IdNews,
+		}
+
+		switch receivedMessage.TypeQuery {
+		case contracts.TypeNews:
+			// Обработка запроса, например, запрос к БД
+			news, paginate, err := db.News(ctx, receivedMessage.Rubric, receivedMessage.CountNews, receivedMessage.Filter, receivedMessage.Page)
+			if err != nil {
+				return dbError(ctx, err, logs)
+			}
+			responseMessage.News = news
+			responseMessage.Paginate = paginate
+
+			return sendReply(ctx, producer, codec, replyTopic(md.ReplyTo, config.TopicReceived), &responseMessage)
+
+		case contracts.TypeOneNews:
+			// Обработка запроса, например, запрос к БД
+			newsOne, err := db.NewsOne(ctx, receivedMessage.IdNews)
+			if errors.Is(err, storage.ErrNotFound) {
+				responseMessage.Reply = contracts.ReplyFail(contracts.StatusNotFound, "news not found", map[string]string{"id_news": strconv.Itoa(receivedMessage.IdNews)})
+			} else if err != nil {
+				return dbError(ctx, err, logs)
+			} else {
+				responseMessage.News = []contracts.News{newsOne}
+			}
+
+			return sendReply(ctx, producer, codec, replyTopic(md.ReplyTo, config.TopicReceivedOneNews), &responseMessage)
+
+		case contracts.TypePing:
+			responseMessage.Reply = ping(ctx, db, feeds)
+			return sendReply(ctx, producer, codec, replyTopic(md.ReplyTo, config.TopicReceived), &responseMessage)
+		}
+
+		return kafka.Permanent(fmt.Errorf("unknown type_query: %v", receivedMessage.TypeQuery))
+	}
+}
+
+// ping - состояние сервиса для api-gateway: пул соединений PostgreSQL и последняя загрузка RSS
+func ping(ctx context.Context, db storage.Interface, feeds *rss.Status) contracts.Reply {
+	details := map[string]string{contracts.PingPostgres: "ok", contracts.PingRSSLastSuccess: ""}
+	if last := feeds.LastSuccess(); !last.IsZero() {
+		details[contracts.PingRSSLastSuccess] = last.UTC().Format(time.RFC3339)
+	}
+
+	if err := db.Ping(ctx); err != nil {
+		details[contracts.PingPostgres] = err.Error()
+		return contracts.ReplyFail(contracts.StatusInternal, "postgres unavailable", details)
+	}
+	reply := contracts.ReplyOK()
+	reply.Details = details
+	return reply
+}
+
+// dbError - ошибка БД приводит к повторной обработке сообщения,
+// кроме случая, когда истек дедлайн запроса и ответ уже никто не ждет
+func dbError(ctx context.Context, err error, logs *slog.Logger) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		logs.WarnContext(ctx, "deadline exceeded", logger.Err(err))
+		return nil
+	}
+	return err
+}
+
+// ReplyFailure - ответ со статусом internal на запрос, который не удалось обработать,
+// чтобы api-gateway не ждал ответа до таймаута
+func ReplyFailure(producer kafka.ProducerInterface, config *kafka.Config, logs *slog.Logger) func(ctx context.Context, msg *sarama.ConsumerMessage, err error) {
+	return func(ctx context.Context, msg *sarama.ConsumerMessage, err error) {
+		codec, err := kafka.MessageCodec(msg)
+		if err != nil {
+			return
+		}
+		var receivedMessage contracts.NewsRequest
+		if codec.Unmarshal(msg.Value, &receivedMessage) != nil {
+			return
+		}
+		md := requestMetadata(ctx, &receivedMessage)
+		ctx = kafka.ContextWithMetadata(ctx, md)
+		ctx = logger.ContextWith(ctx, logger.RequestID(md.RequestID))
+
+		responseMessage := contracts.NewsReply{
+			ID:        md.RequestID,
+			Name:      logger.GetServiceName(),
+			TypeQuery: receivedMessage.TypeQuery,
+			Reply:     contracts.ReplyFail(contracts.StatusInternal, "internal service error", nil),
+			IdNews:    receivedMessage.IdNews,
+		}
+
+		topic := config.TopicReceived
+		if receivedMessage.TypeQuery == contracts.TypeOneNews {
+			topic = config.TopicReceivedOneNews
+		}
+
+		if err := sendReply(ctx, producer, codec, replyTopic(md.ReplyTo, topic), &responseMessage); err != nil {
+			logs.ErrorContext(ctx, "failed to send failure reply", logger.Err(err))
+		}
+	}
+}
+
+// sendReply - отправка ответа в Kafka с идентификатором запроса и спаном обработки из контекста
+func sendReply(ctx context.Context, producer kafka.ProducerInterface, codec contracts.Codec, topic string, responseMessage *contracts.NewsReply) error {
+	md := kafka.MetadataFromContext(ctx)
+	ctx = kafka.ContextWithMetadata(ctx, kafka.Metadata{
+		RequestID: md.RequestID,
+		Source:    logger.GetServiceName(),
+	})
+
+	msg, err := kafka.NewMessage(ctx, codec, topic, responseMessage)
+	if err != nil {
+		return kafka.Permanent(err)
+	}
+	return producer.Send(ctx, msg)
+}
+
+// requestMetadata - метаданные запроса из заголовков сообщения (контекст Pipeline).
+// Отправители предыдущих версий передают их только в теле сообщения.
+func requestMetadata(ctx context.Context, receivedMessage *contracts.NewsRequest) kafka.Metadata {
+	return kafka.MetadataFromContext(ctx).WithDefaults(kafka.Metadata{
+		RequestID: receivedMessage.ID,
+		ReplyTo:   receivedMessage.ReplyTo,
+		Deadline:  receivedMessage.Deadline,
+		Source:    receivedMessage.Name,
+	})
+}
+
+// replyTopic - топик для ответа: reply_to из запроса или топик из конфигурации
+func replyTopic(replyTo, defaultTopic string) string {
+	if replyTo != "" {
+		return replyTo
+	}
+	return defaultTopic
+}
+
+// logMessage - запись запроса в лог, тело запроса в формате JSON передается в поле body
+// после скрытия данных по правилам redact
+func logMessage(ctx context.Context, logs *slog.Logger, redact *logger.Redactor, msg interface{}) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		data = []byte(fmt.Sprintf("%+v", msg))
+	}
+	logs.InfoContext(ctx, "request received", redact.Body(data))
+}
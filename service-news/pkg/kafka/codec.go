@@ -0,0 +1,55 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"news-kafka/contracts"
+
+	"github.com/IBM/sarama"
+)
+
+// HeaderContentType - заголовок Kafka с форматом кодирования сообщения
+const HeaderContentType = "content-type"
+
+// NewMessage - кодирование сообщения для отправки в Kafka.
+// Метаданные запроса и текущий спан из контекста передаются в заголовках,
+// ключ сообщения - идентификатор запроса.
+func NewMessage(ctx context.Context, codec contracts.Codec, topic string, msg contracts.Message) (*sarama.ProducerMessage, error) {
+	value, err := codec.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode message: %w", err)
+	}
+	md := MetadataFromContext(ctx)
+	if tp := traceparent(ctx); tp != "" {
+		md.Traceparent = tp
+	}
+	md.SchemaVersion = contracts.SchemaVersion
+	md.ContentType = codec.ContentType()
+	return &sarama.ProducerMessage{
+		Topic:   topic,
+		Key:     sarama.StringEncoder(md.RequestID),
+		Value:   sarama.ByteEncoder(value),
+		Headers: md.headers(),
+	}, nil
+}
+
+// MessageCodec - кодек по заголовку content-type сообщения.
+// Сообщения без заголовка закодированы в JSON.
+func MessageCodec(msg *sarama.ConsumerMessage) (contracts.Codec, error) {
+	contentType := ""
+	for _, h := range msg.Headers {
+		if h != nil && string(h.Key) == HeaderContentType {
+			contentType = string(h.Value)
+		}
+	}
+	return contracts.CodecFor(contentType)
+}
+
+// Decode - декодирование сообщения Kafka в формате из заголовка content-type
+func Decode(msg *sarama.ConsumerMessage, v contracts.Message) error {
+	codec, err := MessageCodec(msg)
+	if err != nil {
+		return err
+	}
+	return codec.Unmarshal(msg.Value, v)
+}
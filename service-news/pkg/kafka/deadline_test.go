@@ -0,0 +1,33 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpired(t *testing.T) {
+	assert.False(t, Expired(0))
+	assert.False(t, Expired(time.Now().Add(time.Minute).UnixMilli()))
+	assert.True(t, Expired(time.Now().Add(-time.Millisecond).UnixMilli()))
+}
+
+func TestDeadlineContext(t *testing.T) {
+	ctx, cancel := DeadlineContext(context.Background(), 0)
+	defer cancel()
+	_, ok := ctx.Deadline()
+	assert.False(t, ok)
+
+	deadline := time.Now().Add(time.Minute).UnixMilli()
+	ctx, cancel = DeadlineContext(context.Background(), deadline)
+	defer cancel()
+	got, ok := ctx.Deadline()
+	assert.True(t, ok)
+	assert.Equal(t, deadline, got.UnixMilli())
+
+	ctx, cancel = DeadlineContext(context.Background(), time.Now().Add(-time.Second).UnixMilli())
+	defer cancel()
+	assert.ErrorIs(t, ctx.Err(), context.DeadlineExceeded)
+}
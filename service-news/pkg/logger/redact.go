@@ -0,0 +1,195 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Redacted - замена скрытых значений в логе
+const Redacted = "[REDACTED]"
+
+// RedactRules - правила скрытия персональных данных в теле запроса и адресе клиента перед записью в лог
+type RedactRules struct {
+	Fields       []string `json:"fields"`         //Пути полей JSON через точку, * - любое поле; массивы проходятся насквозь
+	Emails       bool     `json:"emails"`         //Скрытие адресов электронной почты в строках
+	Phones       bool     `json:"phones"`         //Скрытие номеров телефонов в строках
+	Patterns     []string `json:"patterns"`       //Дополнительные регулярные выражения, совпадения скрываются
+	AnonymizeIP  bool     `json:"anonymize_ip"`   //Обнуление последнего октета IPv4 и последних 80 бит IPv6
+	MaxBodyBytes int      `json:"max_body_bytes"` //Размер тела в логе, остаток отбрасывается; 0 - без ограничения
+}
+
+// DefaultRedactRules - правила, если они не заданы в конфигурации
+var DefaultRedactRules = RedactRules{
+	Fields:       []string{"user_name", "content"},
+	Emails:       true,
+	Phones:       true,
+	AnonymizeIP:  true,
+	MaxBodyBytes: 2048,
+}
+
+// Шаблоны адреса электронной почты и номера телефона. Номер должен стоять отдельно:
+// идентификаторы из цифр внутри слов (например, в UUID) не скрываются.
+var (
+	emailPattern = regexp.MustCompile(`[\w.+-]+@[\w-]+(\.[\w-]+)+`)
+	phonePattern = regexp.MustCompile(`(^|[^\w+])((?:\+\d{1,3}[\s-]?|\d[\s-]?)?(?:\(\d{3}\)|\d{3})[\s-]?\d{3}[\s-]?\d{2}[\s-]?\d{2})($|[^\w])`)
+)
+
+// Redactor - скомпилированные правила скрытия данных
+type Redactor struct {
+	rules    RedactRules
+	fields   [][]string
+	patterns []*regexp.Regexp
+}
+
+// NewRedactor - проверка и компиляция правил
+func NewRedactor(rules RedactRules) (*Redactor, error) {
+	r := &Redactor{rules: rules}
+	for _, path := range rules.Fields {
+		if path == "" {
+			return nil, fmt.Errorf("empty field path")
+		}
+		r.fields = append(r.fields, strings.Split(path, "."))
+	}
+	for _, p := range rules.Patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", p, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	return r, nil
+}
+
+// defaultRedactor - правила по умолчанию, шаблоны в них отсутствуют, поэтому ошибки нет
+var defaultRedactor, _ = NewRedactor(DefaultRedactRules)
+
+// DefaultRedactor - Redactor с правилами DefaultRedactRules
+func DefaultRedactor() *Redactor {
+	return defaultRedactor
+}
+
+// Body - поле body с телом запроса после скрытия данных. Тело в формате JSON
+// записывается в лог как вложенный объект, иначе и после обрезки - как строка.
+func (r *Redactor) Body(data []byte) slog.Attr {
+	var v any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil || dec.More() {
+		return slog.String("body", r.truncate(r.text(string(data))))
+	}
+
+	v = r.value(v, nil)
+	out, err := json.Marshal(v)
+	if err != nil {
+		return slog.String("body", r.truncate(r.text(string(data))))
+	}
+	if r.rules.MaxBodyBytes > 0 && len(out) > r.rules.MaxBodyBytes {
+		return slog.String("body", r.truncate(string(out)))
+	}
+	return slog.Any("body", json.RawMessage(out))
+}
+
+// Addr - адрес клиента с обнулением младших разрядов IP, порт сохраняется
+func (r *Redactor) Addr(addr string) string {
+	if !r.rules.AnonymizeIP {
+		return addr
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		host, port = addr, ""
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return addr
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4.Mask(net.CIDRMask(24, 32))
+	} else {
+		ip = ip.Mask(net.CIDRMask(48, 128))
+	}
+	if port == "" {
+		return ip.String()
+	}
+	return net.JoinHostPort(ip.String(), port)
+}
+
+// value - скрытие полей по путям и данных в строках значения JSON, path - путь к значению
+func (r *Redactor) value(v any, path []string) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, item := range v {
+			itemPath := append(path[:len(path):len(path)], key)
+			if r.matchField(itemPath) {
+				v[key] = Redacted
+				continue
+			}
+			v[key] = r.value(item, itemPath)
+		}
+		return v
+	case []any:
+		for i, item := range v {
+			v[i] = r.value(item, path)
+		}
+		return v
+	case string:
+		return r.text(v)
+	}
+	return v
+}
+
+// matchField - путь совпадает с одним из путей правил
+func (r *Redactor) matchField(path []string) bool {
+	for _, field := range r.fields {
+		if len(field) != len(path) {
+			continue
+		}
+		match := true
+		for i := range field {
+			if field[i] != "*" && field[i] != path[i] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// text - скрытие адресов почты, телефонов и совпадений дополнительных шаблонов
+func (r *Redactor) text(s string) string {
+	if r.rules.Emails {
+		s = emailPattern.ReplaceAllString(s, Redacted)
+	}
+	if r.rules.Phones {
+		// Разделитель между соседними номерами входит в совпадение первого из них,
+		// поэтому замена повторяется, пока находятся номера
+		for phonePattern.MatchString(s) {
+			s = phonePattern.ReplaceAllString(s, "${1}"+Redacted+"${3}")
+		}
+	}
+	for _, re := range r.patterns {
+		s = re.ReplaceAllString(s, Redacted)
+	}
+	return s
+}
+
+// truncate - обрезка до MaxBodyBytes по границе символа
+func (r *Redactor) truncate(s string) string {
+	max := r.rules.MaxBodyBytes
+	if max <= 0 || len(s) <= max {
+		return s
+	}
+	cut := max
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return fmt.Sprintf("%s...(truncated, %d bytes)", s[:cut], len(s))
+}
@@ -0,0 +1,226 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+)
+
+// Ключи полей структурированного лога
+const (
+	KeyRequestID  = "request_id"  //Идентификатор запроса
+	KeyService    = "service"     //Имя сервиса
+	KeyTopic      = "topic"       //Топик Kafka
+	KeyLatency    = "latency"     //Длительность обработки
+	KeyStatusCode = "status_code" //HTTP-код ответа
+	KeyRemoteAddr = "remote_addr" //Адрес клиента
+	KeyError      = "error"       //Текст ошибки
+)
+
+// EnvLevel - переменная окружения с уровнем логирования: debug, info, warn или error
+const EnvLevel = "LOGLEVEL"
+
+// New - структурированный логгер на основе log/slog, записи которого
+// сохраняются в sink в формате RequestLog
+func New(sink *Logger, level slog.Leveler) *slog.Logger {
+	return slog.New(NewHandler(sink, level))
+}
+
+// LevelFromEnv - уровень логирования из переменной окружения LOGLEVEL, по умолчанию info
+func LevelFromEnv() slog.Level {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(os.Getenv(EnvLevel))); err != nil {
+		return slog.LevelInfo
+	}
+	return level
+}
+
+// RequestID - поле с идентификатором запроса
+func RequestID(id string) slog.Attr {
+	return slog.String(KeyRequestID, id)
+}
+
+// Topic - поле с топиком Kafka
+func Topic(topic string) slog.Attr {
+	return slog.String(KeyTopic, topic)
+}
+
+// Latency - поле с длительностью обработки
+func Latency(d time.Duration) slog.Attr {
+	return slog.Duration(KeyLatency, d)
+}
+
+// Err - поле с текстом ошибки
+func Err(err error) slog.Attr {
+	return slog.Any(KeyError, err)
+}
+
+// contextKey - ключ полей лога в контексте
+type contextKey struct{}
+
+// ContextWith - контекст с полями, которые добавляются ко всем записям,
+// сделанным с этим контекстом (InfoContext, ErrorContext и т.д.)
+func ContextWith(ctx context.Context, attrs ...slog.Attr) context.Context {
+	prev := attrsFromContext(ctx)
+	merged := make([]slog.Attr, 0, len(prev)+len(attrs))
+	merged = append(merged, prev...)
+	merged = append(merged, attrs...)
+	return context.WithValue(ctx, contextKey{}, merged)
+}
+
+// attrsFromContext - поля лога из контекста
+func attrsFromContext(ctx context.Context) []slog.Attr {
+	if ctx == nil {
+		return nil
+	}
+	attrs, _ := ctx.Value(contextKey{}).([]slog.Attr)
+	return attrs
+}
+
+// Handler - slog.Handler, преобразующий записи в RequestLog.
+// Поля request_id, service, topic, latency, status_code и remote_addr
+// переносятся в одноименные поля RequestLog, остальные - в fields.
+type Handler struct {
+	sink    *Logger
+	level   slog.Leveler
+	localIP string
+	attrs   []slog.Attr
+	groups  []string
+}
+
+// NewHandler - создание нового экземпляра Handler
+func NewHandler(sink *Logger, level slog.Leveler) *Handler {
+	if level == nil {
+		level = slog.LevelInfo
+	}
+	return &Handler{sink: sink, level: level, localIP: GetLocalIP()}
+}
+
+// Enabled - проверка уровня записи
+func (h *Handler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+// Handle - запись в sink
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	entry := RequestLog{
+		Timestamp:   r.Time,
+		ServiceID:   GetServiceName(),
+		RemoteAddr:  h.localIP,
+		StatusCode:  statusCode(r.Level),
+		DataRequest: r.Message,
+		Level:       r.Level.String(),
+	}
+
+	var errText string
+	add := func(prefix string) func(slog.Attr) bool {
+		return func(a slog.Attr) bool {
+			a.Value = a.Value.Resolve()
+			if a.Equal(slog.Attr{}) {
+				return true
+			}
+			switch {
+			case prefix != "":
+				setField(&entry, prefix+a.Key, a.Value)
+			case a.Key == KeyRequestID:
+				entry.RequestID = a.Value.String()
+			case a.Key == KeyService:
+				entry.ServiceID = a.Value.String()
+			case a.Key == KeyTopic:
+				entry.Topic = a.Value.String()
+			case a.Key == KeyLatency && a.Value.Kind() == slog.KindDuration:
+				entry.LatencyMs = float64(a.Value.Duration().Microseconds()) / 1000
+			case a.Key == KeyStatusCode && a.Value.Kind() == slog.KindInt64:
+				entry.StatusCode = int(a.Value.Int64())
+			case a.Key == KeyRemoteAddr:
+				entry.RemoteAddr = a.Value.String()
+			case a.Key == KeyError:
+				errText = a.Value.String()
+			default:
+				setField(&entry, a.Key, a.Value)
+			}
+			return true
+		}
+	}
+	// Поля обработчика уже содержат префикс своей группы
+	for _, a := range attrsFromContext(ctx) {
+		add("")(a)
+	}
+	for _, a := range h.attrs {
+		add("")(a)
+	}
+	r.Attrs(add(h.prefix()))
+
+	// Текст ошибки, как и раньше, входит в data_request
+	if errText != "" {
+		entry.DataRequest += ": " + errText
+	}
+
+	h.sink.Log(entry)
+	return nil
+}
+
+// WithAttrs - обработчик с дополнительными полями
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.attrs = make([]slog.Attr, 0, len(h.attrs)+len(attrs))
+	h2.attrs = append(h2.attrs, h.attrs...)
+	for _, a := range attrs {
+		a.Key = h.prefix() + a.Key
+		h2.attrs = append(h2.attrs, a)
+	}
+	return &h2
+}
+
+// WithGroup - обработчик, добавляющий префикс группы к именам полей
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.groups = append(append([]string(nil), h.groups...), name)
+	return &h2
+}
+
+// prefix - префикс имен полей для групп обработчика
+func (h *Handler) prefix() string {
+	if len(h.groups) == 0 {
+		return ""
+	}
+	return strings.Join(h.groups, ".") + "."
+}
+
+// statusCode - код статуса записи без поля status_code: 500 для ошибок, иначе 200
+func statusCode(level slog.Level) int {
+	if level >= slog.LevelError {
+		return 500
+	}
+	return 200
+}
+
+// setField - сохранение поля, не имеющего отдельного места в RequestLog
+func setField(e *RequestLog, key string, value slog.Value) {
+	if e.Fields == nil {
+		e.Fields = make(map[string]any)
+	}
+	switch value.Kind() {
+	case slog.KindGroup:
+		for _, a := range value.Group() {
+			setField(e, key+"."+a.Key, a.Value.Resolve())
+		}
+	case slog.KindDuration:
+		e.Fields[key] = value.Duration().String()
+	case slog.KindTime:
+		e.Fields[key] = value.Time()
+	case slog.KindAny:
+		if err, ok := value.Any().(error); ok {
+			e.Fields[key] = err.Error()
+			return
+		}
+		e.Fields[key] = value.Any()
+	default:
+		e.Fields[key] = value.Any()
+	}
+}
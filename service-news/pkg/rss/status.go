@@ -0,0 +1,28 @@
+package rss
+
+import (
+	"sync"
+	"time"
+)
+
+// Status - время последней успешной загрузки RSS-лент, используется в ответе на Ping
+type Status struct {
+	mu          sync.Mutex
+	lastSuccess time.Time
+}
+
+// Success - отметка успешной загрузки RSS-ленты
+func (s *Status) Success(t time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if t.After(s.lastSuccess) {
+		s.lastSuccess = t
+	}
+}
+
+// LastSuccess - время последней успешной загрузки, нулевое, если загрузок еще не было
+func (s *Status) LastSuccess() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastSuccess
+}
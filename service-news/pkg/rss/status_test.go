@@ -0,0 +1,18 @@
+package rss
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatus_LastSuccess(t *testing.T) {
+	var s Status
+	assert.True(t, s.LastSuccess().IsZero())
+
+	now := time.Now()
+	s.Success(now)
+	s.Success(now.Add(-time.Minute))
+	assert.Equal(t, now, s.LastSuccess())
+}
@@ -0,0 +1,115 @@
+// Package memdb - хранилище новостей в памяти для тестов
+package memdb
+
+import (
+	"context"
+	"news-kafka/service-news/pkg/storage"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Хранилище данных.
+type Store struct {
+	mu   sync.Mutex
+	news []storage.News
+}
+
+// Конструктор объекта хранилища.
+func New() *Store {
+	return &Store{}
+}
+
+func (s *Store) GetInform() string {
+	return "Memory"
+}
+
+// Ping - хранилище в памяти всегда доступно
+func (s *Store) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (s *Store) Close() {}
+
+// News возвращает последние новости с учетом рубрики, фильтра по названию и страницы.
+// Пустая рубрика или "%" соответствует всем рубрикам, как LIKE в PostgreSQL.
+func (s *Store) News(ctx context.Context, rubric string, countNews int, filter string, pageCurr int) ([]storage.News, storage.Paginate, error) {
+	if countNews <= 0 {
+		countNews = 10
+	}
+	if pageCurr < 1 {
+		pageCurr = 1
+	}
+
+	s.mu.Lock()
+	var selected []storage.News
+	for _, n := range s.news {
+		if rubric != "" && rubric != "%" && n.Rubric != rubric {
+			continue
+		}
+		if !strings.Contains(strings.ToLower(n.Title), strings.ToLower(filter)) {
+			continue
+		}
+		selected = append(selected, n)
+	}
+	s.mu.Unlock()
+
+	sort.SliceStable(selected, func(i, j int) bool { return selected[i].PublicTime > selected[j].PublicTime })
+
+	totalCount := len(selected)
+	pageCount := totalCount / countNews
+	if totalCount%countNews != 0 {
+		pageCount++
+	}
+
+	from := (pageCurr - 1) * countNews
+	if from > totalCount {
+		from = totalCount
+	}
+	to := from + countNews
+	if to > totalCount {
+		to = totalCount
+	}
+
+	return selected[from:to], storage.Paginate{
+		PageCurr:       pageCurr,
+		PageCount:      pageCount,
+		PageCountList:  countNews,
+		PageCountTotal: totalCount,
+	}, nil
+}
+
+// NewsOne возвращает новость по ID, storage.ErrNotFound если ее нет.
+func (s *Store) NewsOne(ctx context.Context, id int) (storage.News, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, n := range s.news {
+		if n.Id == id {
+			return n, nil
+		}
+	}
+	return storage.News{}, storage.ErrNotFound
+}
+
+// AddNew добавляет новости, пропуская уже сохраненные ссылки.
+func (s *Store) AddNew(ctx context.Context, news []storage.News) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, n := range news {
+		duplicate := false
+		for _, saved := range s.news {
+			if saved.Link == n.Link {
+				duplicate = true
+				break
+			}
+		}
+		if duplicate {
+			continue
+		}
+		n.Id = len(s.news) + 1
+		s.news = append(s.news, n)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package memdb
+
+import (
+	"context"
+	"news-kafka/service-news/pkg/storage"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStore_News(t *testing.T) {
+	s := New()
+	err := s.AddNew(context.Background(), []storage.News{
+		{Title: "Football final", Rubric: "sport", Link: "l1", PublicTime: 1},
+		{Title: "Hockey", Rubric: "sport", Link: "l2", PublicTime: 3},
+		{Title: "Elections", Rubric: "politics", Link: "l3", PublicTime: 2},
+		{Title: "Duplicate", Rubric: "sport", Link: "l1", PublicTime: 4},
+	})
+	assert.NoError(t, err)
+
+	news, paginate, err := s.News(context.Background(), "sport", 1, "", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "Hockey", news[0].Title)
+	assert.Equal(t, storage.Paginate{PageCurr: 1, PageCount: 2, PageCountList: 1, PageCountTotal: 2}, paginate)
+
+	news, _, err = s.News(context.Background(), "%", 10, "FOOT", 1)
+	assert.NoError(t, err)
+	assert.Len(t, news, 1)
+	assert.Equal(t, 1, news[0].Id)
+}
+
+func TestStore_NewsOne(t *testing.T) {
+	s := New()
+	assert.NoError(t, s.AddNew(context.Background(), []storage.News{{Title: "News", Link: "l1"}}))
+
+	news, err := s.NewsOne(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "News", news.Title)
+
+	_, err = s.NewsOne(context.Background(), 2)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+}
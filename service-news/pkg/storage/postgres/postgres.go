@@ -2,11 +2,17 @@ package postgres
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"news-kafka/service-news/pkg/metrics"
 	"news-kafka/service-news/pkg/storage"
+	"news-kafka/service-news/pkg/tracing"
 	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v4/pgxpool"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Хранилище данных.
@@ -37,15 +43,49 @@ func (s *Store) Close() {
 	s.db.Close()
 }
 
+// Ping проверяет доступность БД через пул соединений.
+func (s *Store) Ping(ctx context.Context) error {
+	return s.db.Ping(ctx)
+}
+
+// query - спан и время выполнения запроса к PostgreSQL
+type query struct {
+	method string
+	start  time.Time
+	span   trace.Span
+}
+
+// startQuery - начало запроса к PostgreSQL: спан трассировки и отсчет времени для метрик
+func startQuery(ctx context.Context, method, operation, table string) (context.Context, *query) {
+	ctx, span := tracing.Tracer().Start(ctx, "postgres "+method, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		attribute.String("db.system", "postgresql"),
+		attribute.String("db.operation", operation),
+		attribute.String("db.sql.table", table),
+	))
+	return ctx, &query{method: method, start: time.Now(), span: span}
+}
+
+// end - завершение запроса, отсутствие записи не считается ошибкой
+func (q *query) end(err error) {
+	if errors.Is(err, storage.ErrNotFound) {
+		err = nil
+	}
+	metrics.DBQueryDuration.WithLabelValues(q.method, metrics.Result(err)).Observe(time.Since(q.start).Seconds())
+	tracing.End(q.span, err)
+}
+
 // News возвращает последние новости из БД.
-func (s *Store) News(rubric string, countNews int, filter string, pageCurr int) ([]storage.News, storage.Paginate, error) {
+func (s *Store) News(ctx context.Context, rubric string, countNews int, filter string, pageCurr int) (_ []storage.News, _ storage.Paginate, err error) {
+	ctx, q := startQuery(ctx, "News", "SELECT", "news")
+	defer func() { q.end(err) }()
+
 	if countNews <= 0 {
 		countNews = 10
 	}
 
 	// Получаем общее количество новостей с учетом фильтра
 	var totalCount int
-	err := s.db.QueryRow(context.Background(), `
+	err = s.db.QueryRow(ctx, `
 	 SELECT COUNT(*) FROM news
 	 WHERE rubric LIKE $1 AND title ILIKE $2
 	`, rubric, "%"+filter+"%").Scan(&totalCount)
@@ -60,7 +100,7 @@ func (s *Store) News(rubric string, countNews int, filter string, pageCurr int)
 	}
 
 	// Выполняем запрос с пагинацией
-	rows, err := s.db.Query(context.Background(), `
+	rows, err := s.db.Query(ctx, `
 	 SELECT id, title, content, public_time, image_link, rubric, link, link_title 
 	 FROM news
 	 WHERE rubric LIKE $1 AND title ILIKE $2
@@ -105,9 +145,11 @@ func (s *Store) News(rubric string, countNews int, filter string, pageCurr int)
 }
 
 // News возвращает последние новости из БД.
-func (s *Store) NewsOne(id int) (storage.News, error) {
+func (s *Store) NewsOne(ctx context.Context, id int) (_ storage.News, err error) {
+	ctx, q := startQuery(ctx, "NewsOne", "SELECT", "news")
+	defer func() { q.end(err) }()
 
-	rows, err := s.db.Query(context.Background(), `
+	rows, err := s.db.Query(ctx, `
 	SELECT id, title, content, public_time, image_link, rubric, link, link_title FROM news
 	WHERE id = $1
 	`,
@@ -116,9 +158,11 @@ func (s *Store) NewsOne(id int) (storage.News, error) {
 	if err != nil {
 		return storage.News{}, err
 	}
+	defer rows.Close()
 
 	// Декодируем результаты
 	var news storage.News
+	found := false
 	for rows.Next() {
 		var p storage.News
 		err = rows.Scan(
@@ -135,18 +179,25 @@ func (s *Store) NewsOne(id int) (storage.News, error) {
 			return storage.News{}, fmt.Errorf("failed to scan news row: %w", err)
 		}
 		news = p
+		found = true
 	}
 	if err := rows.Err(); err != nil {
 		return storage.News{}, fmt.Errorf("failed to iterate news rows: %w", err)
 	}
+	if !found {
+		return storage.News{}, storage.ErrNotFound
+	}
 
-	return news, rows.Err()
+	return news, nil
 }
 
 // Добавляем новость в БД.
-func (s *Store) AddNew(news []storage.News) error {
+func (s *Store) AddNew(ctx context.Context, news []storage.News) (err error) {
+	ctx, q := startQuery(ctx, "AddNew", "INSERT", "news")
+	defer func() { q.end(err) }()
+
 	for _, newsRec := range news {
-		_, err := s.db.Exec(context.Background(), `
+		_, err := s.db.Exec(ctx, `
 		INSERT INTO news(title, content, public_time, image_link, rubric, link, link_title)
 		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
 			newsRec.Title,
@@ -0,0 +1,45 @@
+package tracing
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestInit_Disabled(t *testing.T) {
+	t.Setenv(EnvExporter, "")
+	shutdown, err := Init(context.Background(), "test")
+	require.NoError(t, err)
+	assert.NoError(t, shutdown(context.Background()))
+}
+
+func TestInit_Unsupported(t *testing.T) {
+	t.Setenv(EnvExporter, "zipkin")
+	_, err := Init(context.Background(), "test")
+	assert.Error(t, err)
+}
+
+// Экспорт в файл: спаны записываются при остановке
+func TestInit_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces.json")
+	t.Setenv(EnvExporter, "file")
+	t.Setenv(EnvFile, path)
+	t.Cleanup(func() { otel.SetTracerProvider(noop.NewTracerProvider()) })
+
+	shutdown, err := Init(context.Background(), "test")
+	require.NoError(t, err)
+
+	_, span := Tracer().Start(context.Background(), "operation")
+	End(span, nil)
+	require.NoError(t, shutdown(context.Background()))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"Name":"operation"`)
+}